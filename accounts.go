@@ -0,0 +1,103 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"slices"
+	"time"
+
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// AccountInfo is the account metadata from the Users service
+type AccountInfo struct {
+	Id   string `json:"id"`
+	Name string `json:"name,omitempty"`
+	// brokerage, iis, invest-box or invest-fund
+	Type string `json:"type,omitempty"`
+	// A, B or 3 for IIS accounts, it is not available from the API
+	IISType string `json:"iis_type,omitempty"`
+	// the account follows an auto-following strategy, it is only known from its operations
+	Strategy   bool       `json:"strategy,omitempty"`
+	OpenedDate *time.Time `json:"opened_date,omitempty"`
+	ClosedDate *time.Time `json:"closed_date,omitempty"`
+}
+
+var accountTypes = map[pb.AccountType]string{
+	pb.AccountType_ACCOUNT_TYPE_TINKOFF:     "brokerage",
+	pb.AccountType_ACCOUNT_TYPE_TINKOFF_IIS: "iis",
+	pb.AccountType_ACCOUNT_TYPE_INVEST_BOX:  "invest-box",
+	pb.AccountType_ACCOUNT_TYPE_INVEST_FUND: "invest-fund",
+}
+
+// knownDate returns nil for unset dates
+func knownDate(date time.Time) *time.Time {
+	if date.Unix() <= 0 {
+		return nil
+	}
+	return &date
+}
+
+func NewAccountInfo(account *pb.Account) AccountInfo {
+	return AccountInfo{
+		Id:         account.Id,
+		Name:       account.Name,
+		Type:       accountTypes[account.Type],
+		OpenedDate: knownDate(account.OpenedDate.AsTime()),
+		ClosedDate: knownDate(account.ClosedDate.AsTime()),
+	}
+}
+
+// Start returns the beginning of the evaluated window: the start of the tax year or the account opening
+func (a AccountInfo) Start() time.Time {
+	start := time.Date(TaxYear, 1, 1, 0, 0, 0, 0, Location)
+	if a.OpenedDate != nil && a.OpenedDate.After(start) {
+		return *a.OpenedDate
+	}
+	return start
+}
+
+// End returns the end of the evaluated window: the end of the tax year or the account closure
+func (a AccountInfo) End() time.Time {
+	end := time.Date(TaxYear+1, 1, 1, 0, 0, 0, 0, Location)
+	if a.ClosedDate != nil && a.ClosedDate.Before(end) {
+		return *a.ClosedDate
+	}
+	return end
+}
+
+// ClosedInTaxYear tells whether the account was closed during the tax year
+func (a AccountInfo) ClosedInTaxYear() bool {
+	return a.ClosedDate != nil && InTaxYear(*a.ClosedDate)
+}
+
+// OpenedInTaxYear tells whether the account was opened during the tax year
+func (a AccountInfo) OpenedInTaxYear() bool {
+	return a.OpenedDate != nil && InTaxYear(*a.OpenedDate)
+}
+
+// FollowsStrategy tells whether the operations have fees of an auto-following strategy
+func FollowsStrategy(operations []*pb.OperationItem) bool {
+	return slices.ContainsFunc(operations, func(operation *pb.OperationItem) bool {
+		return slices.Contains(StrategyOperationTypes, operation.Type)
+	})
+}
+
+// IsIIS checks whether the account is an individual investment account
+func (a AccountInfo) IsIIS() bool {
+	return a.Type == accountTypes[pb.AccountType_ACCOUNT_TYPE_TINKOFF_IIS]
+}
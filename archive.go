@@ -0,0 +1,99 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/matshch/tbank-invest/storage"
+	"go.uber.org/zap"
+	"opensource.tbank.ru/invest/invest-go/investgo"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// store archives the fetched data and the timelines, nil if disabled
+var store *storage.Store
+
+// getStoredCandles returns the candles of the tax year from the store if they have been fetched completely
+func getStoredCandles(instrumentUid string, from time.Time) ([]*pb.HistoricCandle, bool, error) {
+	if store == nil {
+		return nil, false, nil
+	}
+	return store.Candles(candlesKey(instrumentUid), from, candlesTo())
+}
+
+// fetchAndStoreCandles fetches the candles and saves them to the store, the candles of the future are not there yet,
+// so only the range until now is complete
+func fetchAndStoreCandles(md *investgo.MarketDataServiceClient, instrumentUid string, from time.Time) ([]*pb.HistoricCandle, error) {
+	now := time.Now()
+	candles, err := fetchCandles(md, instrumentUid, from)
+	if err != nil || store == nil {
+		return candles, err
+	}
+	to := candlesTo()
+	if now.Before(to) {
+		to = now
+	}
+	// a failed save only means the candles are downloaded again next time
+	_ = store.SaveCandles(candlesKey(instrumentUid), from, to, candles)
+	return candles, nil
+}
+
+// ArchiveRun saves the operations, the instruments and the timelines of the evaluated accounts to the store
+func ArchiveRun(logger *zap.Logger, evaluations []*Evaluation) error {
+	if store == nil {
+		return nil
+	}
+	for _, evaluation := range evaluations {
+		err := store.SaveOperations(evaluation.AccountId, evaluation.Operations)
+		if err != nil {
+			logger.Error("error archiving operations", zap.String("account", evaluation.AccountId), zap.Error(err))
+			return err
+		}
+		timeline := make([]storage.Point, 0, len(evaluation.Timeline))
+		for _, point := range evaluation.Timeline {
+			timeline = append(timeline, storage.Point{Time: point.Time, Aggregate: point.Aggregate.RatString()})
+		}
+		err = store.SaveTimeline(evaluation.AccountId, TaxYear, timeline)
+		if err != nil {
+			logger.Error("error archiving timeline", zap.String("account", evaluation.AccountId), zap.Error(err))
+			return err
+		}
+	}
+	var instruments []storage.Instrument
+	for _, instrumentUid := range slices.Sorted(maps.Keys(assets)) {
+		assetUid := assets[instrumentUid]
+		instruments = append(instruments, storage.Instrument{
+			Uid:      instrumentUid,
+			AssetUid: assetUid,
+			Ticker:   tickers[assetUid],
+			Name:     names[assetUid],
+			Isin:     isins[assetUid],
+			Kind:     kinds[assetUid].String(),
+			Currency: instrumentCurrencies[instrumentUid],
+		})
+	}
+	err := store.SaveInstruments(instruments)
+	if err != nil {
+		logger.Error("error archiving instruments", zap.Error(err))
+		return err
+	}
+	logger.Info("run archived", zap.Int("accounts", len(evaluations)), zap.Int("instruments", len(instruments)))
+	return nil
+}
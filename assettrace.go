@@ -0,0 +1,148 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// AssetPoint is the position of the traced asset when its quantity or price changes
+type AssetPoint struct {
+	Time     time.Time
+	Quantity *big.Rat
+	// nil for currencies and assets without a price
+	Price *big.Rat
+	// value in USD and its share of the aggregate value
+	Value     *big.Rat
+	Aggregate *big.Rat
+}
+
+// AssetTrace follows a single asset through the evaluation for -asset
+type AssetTrace struct {
+	Asset  string
+	Points []AssetPoint
+}
+
+// NewAssetTrace finds the asset by its ticker, ISIN, UID or currency code, nil if it is unknown
+func NewAssetTrace(logger *zap.Logger, id string) *AssetTrace {
+	if _, ok := ExchangeRates[id]; ok {
+		return &AssetTrace{Asset: id}
+	}
+	assetUid, ok := FindAsset(id)
+	if !ok {
+		logger.Warn("cannot find traced asset, it is not held or traded", zap.String("asset", id))
+		return nil
+	}
+	return &AssetTrace{Asset: assetUid}
+}
+
+// Observe records the position of the asset if its quantity or price has changed since the last point,
+// the points come back in time, so an unchanged position moves the last point to its start
+func (t *AssetTrace) Observe(date time.Time, state *State, aggregate *big.Rat) {
+	if t == nil {
+		return
+	}
+	quantity := state.Portfolio[t.Asset]
+	if quantity == nil {
+		quantity = &big.Rat{}
+	}
+	point := AssetPoint{Time: date, Quantity: quantity, Value: &big.Rat{}, Aggregate: aggregate}
+	if rate, ok := ExchangeRates[t.Asset]; ok {
+		point.Value = (&big.Rat{}).Quo(quantity, rate)
+	} else if price, ok := state.Prices[t.Asset]; ok {
+		point.Price = AddRat(price, state.Accrued[t.Asset])
+		if rate, ok := ExchangeRates[state.Currencies[t.Asset]]; ok && !IsFutures(t.Asset) {
+			point.Value = (&big.Rat{}).Mul(point.Price, quantity)
+			point.Value.Quo(point.Value, rate)
+		}
+	}
+	if n := len(t.Points); n > 0 {
+		last := t.Points[n-1]
+		if last.Quantity.Cmp(point.Quantity) == 0 && (last.Price == point.Price ||
+			last.Price != nil && point.Price != nil && last.Price.Cmp(point.Price) == 0) {
+			t.Points[n-1].Time, t.Points[n-1].Aggregate = date, aggregate
+			return
+		}
+	}
+	t.Points = append(t.Points, point)
+}
+
+// PrintAssetTrace prints the operations, the candles and the positions of the traced asset
+// and its contribution to the maximum
+func PrintAssetTrace(w io.Writer, evaluation *Evaluation) error {
+	trace := evaluation.Asset
+	if trace == nil {
+		return nil
+	}
+	tw := NewTable(w, tabwriter.AlignRight)
+	fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\n", T("TIME"), T("TYPE"), T("QUANTITY"), T("AMOUNT"))
+	for _, operation := range evaluation.Operations {
+		if operation.AssetUid != trace.Asset {
+			continue
+		}
+		amount := ""
+		if operation.Payment != nil {
+			amount = FormatMoney(ToRat(operation.Payment), operation.Payment.Currency)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t\n", operation.Date.AsTime().In(Location).Format(time.DateTime),
+			operation.Type.String(), operation.Quantity, amount)
+	}
+	err := tw.Flush()
+	if err != nil {
+		return err
+	}
+
+	for _, series := range evaluation.Series {
+		if series.Asset != trace.Asset || len(series.Candles) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, T("%d candles from %s to %s\n"), len(series.Candles),
+			series.Candles[0].Time.AsTime().In(Location).Format(time.DateTime),
+			series.Candles[len(series.Candles)-1].Time.AsTime().In(Location).Format(time.DateTime))
+	}
+
+	tw = NewTable(w, tabwriter.AlignRight)
+	fmt.Fprintf(tw, "%s\t%s\t%s\tUSD\t%s\t\n", T("TIME"), T("QUANTITY"), T("PRICE"), T("SHARE"))
+	for _, point := range trace.Points {
+		price := "-"
+		if point.Price != nil {
+			price = formatDecimal(point.Price)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t\n", point.Time.Format(time.DateTime),
+			formatDecimal(ScaleAmount(point.Quantity)), price, FormatUSD(point.Value),
+			percent(point.Value, point.Aggregate))
+	}
+	err = tw.Flush()
+	if err != nil {
+		return err
+	}
+
+	values := AssetValues(evaluation.BestState, evaluation.Excluded)
+	value := values[trace.Asset]
+	if value == nil {
+		value = &big.Rat{}
+	}
+	fmt.Fprintf(w, T("At the maximum on %s the asset was worth %s, %s of the account\n"),
+		evaluation.BestTime.Format(time.DateTime), FormatUSD(value), percent(value, evaluation.BestAggregate))
+	return nil
+}
@@ -0,0 +1,52 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestAssetTraceObserve(t *testing.T) {
+	trace := &AssetTrace{Asset: "a"}
+	date := time.Date(TaxYear, 3, 1, 0, 0, 0, 0, time.UTC)
+	state := func(quantity, price int64) *State {
+		return &State{
+			Portfolio:  map[string]*big.Rat{"a": big.NewRat(quantity, 1)},
+			Prices:     map[string]*big.Rat{"a": big.NewRat(price, 1)},
+			Currencies: map[string]string{"a": "usd"},
+		}
+	}
+	// back in time: 10 at 5 since the day 2, 10 at 4 on the day 1, none before
+	trace.Observe(date.AddDate(0, 0, 3), state(10, 5), big.NewRat(100, 1))
+	trace.Observe(date.AddDate(0, 0, 2), state(10, 5), big.NewRat(100, 1))
+	trace.Observe(date.AddDate(0, 0, 1), state(10, 4), big.NewRat(80, 1))
+	trace.Observe(date, state(0, 4), big.NewRat(40, 1))
+	if len(trace.Points) != 3 {
+		t.Fatalf("Observe() recorded %d points, want 3", len(trace.Points))
+	}
+	first := trace.Points[0]
+	if !first.Time.Equal(date.AddDate(0, 0, 2)) || first.Value.Cmp(big.NewRat(50, 1)) != 0 {
+		t.Errorf("Points[0] = %+v, want 50 since the day 2", first)
+	}
+	if last := trace.Points[2]; last.Quantity.Sign() != 0 || last.Value.Sign() != 0 {
+		t.Errorf("Points[2] = %+v, want no position", last)
+	}
+	var none *AssetTrace
+	none.Observe(date, state(1, 1), big.NewRat(1, 1))
+}
@@ -0,0 +1,177 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"math/big"
+	"slices"
+	"text/tabwriter"
+
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// Dividends and coupons with the taxes withheld from them, the income is net of the taxes
+var incomeTypes = []pb.OperationType{
+	pb.OperationType_OPERATION_TYPE_DIVIDEND,
+	pb.OperationType_OPERATION_TYPE_DIVIDEND_TAX,
+	pb.OperationType_OPERATION_TYPE_COUPON,
+	pb.OperationType_OPERATION_TYPE_BOND_TAX,
+}
+
+// Attribution is the yearly result of an asset in its currency. The positions held at the start of the year
+// are valued at the start prices, the realized result of sales is against the average cost, and the rest of
+// the change of value, including transfers of securities, is unrealized.
+type Attribution struct {
+	Asset      string
+	Currency   string
+	Start      *big.Rat
+	End        *big.Rat
+	Realized   *big.Rat
+	Unrealized *big.Rat
+	Income     *big.Rat
+	Fees       *big.Rat
+}
+
+// Total returns the yearly result of the asset
+func (a Attribution) Total() *big.Rat {
+	return AddRat(AddRat(a.Realized, a.Unrealized), AddRat(a.Income, a.Fees))
+}
+
+// positionValue values the position in the currency of the asset, zero without a price
+func positionValue(state *State, asset string) (*big.Rat, string) {
+	if state == nil {
+		return &big.Rat{}, ""
+	}
+	quantity, ok := state.Portfolio[asset]
+	price, priced := state.Prices[asset]
+	if !ok || !priced || IsFutures(asset) {
+		return &big.Rat{}, state.Currencies[asset]
+	}
+	return (&big.Rat{}).Mul(AddRat(price, state.Accrued[asset]), quantity), state.Currencies[asset]
+}
+
+// AttributeResults splits the yearly result of each asset into the realized and unrealized results, the income and
+// the fees, by the operations of the tax year and the states at its start and end
+func AttributeResults(operations []*pb.OperationItem, start, end *State) []Attribution {
+	type position struct {
+		*Attribution
+		quantity *big.Rat
+		basis    *big.Rat
+	}
+	positions := make(map[string]*position)
+	get := func(asset string) *position {
+		if p, ok := positions[asset]; ok {
+			return p
+		}
+		value, currency := positionValue(start, asset)
+		quantity := &big.Rat{}
+		if start != nil && start.Portfolio[asset] != nil {
+			quantity = start.Portfolio[asset]
+		}
+		p := &position{
+			Attribution: &Attribution{Asset: asset, Currency: currency, Start: value, Realized: &big.Rat{},
+				Income: &big.Rat{}, Fees: &big.Rat{}},
+			quantity: quantity,
+			basis:    value,
+		}
+		positions[asset] = p
+		return p
+	}
+	if start != nil {
+		for asset := range start.Portfolio {
+			if _, ok := ExchangeRates[asset]; !ok {
+				get(asset)
+			}
+		}
+	}
+	operations = slices.Clone(operations)
+	slices.SortStableFunc(operations, func(a, b *pb.OperationItem) int {
+		return a.Date.AsTime().Compare(b.Date.AsTime())
+	})
+	for _, operation := range operations {
+		if operation.AssetUid == "" || operation.Payment == nil || !InTaxYear(operation.Date.AsTime()) {
+			continue
+		}
+		p := get(operation.AssetUid)
+		if p.Currency == "" {
+			p.Currency = operation.Payment.GetCurrency()
+		}
+		payment := ToRat(operation.Payment)
+		quantity := big.NewRat(operation.Quantity, 1)
+		switch {
+		case operation.Type == pb.OperationType_OPERATION_TYPE_BUY:
+			p.basis = SubRat(p.basis, payment)
+			p.quantity = AddRat(p.quantity, quantity)
+		case operation.Type == pb.OperationType_OPERATION_TYPE_SELL:
+			cost := &big.Rat{}
+			if p.quantity.Sign() > 0 {
+				cost.Mul(p.basis, quantity).Quo(cost, p.quantity)
+			}
+			p.Realized = AddRat(p.Realized, SubRat(payment, cost))
+			p.basis = SubRat(p.basis, cost)
+			p.quantity = SubRat(p.quantity, quantity)
+		case slices.Contains(incomeTypes, operation.Type):
+			p.Income = AddRat(p.Income, payment)
+		case slices.Contains(FeeTypes, operation.Type):
+			p.Fees = AddRat(p.Fees, payment)
+		}
+	}
+	result := make([]Attribution, 0, len(positions))
+	for _, asset := range slices.SortedFunc(maps.Keys(positions), ByTicker) {
+		p := positions[asset]
+		p.End, _ = positionValue(end, asset)
+		p.Unrealized = SubRat(p.End, p.basis)
+		result = append(result, *p.Attribution)
+	}
+	return result
+}
+
+// PrintAttribution prints the yearly result of each asset, largest first
+func PrintAttribution(w io.Writer, attributions []Attribution) error {
+	rows := slices.Clone(attributions)
+	slices.SortStableFunc(rows, func(a, b Attribution) int {
+		return cmp.Compare(usdValue(b.Total(), b.Currency), usdValue(a.Total(), a.Currency))
+	})
+	tw := NewTable(w, tabwriter.AlignRight)
+	fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n", T("TICKER"), T("START"), T("END"), T("REALIZED"),
+		T("UNREALIZED"), T("INCOME"), T("FEES"), T("TOTAL"))
+	for _, row := range rows {
+		name := row.Asset
+		if ticker, ok := tickers[row.Asset]; ok {
+			name = ticker
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n", name, FormatMoney(row.Start, row.Currency),
+			FormatMoney(row.End, row.Currency), FormatMoney(row.Realized, row.Currency),
+			FormatMoney(row.Unrealized, row.Currency), FormatMoney(row.Income, row.Currency),
+			FormatMoney(row.Fees, row.Currency), FormatMoney(row.Total(), row.Currency))
+	}
+	return tw.Flush()
+}
+
+// usdValue converts the amount for sorting, amounts in unknown currencies are zero
+func usdValue(amount *big.Rat, currency string) float64 {
+	rate, ok := ExchangeRates[currency]
+	if !ok {
+		return 0
+	}
+	value, _ := (&big.Rat{}).Quo(amount, rate).Float64()
+	return value
+}
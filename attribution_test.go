@@ -0,0 +1,76 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+func TestAttributeResults(t *testing.T) {
+	date := time.Date(TaxYear, 3, 1, 12, 0, 0, 0, time.UTC)
+	operation := func(kind pb.OperationType, days int, quantity, units int64) *pb.OperationItem {
+		return &pb.OperationItem{
+			Type:     kind,
+			AssetUid: "a",
+			Date:     timestamppb.New(date.AddDate(0, 0, days)),
+			Quantity: quantity,
+			Payment:  &pb.MoneyValue{Currency: "usd", Units: units},
+		}
+	}
+	state := func(quantity, price int64) *State {
+		return &State{
+			Portfolio:  map[string]*big.Rat{"a": big.NewRat(quantity, 1), "usd": big.NewRat(1000, 1)},
+			Prices:     map[string]*big.Rat{"a": big.NewRat(price, 1)},
+			Currencies: map[string]string{"a": "usd"},
+		}
+	}
+	// newest first, as the operations are fetched
+	operations := []*pb.OperationItem{
+		operation(pb.OperationType_OPERATION_TYPE_DIVIDEND, 20, 0, 7),
+		operation(pb.OperationType_OPERATION_TYPE_BROKER_FEE, 10, 0, -1),
+		operation(pb.OperationType_OPERATION_TYPE_SELL, 10, 10, 150),
+		operation(pb.OperationType_OPERATION_TYPE_BUY, 0, 10, -140),
+		operation(pb.OperationType_OPERATION_TYPE_BUY, -400, 10, -50),
+	}
+	// 10 held at 10 since the start, 10 more bought at 14, 10 sold at 15 and 10 left worth 20 at the end
+	results := AttributeResults(operations, state(10, 10), state(10, 20))
+	if len(results) != 1 {
+		t.Fatalf("AttributeResults() = %v, want one asset", results)
+	}
+	result := results[0]
+	for _, test := range []struct {
+		name      string
+		got, want *big.Rat
+	}{
+		{"start", result.Start, big.NewRat(100, 1)},
+		{"end", result.End, big.NewRat(200, 1)},
+		{"realized", result.Realized, big.NewRat(30, 1)},
+		{"unrealized", result.Unrealized, big.NewRat(80, 1)},
+		{"income", result.Income, big.NewRat(7, 1)},
+		{"fees", result.Fees, big.NewRat(-1, 1)},
+		{"total", result.Total(), big.NewRat(116, 1)},
+	} {
+		if test.got.Cmp(test.want) != 0 {
+			t.Errorf("%s = %v, want %v", test.name, test.got, test.want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"opensource.tbank.ru/invest/invest-go/investgo"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+type operationTypeAudit struct {
+	count  int
+	totals map[string]*big.Rat
+}
+
+var InvalidOperationStateError = errors.New("invalid operation state")
+
+// operationStates are the names of operation states accepted by -audit-states
+var operationStates = map[string]pb.OperationState{
+	"executed": pb.OperationState_OPERATION_STATE_EXECUTED,
+	"canceled": pb.OperationState_OPERATION_STATE_CANCELED,
+	"progress": pb.OperationState_OPERATION_STATE_PROGRESS,
+}
+
+// ParseOperationStates parses a comma-separated list of operation states, "all" selects every state
+func ParseOperationStates(value string) ([]pb.OperationState, error) {
+	var states []pb.OperationState
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "all" {
+			return slices.Sorted(maps.Values(operationStates)), nil
+		}
+		state, ok := operationStates[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", InvalidOperationStateError, name)
+		}
+		if !slices.Contains(states, state) {
+			states = append(states, state)
+		}
+	}
+	return states, nil
+}
+
+type auditKey struct {
+	Type  pb.OperationType
+	State pb.OperationState
+}
+
+// Audit collects statistics of operation types by their state
+type Audit map[auditKey]*operationTypeAudit
+
+func (a Audit) Add(operation *pb.OperationItem) {
+	key := auditKey{operation.Type, operation.State}
+	entry, ok := a[key]
+	if !ok {
+		entry = &operationTypeAudit{totals: make(map[string]*big.Rat)}
+		a[key] = entry
+	}
+	entry.count++
+	if operation.Payment != nil {
+		currency := NormalizeCurrency(operation.Payment.Currency)
+		entry.totals[currency] = AddRat(entry.totals[currency], ToRat(operation.Payment))
+	}
+}
+
+func (a Audit) Print(w io.Writer) error {
+	tw := NewTable(w, 0)
+	fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", T("TYPE"), T("STATE"), T("SUPPORTED"), T("COUNT"), T("TOTALS"))
+	keys := slices.SortedFunc(maps.Keys(a), func(x, y auditKey) int {
+		return cmp.Or(strings.Compare(x.Type.String(), y.Type.String()), cmp.Compare(x.State, y.State))
+	})
+	for _, key := range keys {
+		entry := a[key]
+		_, err := OperationToUpdate(&pb.OperationItem{Type: key.Type})
+		supported := T("yes")
+		if err != nil {
+			supported = T("NO")
+		}
+		var totals []string
+		for _, currency := range slices.Sorted(maps.Keys(entry.totals)) {
+			totals = append(totals, entry.totals[currency].FloatString(2)+" "+currency)
+		}
+		state := strings.ToLower(strings.TrimPrefix(key.State.String(), "OPERATION_STATE_"))
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", key.Type, state, supported, entry.count, strings.Join(totals, ", "))
+	}
+	return tw.Flush()
+}
+
+// AuditOperations scans all operations of the states in the period without processing them,
+// operations which are not executed are logged one by one to inspect discrepancies
+func AuditOperations(op *investgo.OperationsServiceClient, logger *zap.Logger,
+	accountId string, from, to time.Time, states []pb.OperationState) (Audit, error) {
+	audit := make(Audit)
+	req := &investgo.GetOperationsByCursorRequest{
+		AccountId: accountId,
+		From:      from,
+		To:        to,
+	}
+	// the request takes a single state, several are filtered here
+	if len(states) == 1 {
+		req.State = states[0]
+	}
+	logger.Debug("getting operations for audit")
+	err := fetchOperations(op, logger, req, func(items []*pb.OperationItem, cursor string, hasNext bool) error {
+		for _, operation := range items {
+			if !slices.Contains(states, operation.State) {
+				continue
+			}
+			if operation.State != pb.OperationState_OPERATION_STATE_EXECUTED {
+				logger.Info("operation is not executed",
+					zap.String("operation", operation.Id),
+					zap.Stringer("state", operation.State),
+					zap.Stringer("type", operation.Type),
+					zap.Time("date", operation.Date.AsTime()),
+					zap.String("ticker", tickers[operation.AssetUid]),
+					zap.String("description", operation.Description),
+					zap.Int64("quantity", operation.Quantity),
+					zap.Stringer("payment", ToRat(operation.Payment)),
+					zap.String("currency", operation.Payment.GetCurrency()))
+			}
+			audit.Add(operation)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return audit, nil
+}
@@ -0,0 +1,139 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"maps"
+	"math/big"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Valuation policies for blocked assets and assets without market prices
+const (
+	// keep the last known price: the latest candle or the broker price
+	BlockedLast = "last"
+	// use the price from config
+	BlockedOverride = "override"
+	// do not count these assets at all
+	BlockedZero = "zero"
+)
+
+type BlockedAssetsOptions struct {
+	Policy string `yaml:"Policy"`
+	// ticker or asset UID -> price in the trading currency, used by the override policy
+	Prices map[string]string `yaml:"Prices"`
+}
+
+// LatestPrice is the price of the latest candle seen for the asset
+type LatestPrice struct {
+	Time     time.Time
+	Price    *big.Rat
+	Currency string
+}
+
+// ApplyBlockedPolicy sets the prices of affected assets in the current state,
+// they are kept until the latest candle, if any, as we are going back in time;
+// overridden prices are in the currency the asset was traded in, if it is not known otherwise
+func ApplyBlockedPolicy(logger *zap.Logger, options BlockedAssetsOptions, state *State,
+	affected map[string]bool, latest map[string]LatestPrice, traded map[string]string) {
+	overrides := make(map[string]*big.Rat, len(options.Prices))
+	for id, value := range options.Prices {
+		assetUid, ok := FindAsset(id)
+		if !ok {
+			logger.Warn("cannot find blocked asset", zap.String("asset", id))
+			continue
+		}
+		price, ok := (&big.Rat{}).SetString(value)
+		if !ok {
+			logger.Warn("invalid blocked asset price", zap.String("asset", id), zap.String("price", value))
+			continue
+		}
+		overrides[assetUid] = price
+	}
+	policy := options.Policy
+	switch policy {
+	case BlockedLast, BlockedOverride, BlockedZero:
+	case "":
+		policy = BlockedLast
+	default:
+		logger.Warn("unknown blocked assets policy, using last known price", zap.String("policy", policy))
+		policy = BlockedLast
+	}
+
+	for _, assetUid := range slices.SortedFunc(maps.Keys(affected), ByTicker) {
+		currency := state.Currencies[assetUid]
+		if currency == "" {
+			currency = latest[assetUid].Currency
+		}
+		if currency == "" {
+			currency = traded[assetUid]
+		}
+		if currency == "" {
+			for _, instrumentUid := range SortedInstruments() {
+				if assets[instrumentUid] == assetUid {
+					currency = instrumentCurrencies[instrumentUid]
+					break
+				}
+			}
+		}
+		switch policy {
+		case BlockedLast:
+			if last, ok := latest[assetUid]; ok {
+				state.Prices[assetUid] = last.Price
+				state.Currencies[assetUid] = last.Currency
+			}
+		case BlockedOverride:
+			if price, ok := overrides[assetUid]; ok {
+				state.Prices[assetUid] = price
+				state.Currencies[assetUid] = currency
+			} else {
+				logger.Warn("no price override for blocked asset, using last known price",
+					zap.String("ticker", tickers[assetUid]))
+				if last, ok := latest[assetUid]; ok {
+					state.Prices[assetUid] = last.Price
+					state.Currencies[assetUid] = last.Currency
+				}
+			}
+		case BlockedZero:
+			state.Prices[assetUid] = &big.Rat{}
+			state.Currencies[assetUid] = currency
+		}
+		if _, ok := state.Prices[assetUid]; !ok {
+			// prevent aggregation of assets without any price
+			state.Prices[assetUid] = &big.Rat{}
+			state.Currencies[assetUid] = currency
+		}
+		if state.Currencies[assetUid] == "" {
+			// the price cannot be converted, so the asset is not counted instead of breaking the aggregation
+			logger.Warn("blocked asset without a known currency, it is not counted",
+				zap.String("ticker", tickers[assetUid]),
+				zap.String("asset", assetUid))
+			state.Prices[assetUid] = &big.Rat{}
+			state.Currencies[assetUid] = "usd"
+		}
+		logger.Warn("blocked or unpriced asset",
+			zap.String("ticker", tickers[assetUid]),
+			zap.String("asset", assetUid),
+			zap.String("policy", policy),
+			zap.Time("latest_candle", latest[assetUid].Time),
+			zap.Stringer("price", state.Prices[assetUid]),
+			zap.String("currency", state.Currencies[assetUid]))
+	}
+}
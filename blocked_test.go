@@ -0,0 +1,42 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestApplyBlockedPolicyUnknownCurrency(t *testing.T) {
+	for _, policy := range []string{BlockedLast, BlockedOverride, BlockedZero} {
+		state := &State{
+			Portfolio:  map[string]*big.Rat{"unknown-asset": big.NewRat(10, 1), "rub": big.NewRat(100, 1)},
+			Prices:     make(map[string]*big.Rat),
+			Accrued:    make(map[string]*big.Rat),
+			Currencies: make(map[string]string),
+		}
+		ApplyBlockedPolicy(zap.NewNop(), BlockedAssetsOptions{Policy: policy}, state,
+			map[string]bool{"unknown-asset": true}, nil, nil)
+		_, _, aggregate := Cost(state, nil)
+		want := Aggregate(map[string]*big.Rat{"rub": big.NewRat(100, 1)})
+		if aggregate.Cmp(want) != 0 {
+			t.Errorf("%s: aggregate = %v, want %v without the asset", policy, aggregate, want)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/zap"
+	"opensource.tbank.ru/invest/invest-go/investgo"
+)
+
+// KindName returns the asset class of the portfolio key
+func KindName(key string) string {
+	if _, ok := ExchangeRates[key]; ok {
+		return "cash"
+	}
+	kind, ok := kinds[key]
+	if !ok {
+		return "unknown"
+	}
+	return strings.ToLower(strings.TrimPrefix(kind.String(), "INSTRUMENT_TYPE_"))
+}
+
+// Breakdown sums the values by the group of each key
+func Breakdown(values map[string]*big.Rat, group func(key string) string) map[string]*big.Rat {
+	result := make(map[string]*big.Rat)
+	for key, value := range values {
+		name := group(key)
+		result[name] = AddRat(result[name], value)
+	}
+	return result
+}
+
+// PrintBreakdown prints the values in USD and their shares of the total
+func PrintBreakdown(w io.Writer, header string, breakdown map[string]*big.Rat) error {
+	total := new(big.Rat)
+	for _, value := range breakdown {
+		total = AddRat(total, value)
+	}
+	tw := NewTable(w, tabwriter.AlignRight)
+	fmt.Fprintf(tw, "%s\tUSD\t%s\t\n", T(header), T("SHARE"))
+	for _, name := range slices.Sorted(maps.Keys(breakdown)) {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t\n", name, FormatUSD(breakdown[name]), percent(breakdown[name], total))
+	}
+	tw.Total()
+	fmt.Fprintf(tw, "%s\t%s\t%s\t\n", T("total"), FormatUSD(total), percent(total, total))
+	return tw.Flush()
+}
+
+// PrintBreakdowns prints the composition tables of the account
+func PrintBreakdowns(in *investgo.InstrumentsServiceClient, logger *zap.Logger, evaluation *Evaluation) error {
+	account := Redact("account", evaluation.AccountId)
+	fmt.Printf(T("Account %s %q, %s"), account, Redact("name", evaluation.Account.Name), evaluation.Account.Type)
+	if evaluation.Account.IISType != "" {
+		fmt.Printf(T(" type %s"), evaluation.Account.IISType)
+	}
+	if evaluation.Account.Strategy {
+		fmt.Print(T(", auto-following strategy"))
+	}
+	if evaluation.Account.OpenedDate != nil {
+		fmt.Printf(T(", opened %s"), evaluation.Account.OpenedDate.Format(time.DateOnly))
+	}
+	if evaluation.Account.ClosedDate != nil {
+		fmt.Printf(T(", closed %s"), evaluation.Account.ClosedDate.Format(time.DateOnly))
+	}
+	fmt.Println()
+	fmt.Printf(T("Account %s maximum value %s at %s\n"), account,
+		FormatUSD(evaluation.BestAggregate), evaluation.BestTime)
+	if result := evaluation.Conservative; result != nil {
+		fmt.Printf(T("Account %s conservative maximum value %s at %s by close prices, carryforward up to %s "+
+			"and liabilities netted\n"), account, FormatUSD(result.Best.Aggregate), result.Best.Time, result.CarryLimit)
+	}
+	if evaluation.PeakOffSession {
+		fmt.Printf(T("Account %s maximum is outside the trading sessions of %s\n"), account, tradingCalendar.Exchange)
+	}
+	fmt.Printf(T("Account %s value stayed above %s of the maximum for %s around the peak, %s in total\n"), account,
+		percent(PeakBand, big.NewRat(1, 1)), evaluation.Persistence.Sustained, evaluation.Persistence.Total)
+	fmt.Printf(T("Account %s maximum securities value %s at %s\n"), account,
+		FormatUSD(evaluation.BestSecurities.Aggregate), evaluation.BestSecurities.Time)
+	fmt.Printf(T("Account %s maximum cash value %s at %s\n"), account,
+		FormatUSD(evaluation.BestCash.Aggregate), evaluation.BestCash.Time)
+	if liabilities := Liabilities(evaluation.BestState, evaluation.Excluded); liabilities.Sign() != 0 {
+		format := T("Account %s liabilities at peak %s are included in the maximum\n")
+		if LiabilitiesPolicy == LiabilitiesGross {
+			format = T("Account %s liabilities at peak %s are not included in the maximum\n")
+		}
+		fmt.Printf(format, account, FormatUSD(liabilities))
+	}
+	fmt.Printf(T("Account %s first threshold crossings\n"), account)
+	err := PrintThresholds(os.Stdout, evaluation.Thresholds)
+	if err != nil {
+		logger.Error("error printing thresholds", zap.Error(err))
+		return err
+	}
+	portfolio := maps.Clone(evaluation.BestState.Portfolio)
+	excluded := Exclude(portfolio, evaluation.Excluded)
+	fmt.Printf(T("Account %s holdings at peak %s\n"), account, evaluation.BestTime)
+	err = PrintHoldings(os.Stdout, evaluation.BestState, portfolio)
+	if err == nil && len(excluded) > 0 {
+		fmt.Printf(T("Account %s excluded assets at peak %s\n"), account, evaluation.BestTime)
+		err = PrintHoldings(os.Stdout, evaluation.BestState, excluded)
+	}
+	if err != nil {
+		logger.Error("error printing holdings", zap.Error(err))
+		return err
+	}
+	if ages := PriceAges(evaluation.Series, evaluation.BestState.Portfolio, evaluation.BestTime); len(ages) > 0 {
+		fmt.Printf(T("Account %s price age at peak %s\n"), account, evaluation.BestTime)
+		err = PrintPriceAges(os.Stdout, ages)
+		if err != nil {
+			logger.Error("error printing price ages", zap.Error(err))
+			return err
+		}
+	}
+	fmt.Printf(T("Account %s currency exposure at peak %s\n"), account, evaluation.BestTime)
+	err = PrintExposure(os.Stdout, evaluation.BestCost)
+	if err == nil && evaluation.YearEndCost != nil {
+		fmt.Printf(T("Account %s currency exposure at year end %s\n"), account, evaluation.YearEndTime)
+		err = PrintExposure(os.Stdout, evaluation.YearEndCost)
+	}
+	if err != nil {
+		logger.Error("error printing currency exposure", zap.Error(err))
+		return err
+	}
+
+	fmt.Printf(T("Account %s yearly totals\n"), account)
+	depositsName := T("deposits")
+	if evaluation.Account.IsIIS() {
+		depositsName = T("iis contributions")
+	}
+	err = PrintTotals(os.Stdout, []Total{
+		{depositsName, evaluation.Contributions()},
+		{T("withdrawals"), YearTotals(evaluation.Operations, WithdrawalTypes)},
+		{T("fees"), YearTotals(evaluation.Operations, FeeTypes)},
+		{T("taxes"), YearTotals(evaluation.Operations, TaxTypes)},
+	})
+	if err != nil {
+		logger.Error("error printing yearly totals", zap.Error(err))
+		return err
+	}
+
+	moments := []struct {
+		name  string
+		state *State
+	}{
+		{fmt.Sprintf(T("at peak %s"), evaluation.BestTime), evaluation.BestState},
+		{T("now"), evaluation.CurrentState},
+	}
+	if *composition {
+		err = LoadSectors(in, logger, evaluation.BestState.Portfolio, evaluation.CurrentState.Portfolio)
+		if err != nil {
+			return err
+		}
+	}
+	for _, moment := range moments {
+		values := AssetValues(moment.state, evaluation.Excluded)
+		fmt.Printf(T("Account %s asset classes %s\n"), account, moment.name)
+		err = PrintBreakdown(os.Stdout, "CLASS", Breakdown(values, KindName))
+		if err == nil && *composition {
+			fmt.Printf(T("Account %s countries %s\n"), account, moment.name)
+			err = PrintBreakdown(os.Stdout, "COUNTRY", Breakdown(values, CountryName))
+		}
+		if err == nil && *composition {
+			fmt.Printf(T("Account %s sectors %s\n"), account, moment.name)
+			err = PrintBreakdown(os.Stdout, "SECTOR", Breakdown(values, SectorName))
+		}
+		if err != nil {
+			logger.Error("error printing composition", zap.Error(err))
+			return err
+		}
+	}
+	if *maxima != "" {
+		periods, err := PeriodMaxima(evaluation.Timeline, *maxima)
+		if err == nil {
+			format := T("Account %s daily maximum values\n")
+			if *maxima == PeriodWeek {
+				format = T("Account %s weekly maximum values\n")
+			}
+			fmt.Printf(format, account)
+			err = PrintPeriodMaxima(os.Stdout, periods, evaluation.BestAggregate)
+		}
+		if err != nil {
+			logger.Error("error printing maxima", zap.Error(err))
+			return err
+		}
+	}
+	return nil
+}
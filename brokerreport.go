@@ -0,0 +1,244 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"maps"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"opensource.tbank.ru/invest/invest-go/investgo"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// MonthEnds keeps the reconstructed state at the end of each month of the tax year,
+// index 0 is the start of the year
+type MonthEnds [13]*State
+
+// Observe is called for every state in reverse order, the last call for each boundary wins,
+// as it is the state just after the boundary
+func (m *MonthEnds) Observe(date time.Time, state *State) {
+	date = date.In(Location)
+	switch {
+	case date.Year() == TaxYear:
+		m[date.Month()-1] = state
+	case date.Year() == TaxYear+1 && date.Month() == time.January:
+		m[12] = state
+	}
+}
+
+func getBrokerReport(op *investgo.OperationsServiceClient, logger *zap.Logger,
+	accountId string, from, to time.Time) ([]*pb.BrokerReport, error) {
+	logger.Debug("requesting broker report", zap.Time("from", from), zap.Time("to", to))
+	AwaitQuota("GenerateBrokerReport")
+	start := time.Now()
+	task, err := op.GenerateBrokerReport(accountId, from, to)
+	TraceCall("GenerateBrokerReport", reportRequest{accountId, from, to}, start, task, err)
+	if err != nil {
+		return nil, err
+	}
+	var result []*pb.BrokerReport
+	for page := int32(0); ; page++ {
+		resp, err := pollReport(logger, func() (*investgo.GetBrokerReportResponse, error) {
+			AwaitQuota("GetBrokerReport")
+			start := time.Now()
+			resp, err := op.GetBrokerReport(task.TaskId, page)
+			TraceCall("GetBrokerReport", reportPageRequest{task.TaskId, page}, start, resp, err)
+			return resp, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, resp.BrokerReport...)
+		if page+1 >= resp.PagesCount {
+			return result, nil
+		}
+	}
+}
+
+func isBuy(trade *pb.BrokerReport) bool {
+	direction := strings.ToLower(trade.Direction)
+	return strings.HasPrefix(direction, "покуп") || strings.HasPrefix(direction, "buy")
+}
+
+// diffRats returns keys with different values
+func diffRats(a, b map[string]*big.Rat) []string {
+	var keys []string
+	for key, value := range a {
+		if SubRat(value, b[key]).Sign() != 0 {
+			keys = append(keys, key)
+		}
+	}
+	for key, value := range b {
+		if _, ok := a[key]; !ok && value.Sign() != 0 {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// PortfolioChanges are the changes of the portfolio by time, applied forward
+type PortfolioChanges map[time.Time][]func(portfolio map[string]*big.Rat)
+
+// BrokerReportChanges returns the changes of the portfolio by the broker reports: the trades of the broker report,
+// the foreign dividends of the foreign issuer report, and the other operations and the corporate actions,
+// which are not in the reports
+func BrokerReportChanges(trades []*pb.BrokerReport, foreign []*pb.DividendsForeignIssuerReport,
+	operations []*pb.OperationItem, actions []CorporateAction) (PortfolioChanges, error) {
+	changes := make(PortfolioChanges)
+	add := func(date time.Time, delta map[string]*big.Rat) {
+		changes[date] = append(changes[date], func(portfolio map[string]*big.Rat) {
+			addDelta(portfolio, delta)
+		})
+	}
+	for _, trade := range trades {
+		if trade.TradeDatetime == nil {
+			continue
+		}
+		key, ok := FindAsset(trade.Ticker)
+		if !ok {
+			key = trade.Ticker
+		}
+		quantity := big.NewRat(trade.Quantity, 1)
+		amount := ToRat(trade.TotalOrderAmount)
+		if isBuy(trade) {
+			amount.Neg(amount)
+		} else {
+			quantity.Neg(quantity)
+		}
+		currency := NormalizeCurrency(trade.TotalOrderAmount.GetCurrency())
+		add(trade.TradeDatetime.AsTime(), map[string]*big.Rat{key: quantity, currency: amount})
+	}
+	for _, row := range foreign {
+		if row.PaymentDate == nil || row.DividendAmount == nil {
+			continue
+		}
+		add(row.PaymentDate.AsTime(), map[string]*big.Rat{NormalizeCurrency(row.Currency): ToRat(row.DividendAmount)})
+	}
+	for _, operation := range operations {
+		switch operation.Type {
+		case pb.OperationType_OPERATION_TYPE_BUY, pb.OperationType_OPERATION_TYPE_SELL:
+			continue
+		case pb.OperationType_OPERATION_TYPE_DIVIDEND, pb.OperationType_OPERATION_TYPE_DIVIDEND_TAX:
+			// the foreign issuer report has the final amounts of these
+			if IsForeign(isins[operation.AssetUid]) {
+				continue
+			}
+		}
+		delta, err := OperationDelta(operation)
+		if err != nil {
+			return nil, err
+		}
+		add(operation.Date.AsTime(), delta)
+	}
+	for _, action := range actions {
+		change, err := CorporateActionChange(action)
+		// skipped with a warning by the backward replay too
+		if errors.Is(err, UnknownAssetError) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		changes[action.Date] = append(changes[action.Date], change)
+	}
+	return changes, nil
+}
+
+// CompareMonth applies the changes of the month to the reconstructed portfolio at its start and compares
+// the holdings and the cash with the reconstructed portfolio at its end, it returns the number of discrepancies
+func CompareMonth(logger *zap.Logger, month int, start, end *State, changes PortfolioChanges) int {
+	from := time.Date(TaxYear, time.Month(month), 1, 0, 0, 0, 0, Location)
+	to := from.AddDate(0, 1, 0)
+	portfolio := maps.Clone(start.Portfolio)
+	for _, date := range slices.SortedFunc(maps.Keys(changes), time.Time.Compare) {
+		if date.Before(from) || !date.Before(to) {
+			continue
+		}
+		for _, change := range changes[date] {
+			change(portfolio)
+		}
+	}
+	expected, reconstructed := ToTickers(portfolio), ToTickers(end.Portfolio)
+	mismatches := 0
+	for _, key := range diffRats(expected, reconstructed) {
+		mismatches++
+		message := "month end position differs from broker report"
+		field := zap.String("ticker", key)
+		if _, ok := ExchangeRates[key]; ok {
+			message = "month end cash differs from broker report"
+			field = zap.String("currency", key)
+		}
+		logger.Warn(message,
+			zap.Int("month", month),
+			field,
+			zap.Stringer("report", AddRat(expected[key], nil)),
+			zap.Stringer("reconstructed", AddRat(reconstructed[key], nil)))
+	}
+	return mismatches
+}
+
+// CompareBrokerReports fetches the monthly broker reports and the foreign issuer dividends report, and compares
+// the reconstructed holdings and cash at each month end with the ones expected by them from the month start,
+// it returns the number of discrepancies found
+func CompareBrokerReports(op *investgo.OperationsServiceClient, logger *zap.Logger, accountId string,
+	months *MonthEnds, operations []*pb.OperationItem, actions []CorporateAction, now time.Time) (int, error) {
+	yearEnd := time.Date(TaxYear+1, 1, 1, 0, 0, 0, 0, Location)
+	if now.Before(yearEnd) {
+		yearEnd = now
+	}
+	foreign, err := getDividendsForeignIssuerReport(op, logger, accountId,
+		time.Date(TaxYear, 1, 1, 0, 0, 0, 0, Location), yearEnd)
+	if err != nil {
+		return 0, err
+	}
+	var trades []*pb.BrokerReport
+	for month := 1; month <= 12; month++ {
+		from := time.Date(TaxYear, time.Month(month), 1, 0, 0, 0, 0, Location)
+		if from.After(now) {
+			break
+		}
+		report, err := getBrokerReport(op, logger, accountId, from, from.AddDate(0, 1, 0))
+		if err != nil {
+			return 0, err
+		}
+		trades = append(trades, report...)
+	}
+	changes, err := BrokerReportChanges(trades, foreign, operations, actions)
+	if err != nil {
+		return 0, err
+	}
+
+	mismatches := 0
+	for month := 1; month <= 12; month++ {
+		if time.Date(TaxYear, time.Month(month), 1, 0, 0, 0, 0, Location).After(now) {
+			break
+		}
+		if months[month-1] == nil || months[month] == nil {
+			logger.Warn("no reconstructed state for month", zap.Int("month", month))
+			continue
+		}
+		mismatches += CompareMonth(logger, month, months[month-1], months[month], changes)
+		logger.Info("month compared with broker report", zap.Int("month", month))
+	}
+	return mismatches, nil
+}
@@ -0,0 +1,90 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+func TestCompareMonth(t *testing.T) {
+	tickers["report-share"], isins["report-share"] = "SHARE", "RU0000000001"
+	tickers["foreign-share"], isins["foreign-share"] = "FOREIGN", "US0000000001"
+	defer func() {
+		delete(tickers, "report-share")
+		delete(isins, "report-share")
+		delete(tickers, "foreign-share")
+		delete(isins, "foreign-share")
+	}()
+	date := func(day int) *timestamppb.Timestamp {
+		return timestamppb.New(time.Date(TaxYear, 3, day, 12, 0, 0, 0, Location))
+	}
+	trades := []*pb.BrokerReport{{
+		Ticker:           "SHARE",
+		Direction:        "Покупка",
+		Quantity:         5,
+		TradeDatetime:    date(3),
+		TotalOrderAmount: &pb.MoneyValue{Currency: "RUB", Units: 500},
+	}}
+	foreign := []*pb.DividendsForeignIssuerReport{{
+		PaymentDate:    date(10),
+		Isin:           "US0000000001",
+		DividendAmount: &pb.Quotation{Units: 9},
+		Currency:       "usd",
+	}}
+	operations := []*pb.OperationItem{
+		// the trade is in the broker report
+		{Type: pb.OperationType_OPERATION_TYPE_BUY, AssetUid: "report-share", Quantity: 5, Date: date(3),
+			Payment: &pb.MoneyValue{Currency: "rub", Units: -500}},
+		{Type: pb.OperationType_OPERATION_TYPE_BROKER_FEE, Date: date(3),
+			Payment: &pb.MoneyValue{Currency: "rub", Units: -5}},
+		// the foreign dividend is in the foreign issuer report
+		{Type: pb.OperationType_OPERATION_TYPE_DIVIDEND, AssetUid: "foreign-share", Date: date(11),
+			Payment: &pb.MoneyValue{Currency: "usd", Units: 10}},
+		{Type: pb.OperationType_OPERATION_TYPE_DIVIDEND_TAX, AssetUid: "foreign-share", Date: date(11),
+			Payment: &pb.MoneyValue{Currency: "usd", Units: -1}},
+		{Type: pb.OperationType_OPERATION_TYPE_INPUT, Date: date(20),
+			Payment: &pb.MoneyValue{Currency: "rub", Units: 1000}},
+		// next month
+		{Type: pb.OperationType_OPERATION_TYPE_INPUT, Date: timestamppb.New(time.Date(TaxYear, 4, 1, 12, 0, 0, 0, Location)),
+			Payment: &pb.MoneyValue{Currency: "rub", Units: 1000}},
+	}
+	actions := []CorporateAction{{Date: time.Date(TaxYear, 3, 15, 0, 0, 0, 0, Location), Asset: "SHARE", Ratio: "2"}}
+	changes, err := BrokerReportChanges(trades, foreign, operations, actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := &State{Portfolio: map[string]*big.Rat{"report-share": big.NewRat(10, 1), "rub": big.NewRat(1000, 1)}}
+	end := &State{Portfolio: map[string]*big.Rat{
+		"report-share": big.NewRat(30, 1),
+		"rub":          big.NewRat(1495, 1),
+		"usd":          big.NewRat(9, 1),
+	}}
+	if mismatches := CompareMonth(zap.NewNop(), 3, start, end, changes); mismatches != 0 {
+		t.Errorf("CompareMonth() = %d, want no mismatches", mismatches)
+	}
+	end.Portfolio["report-share"] = big.NewRat(29, 1)
+	end.Portfolio["usd"] = big.NewRat(10, 1)
+	if mismatches := CompareMonth(zap.NewNop(), 3, start, end, changes); mismatches != 2 {
+		t.Errorf("CompareMonth() = %d, want a position and a cash mismatch", mismatches)
+	}
+}
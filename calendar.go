@@ -0,0 +1,129 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	"opensource.tbank.ru/invest/invest-go/investgo"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// The trading schedules are requested by two weeks at most
+const tradingSchedulesWindow = 14 * 24 * time.Hour
+
+type TradingCalendarOptions struct {
+	// exchange of the trading schedules, e.g. MOEX, the calendar is not used if empty
+	Exchange string `yaml:"Exchange"`
+	// search the maximum within the trading sessions only
+	SessionsOnly bool `yaml:"SessionsOnly"`
+}
+
+// session is the trading hours of a trading day
+type session struct {
+	start, end time.Time
+}
+
+// TradingCalendar is the trading days and sessions of an exchange during the tax year
+type TradingCalendar struct {
+	Exchange     string
+	SessionsOnly bool
+	// sessions in ascending time order
+	sessions []session
+	// date in the reporting timezone -> whether it is a trading day
+	days map[string]bool
+}
+
+// tradingCalendar is shared by all accounts, nil if it is not used
+var tradingCalendar *TradingCalendar
+
+// LoadTradingCalendar gets the trading schedules of the exchange for the tax year
+func LoadTradingCalendar(in *investgo.InstrumentsServiceClient, logger *zap.Logger,
+	options TradingCalendarOptions) (*TradingCalendar, error) {
+	calendar := &TradingCalendar{
+		Exchange:     options.Exchange,
+		SessionsOnly: options.SessionsOnly,
+		days:         make(map[string]bool),
+	}
+	end := time.Date(TaxYear+1, 1, 1, 0, 0, 0, 0, Location)
+	for from := time.Date(TaxYear, 1, 1, 0, 0, 0, 0, Location); from.Before(end); from = from.Add(tradingSchedulesWindow) {
+		to := from.Add(tradingSchedulesWindow)
+		if to.After(end) {
+			to = end
+		}
+		AwaitQuota("TradingSchedules")
+		start := time.Now()
+		resp, err := in.TradingSchedules(options.Exchange, from, to)
+		TraceCall("TradingSchedules", options.Exchange, start, resp, err)
+		if err != nil {
+			logger.Error("error getting trading schedules",
+				zap.String("exchange", options.Exchange),
+				zap.Time("from", from),
+				zap.Error(err))
+			return nil, err
+		}
+		for _, schedule := range resp.Exchanges {
+			for _, day := range schedule.Days {
+				calendar.addDay(day)
+			}
+		}
+	}
+	sort.Slice(calendar.sessions, func(i, j int) bool {
+		return calendar.sessions[i].start.Before(calendar.sessions[j].start)
+	})
+	logger.Info("loaded trading calendar",
+		zap.String("exchange", options.Exchange),
+		zap.Int("days", len(calendar.days)),
+		zap.Int("trading_days", len(calendar.sessions)))
+	return calendar, nil
+}
+
+func (c *TradingCalendar) addDay(day *pb.TradingDay) {
+	date := day.Date.AsTime()
+	key := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC).Format(time.DateOnly)
+	c.days[key] = day.IsTradingDay
+	if day.IsTradingDay && day.StartTime != nil && day.EndTime != nil {
+		c.sessions = append(c.sessions, session{start: day.StartTime.AsTime(), end: day.EndTime.AsTime()})
+	}
+}
+
+// InSession tells whether the time is within a trading session
+func (c *TradingCalendar) InSession(date time.Time) bool {
+	i := sort.Search(len(c.sessions), func(i int) bool {
+		return c.sessions[i].end.After(date)
+	})
+	return i < len(c.sessions) && !c.sessions[i].start.After(date)
+}
+
+// TradingDay tells whether the date is a trading day, known is false for dates out of the schedules
+func (c *TradingCalendar) TradingDay(date time.Time) (trading, known bool) {
+	trading, known = c.days[date.Format(time.DateOnly)]
+	return trading, known
+}
+
+// countsForMaximum tells whether a point may be the maximum, the points outside the trading sessions
+// are only skipped if the calendar is used with SessionsOnly
+func countsForMaximum(date time.Time) bool {
+	return tradingCalendar == nil || !tradingCalendar.SessionsOnly || tradingCalendar.InSession(date)
+}
+
+// outsideSessions tells whether the calendar is used and the time is outside the trading sessions
+func outsideSessions(date time.Time) bool {
+	return tradingCalendar != nil && !tradingCalendar.InSession(date)
+}
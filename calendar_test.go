@@ -0,0 +1,72 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+func TestTradingCalendar(t *testing.T) {
+	defer func() { tradingCalendar = nil }()
+	day := func(date time.Time, trading bool) *pb.TradingDay {
+		result := &pb.TradingDay{Date: timestamppb.New(date), IsTradingDay: trading}
+		if trading {
+			result.StartTime = timestamppb.New(date.Add(7 * time.Hour))
+			result.EndTime = timestamppb.New(date.Add(15*time.Hour + 50*time.Minute))
+		}
+		return result
+	}
+	friday := time.Date(2025, 3, 7, 0, 0, 0, 0, time.UTC)
+	tradingCalendar = &TradingCalendar{Exchange: "MOEX", days: make(map[string]bool)}
+	for i := range 4 {
+		date := friday.AddDate(0, 0, i)
+		tradingCalendar.addDay(day(date, date.Weekday() != time.Saturday && date.Weekday() != time.Sunday))
+	}
+	for _, test := range []struct {
+		date time.Time
+		want bool
+	}{
+		{friday.Add(6 * time.Hour), false},
+		{friday.Add(7 * time.Hour), true},
+		{friday.Add(15 * time.Hour), true},
+		{friday.Add(16 * time.Hour), false},
+		{friday.AddDate(0, 0, 1).Add(10 * time.Hour), false},
+		{friday.AddDate(0, 0, 3).Add(10 * time.Hour), true},
+	} {
+		if got := tradingCalendar.InSession(test.date); got != test.want {
+			t.Errorf("InSession(%s) = %v, want %v", test.date, got, test.want)
+		}
+		if !countsForMaximum(test.date) {
+			t.Errorf("countsForMaximum(%s) = false without SessionsOnly", test.date)
+		}
+	}
+	if trading, known := tradingCalendar.TradingDay(friday.AddDate(0, 0, 2)); trading || !known {
+		t.Errorf("TradingDay(Sunday) = %v, %v, want a known non-trading day", trading, known)
+	}
+	tradingCalendar.SessionsOnly = true
+	timeline := Timeline{
+		{Time: friday.Add(10 * time.Hour), Aggregate: rat("100")},
+		{Time: friday.AddDate(0, 0, 1).Add(10 * time.Hour), Aggregate: rat("120")},
+	}
+	if best := timeline.Best(); !best.Time.Equal(timeline[0].Time) {
+		t.Errorf("Best() = %v, want the point within the sessions", best)
+	}
+}
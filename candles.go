@@ -0,0 +1,209 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"opensource.tbank.ru/invest/invest-go/investgo"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// Policies for candle fetch failures
+const (
+	CandleErrorFail  = "fail"
+	CandleErrorSkip  = "skip"
+	CandleErrorRetry = "retry"
+)
+
+// Retries of the retry policy before the instrument is skipped
+const (
+	candleRetries       = 3
+	candleRetryInterval = 10 * time.Second
+)
+
+// Candle sources: weekend prints of the over-the-counter trading may set another maximum than
+// the exchange sessions
+const (
+	CandleSourceWeekend  = "weekend"
+	CandleSourceExchange = "exchange"
+)
+
+var candleSources = map[string]pb.GetCandlesRequest_CandleSource{
+	CandleSourceWeekend:  pb.GetCandlesRequest_CANDLE_SOURCE_INCLUDE_WEEKEND,
+	CandleSourceExchange: pb.GetCandlesRequest_CANDLE_SOURCE_EXCHANGE,
+}
+
+var candleSourceNames = map[string]string{
+	CandleSourceWeekend:  "exchange sessions and weekend trading",
+	CandleSourceExchange: "exchange sessions only",
+}
+
+// CandleSource is the source of the candles, the exchange sessions and the weekends by default
+var CandleSource = CandleSourceWeekend
+
+// candlesKey is the name of the candles in the caches and the store,
+// the candles of other sources than the default one are kept apart
+func candlesKey(instrumentUid string) string {
+	if CandleSource == CandleSourceWeekend {
+		return instrumentUid
+	}
+	return CandleSource + "-" + instrumentUid
+}
+
+// CandleFilterOptions limit the candle downloads, e.g. to debug a single asset quickly, the assets are
+// tickers, ISINs, asset or instrument UIDs
+type CandleFilterOptions struct {
+	// only these assets get candles if set
+	Only []string `yaml:"Only"`
+	// these assets never get candles, e.g. known problematic ones
+	Skip []string `yaml:"Skip"`
+}
+
+func matchesAsset(ids []string, assetUid, instrumentUid string) bool {
+	for _, id := range ids {
+		if id == assetUid || id == instrumentUid || id == tickers[assetUid] || id == isins[assetUid] {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows tells whether the candles of the instrument are downloaded, the filtered out assets are valued
+// like the assets without candles
+func (o CandleFilterOptions) Allows(assetUid, instrumentUid string) bool {
+	if len(o.Only) > 0 && !matchesAsset(o.Only, assetUid, instrumentUid) {
+		return false
+	}
+	return !matchesAsset(o.Skip, assetUid, instrumentUid)
+}
+
+// CandleErrorPolicies map gRPC error codes, e.g. NotFound or Unavailable, to policies,
+// the "default" key is used for other codes
+type CandleErrorPolicies map[string]string
+
+// For returns the policy for the error, missing candles are skipped and other errors fail by default
+func (p CandleErrorPolicies) For(err error) string {
+	code := status.Code(err)
+	if policy, ok := p[code.String()]; ok {
+		return policy
+	}
+	if policy, ok := p["default"]; ok {
+		return policy
+	}
+	if code == codes.NotFound {
+		return CandleErrorSkip
+	}
+	return CandleErrorFail
+}
+
+// SkippedInstrument is an instrument valued without candles
+type SkippedInstrument struct {
+	InstrumentUid string
+	AssetUid      string
+	Code          codes.Code
+}
+
+// getCandlesWithPolicy applies the error policy, for skipped instruments it returns their error as skipped
+func getCandlesWithPolicy(md *investgo.MarketDataServiceClient, logger *zap.Logger, policies CandleErrorPolicies,
+	instrumentUid string) (candles []*pb.HistoricCandle, skipped error, err error) {
+	for attempt := 0; ; attempt++ {
+		candles, err := getCandles(md, instrumentUid)
+		if err == nil {
+			return candles, nil, nil
+		}
+		switch policies.For(err) {
+		case CandleErrorSkip:
+			return nil, err, nil
+		case CandleErrorRetry:
+			if attempt >= candleRetries {
+				return nil, err, nil
+			}
+			logger.Warn("retrying candles request",
+				zap.String("instrument", instrumentUid),
+				zap.Int("attempt", attempt+1),
+				zap.Error(err))
+			time.Sleep(candleRetryInterval)
+		default:
+			return nil, nil, err
+		}
+	}
+}
+
+// getFallbackCandles tries daily candles and then the latest official close price,
+// so an instrument without hourly candles still has a reasonable value
+func getFallbackCandles(md *investgo.MarketDataServiceClient, logger *zap.Logger,
+	instrumentUid string) []*pb.HistoricCandle {
+	logger.Debug("getting daily candles", zap.String("instrument", instrumentUid))
+	req := &investgo.GetHistoricCandlesRequest{
+		Instrument: instrumentUid,
+		Interval:   pb.CandleInterval_CANDLE_INTERVAL_DAY,
+		From:       time.Date(TaxYear, 1, 1, 0, 0, 0, 0, Location),
+		To:         candlesTo(),
+		Source:     candleSources[CandleSource],
+	}
+	AwaitQuota("GetCandles")
+	start := time.Now()
+	candles, err := md.GetHistoricCandles(req)
+	TraceCall("GetCandles", req, start, candles, err)
+	if err == nil && len(candles) > 0 {
+		logger.Info("using daily candles", zap.String("instrument", instrumentUid))
+		return candles
+	}
+	logger.Debug("getting close price", zap.String("instrument", instrumentUid), zap.Error(err))
+	AwaitQuota("GetClosePrices")
+	start = time.Now()
+	resp, err := md.GetClosePrices([]string{instrumentUid})
+	TraceCall("GetClosePrices", instrumentUid, start, resp, err)
+	if err != nil {
+		logger.Debug("cannot get close price", zap.String("instrument", instrumentUid), zap.Error(err))
+		return nil
+	}
+	for _, closePrice := range resp.ClosePrices {
+		if closePrice.Price == nil || closePrice.Time == nil {
+			continue
+		}
+		logger.Info("using close price", zap.String("instrument", instrumentUid), zap.Time("time", closePrice.Time.AsTime()))
+		return []*pb.HistoricCandle{{Time: closePrice.Time, High: closePrice.Price}}
+	}
+	return nil
+}
+
+// ReportSkipped logs the instruments valued without candles and their current value as the potential impact
+func ReportSkipped(logger *zap.Logger, skipped []SkippedInstrument, state *State, excluded map[string]bool) {
+	if len(skipped) == 0 {
+		return
+	}
+	values := AssetValues(state, excluded)
+	impact := new(big.Rat)
+	for _, instrument := range skipped {
+		impact = AddRat(impact, values[instrument.AssetUid])
+		logger.Warn("instrument valued without candles",
+			zap.String("instrument", instrument.InstrumentUid),
+			zap.String("ticker", tickers[instrument.AssetUid]),
+			zap.Stringer("code", instrument.Code),
+			zap.Stringer("quantity", AddRat(state.Portfolio[instrument.AssetUid], nil)),
+			zap.String("current_value", FormatUSD(values[instrument.AssetUid])))
+	}
+	logger.Warn("some instruments were valued by the blocked assets policy",
+		zap.Int("instruments", len(skipped)),
+		zap.String("current_value", FormatUSD(impact)))
+}
@@ -0,0 +1,42 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import "testing"
+
+func TestCandleFilterAllows(t *testing.T) {
+	tickers["asset-sber"] = "SBER"
+	isins["asset-sber"] = "RU0009029540"
+	defer delete(tickers, "asset-sber")
+	defer delete(isins, "asset-sber")
+	for _, test := range []struct {
+		name    string
+		filter  CandleFilterOptions
+		allowed bool
+	}{
+		{"all by default", CandleFilterOptions{}, true},
+		{"only by ticker", CandleFilterOptions{Only: []string{"SBER"}}, true},
+		{"only another", CandleFilterOptions{Only: []string{"GAZP"}}, false},
+		{"skip by ISIN", CandleFilterOptions{Skip: []string{"RU0009029540"}}, false},
+		{"skip by instrument", CandleFilterOptions{Skip: []string{"instrument-sber"}}, false},
+		{"skip wins", CandleFilterOptions{Only: []string{"SBER"}, Skip: []string{"asset-sber"}}, false},
+	} {
+		if got := test.filter.Allows("asset-sber", "instrument-sber"); got != test.allowed {
+			t.Errorf("%s: Allows() = %v, want %v", test.name, got, test.allowed)
+		}
+	}
+}
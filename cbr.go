@@ -0,0 +1,104 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+var UnknownCBRCurrencyError = errors.New("currency is missing in the CBR rates")
+
+// Official rates of the Central Bank of Russia, the XML is in windows-1251
+const cbrRatesURL = "https://www.cbr.ru/scripts/XML_daily.asp?date_req=%s"
+
+type cbrRates struct {
+	Valutes []struct {
+		CharCode string `xml:"CharCode"`
+		Nominal  string `xml:"Nominal"`
+		Value    string `xml:"Value"`
+	} `xml:"Valute"`
+}
+
+// date -> currency -> RUB for one unit
+var cbrCache = make(map[string]map[string]*big.Rat)
+
+func getCBRRates(date string) (map[string]*big.Rat, error) {
+	if rates, ok := cbrCache[date]; ok {
+		return rates, nil
+	}
+	resp, err := http.Get(fmt.Sprintf(cbrRatesURL, date))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("CBR rates request failed: %s", resp.Status)
+	}
+	decoder := xml.NewDecoder(resp.Body)
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		if strings.EqualFold(charset, "windows-1251") {
+			return charmap.Windows1251.NewDecoder().Reader(input), nil
+		}
+		return nil, fmt.Errorf("unsupported charset %s", charset)
+	}
+	var parsed cbrRates
+	err = decoder.Decode(&parsed)
+	if err != nil {
+		return nil, err
+	}
+	rates := make(map[string]*big.Rat, len(parsed.Valutes))
+	for _, valute := range parsed.Valutes {
+		value, ok := (&big.Rat{}).SetString(strings.ReplaceAll(valute.Value, ",", "."))
+		if !ok {
+			return nil, fmt.Errorf("invalid CBR rate %q for %s", valute.Value, valute.CharCode)
+		}
+		nominal, ok := (&big.Rat{}).SetString(valute.Nominal)
+		if !ok || nominal.Sign() == 0 {
+			return nil, fmt.Errorf("invalid CBR nominal %q for %s", valute.Nominal, valute.CharCode)
+		}
+		rates[strings.ToLower(valute.CharCode)] = value.Quo(value, nominal)
+	}
+	cbrCache[date] = rates
+	return rates, nil
+}
+
+// CBRRate returns the official RUB rate of the currency set for the date
+func CBRRate(currency string, date time.Time) (*big.Rat, error) {
+	currency = NormalizeCurrency(currency)
+	if currency == "rub" {
+		return big.NewRat(1, 1), nil
+	}
+	moscow := time.FixedZone("MSK", 3*60*60)
+	rates, err := getCBRRates(date.In(moscow).Format("02/01/2006"))
+	if err != nil {
+		return nil, err
+	}
+	rate, ok := rates[currency]
+	if !ok {
+		return nil, UnknownCBRCurrencyError
+	}
+	return rate, nil
+}
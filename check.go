@@ -0,0 +1,162 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/zap"
+	"opensource.tbank.ru/invest/invest-go/investgo"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// CheckResult is what the check found about an account without evaluating it
+type CheckResult struct {
+	Account AccountInfo
+	// estimated for the whole evaluated window from a sample page
+	Operations  int
+	Instruments int
+	Estimate    APIEstimate
+}
+
+// CheckOptions reports the options which are otherwise validated only during the evaluation
+func CheckOptions(logger *zap.Logger, options Options) bool {
+	valid := true
+	if options.StaleGap != "" {
+		if _, err := time.ParseDuration(options.StaleGap); err != nil {
+			logger.Error("invalid stale gap", zap.String("gap", options.StaleGap), zap.Error(err))
+			valid = false
+		}
+	}
+	switch options.BlockedAssets.Policy {
+	case "", BlockedLast, BlockedOverride, BlockedZero:
+	default:
+		logger.Error("unknown blocked assets policy", zap.String("policy", options.BlockedAssets.Policy))
+		valid = false
+	}
+	for code, policy := range options.CandleErrors {
+		switch policy {
+		case CandleErrorFail, CandleErrorSkip, CandleErrorRetry:
+		default:
+			logger.Error("unknown candle error policy", zap.String("code", code), zap.String("policy", policy))
+			valid = false
+		}
+	}
+	return valid
+}
+
+// CheckAccount samples one page of operations and the portfolio of the account
+// to estimate the number of operations and instruments of its evaluation
+func CheckAccount(op *investgo.OperationsServiceClient, logger *zap.Logger, currencyInstruments map[string]string,
+	account AccountInfo, now time.Time) (CheckResult, error) {
+	result := CheckResult{Account: account}
+	instruments := make(map[string]bool)
+	AwaitQuota("GetPortfolio")
+	start := time.Now()
+	positions, err := op.GetPortfolio(account.Id, pb.PortfolioRequest_RUB)
+	TraceCall("GetPortfolio", account.Id, start, positions, err)
+	if err != nil {
+		logger.Error("error getting portfolio", zap.String("account", account.Id), zap.Error(err))
+		return result, err
+	}
+	for _, position := range positions.Positions {
+		if _, ok := currencyInstruments[position.PositionUid]; !ok {
+			instruments[position.InstrumentUid] = true
+		}
+	}
+
+	from := account.Start()
+	req := &investgo.GetOperationsByCursorRequest{
+		AccountId: account.Id,
+		From:      from,
+		To:        now,
+		State:     pb.OperationState_OPERATION_STATE_EXECUTED,
+		Limit:     OperationsPageSize,
+	}
+	AwaitQuota("GetOperationsByCursor")
+	start = time.Now()
+	page, err := op.GetOperationsByCursor(req)
+	TraceCall("GetOperationsByCursor", req, start, page, err)
+	var items []*pb.OperationItem
+	var hasNext bool
+	if cursorUnsupported(err) {
+		sampleFrom := now.Add(-legacyOperationsWindow)
+		hasNext = sampleFrom.After(from)
+		if !hasNext {
+			sampleFrom = from
+		}
+		items, err = getLegacyOperations(op, logger, account.Id, req.State, sampleFrom, now)
+	} else if err == nil {
+		items, hasNext = page.Items, page.HasNext
+	}
+	if err != nil {
+		logger.Error("error getting operations", zap.String("account", account.Id), zap.Error(err))
+		return result, err
+	}
+	for _, operation := range items {
+		if _, ok := currencyInstruments[operation.PositionUid]; !ok && operation.InstrumentUid != "" {
+			instruments[operation.InstrumentUid] = true
+		}
+	}
+
+	result.Operations = len(items)
+	if hasNext && len(items) > 0 {
+		// operations come newest first, the rest of the window is assumed to be as busy as the sample
+		oldest := slices.MinFunc(items, func(x, y *pb.OperationItem) int {
+			return x.Date.AsTime().Compare(y.Date.AsTime())
+		}).Date.AsTime()
+		if sampled := now.Sub(oldest); sampled > 0 {
+			result.Operations = int(float64(len(items)) * float64(now.Sub(from)) / float64(sampled))
+		}
+	}
+	result.Instruments = len(instruments)
+	result.Estimate = EstimateRequests(result.Operations, result.Instruments)
+	return result, nil
+}
+
+// PrintCheck prints the accounts of the token, the selected ones with their estimates
+func PrintCheck(w io.Writer, accounts []*pb.Account, results []CheckResult) error {
+	fmt.Fprintln(w, T("Configuration is valid, the token has access to the selected accounts"))
+	tw := NewTable(w, tabwriter.AlignRight)
+	fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n", T("ACCOUNT"), T("NAME"), T("TYPE"),
+		T("OPERATIONS"), T("INSTRUMENTS"), T("REQUESTS"), T("DURATION"))
+	var total APIEstimate
+	for _, account := range accounts {
+		info := NewAccountInfo(account)
+		i := slices.IndexFunc(results, func(result CheckResult) bool { return result.Account.Id == account.Id })
+		if i < 0 {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t-\t-\t-\t-\t\n", Redact("account", info.Id), Redact("name", info.Name), info.Type)
+			continue
+		}
+		result := results[i]
+		total = total.Add(result.Estimate)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t~%d\t%d\t%d\t%s\t\n", Redact("account", info.Id), Redact("name", info.Name),
+			info.Type, result.Operations, result.Instruments, result.Estimate.Total(), formatMinutes(result.Estimate.Duration()))
+	}
+	tw.Total()
+	fmt.Fprintf(tw, "%s\t\t\t\t\t%d\t%s\t\n", T("total"), total.Total(), formatMinutes(total.Duration()))
+	return tw.Flush()
+}
+
+// formatMinutes formats the estimated duration to whole minutes, at least one
+func formatMinutes(duration time.Duration) string {
+	return fmt.Sprintf(T("%d min"), max(1, int((duration+time.Minute-1)/time.Minute)))
+}
@@ -0,0 +1,202 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+	"opensource.tbank.ru/invest/invest-go/investgo"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// CheckpointDir keeps the progress of an interrupted run, it is removed after a successful one
+var CheckpointDir = ".checkpoint"
+
+// loadJSON reads the saved value from the directory, it returns false if there is none
+func loadJSON(dir, name string, value any) (bool, error) {
+	if dir == "" {
+		return false, nil
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	err = json.Unmarshal(data, value)
+	return err == nil, err
+}
+
+// saveJSON writes the value atomically, so an interrupted write does not corrupt it
+func saveJSON(dir, name string, value any) error {
+	if dir == "" {
+		return nil
+	}
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	filename := filepath.Join(dir, name)
+	err = os.WriteFile(filename+".tmp", data, 0o644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(filename+".tmp", filename)
+}
+
+// loadCheckpoint reads the saved progress, it returns false if there is none
+func loadCheckpoint(name string, value any) (bool, error) {
+	return loadJSON(CheckpointDir, name, value)
+}
+
+func saveCheckpoint(name string, value any) error {
+	return saveJSON(CheckpointDir, name, value)
+}
+
+// ClearCheckpoint removes the progress after a successful run
+func ClearCheckpoint() error {
+	if CheckpointDir == "" {
+		return nil
+	}
+	return os.RemoveAll(CheckpointDir)
+}
+
+// operationsCheckpoint is the progress of getting operations of an account
+type operationsCheckpoint struct {
+	To     time.Time
+	Cursor string
+	Done   bool
+	Items  []*pb.OperationItem
+}
+
+// MaxOperationsPageSize is the largest page of operations the API returns
+const MaxOperationsPageSize = 1000
+
+// OperationsPageSize limits operations per cursor request, the API default is used if zero
+var OperationsPageSize int32
+
+// fetchOperations gets all pages of operations starting from the request cursor,
+// accounts without the cursor-based method get them all at once from the legacy one
+func fetchOperations(op *investgo.OperationsServiceClient, logger *zap.Logger, req *investgo.GetOperationsByCursorRequest,
+	page func(items []*pb.OperationItem, cursor string, hasNext bool) error) error {
+	if req.Limit == 0 {
+		req.Limit = OperationsPageSize
+	}
+	for {
+		AwaitQuota("GetOperationsByCursor")
+		start := time.Now()
+		operations, err := op.GetOperationsByCursor(req)
+		TraceCall("GetOperationsByCursor", req, start, operations, err)
+		if err != nil && req.Cursor == "" && cursorUnsupported(err) {
+			logger.Warn("cursor-based operations are unavailable, falling back to legacy operations",
+				zap.String("account", req.AccountId),
+				zap.Error(err))
+			items, err := getLegacyOperations(op, logger, req.AccountId, req.State, req.From, req.To)
+			if err != nil {
+				return err
+			}
+			return page(items, "", false)
+		}
+		if err != nil {
+			logger.Error("error getting operations",
+				zap.Any("request", req),
+				zap.Error(err))
+			return err
+		}
+		err = page(operations.Items, operations.NextCursor, operations.HasNext)
+		if err != nil {
+			return err
+		}
+		if !operations.HasNext {
+			return nil
+		}
+		req.Cursor = operations.NextCursor
+		logger.Debug("getting operations", zap.Time("last_processed", operations.Items[len(operations.Items)-1].Date.AsTime()))
+	}
+}
+
+// getOperations gets executed operations of the account, resuming from the checkpoint if there is one
+func getOperations(op *investgo.OperationsServiceClient, logger *zap.Logger,
+	accountId string, from, now time.Time) ([]*pb.OperationItem, error) {
+	name := "operations-" + accountId + ".json"
+	var progress operationsCheckpoint
+	ok, err := loadCheckpoint(name, &progress)
+	if err != nil {
+		logger.Warn("ignoring broken operations checkpoint", zap.String("account", accountId), zap.Error(err))
+	}
+	if !ok {
+		progress = operationsCheckpoint{To: now}
+	} else {
+		logger.Info("resuming operations from checkpoint",
+			zap.String("account", accountId),
+			zap.Int("operations", len(progress.Items)))
+	}
+
+	if !progress.Done {
+		req := &investgo.GetOperationsByCursorRequest{
+			AccountId: accountId,
+			From:      from,
+			To:        progress.To,
+			Cursor:    progress.Cursor,
+			State:     pb.OperationState_OPERATION_STATE_EXECUTED,
+		}
+		err = fetchOperations(op, logger, req, func(items []*pb.OperationItem, cursor string, hasNext bool) error {
+			progress.Items = append(progress.Items, items...)
+			progress.Cursor = cursor
+			progress.Done = !hasNext
+			err := saveCheckpoint(name, progress)
+			if err != nil {
+				logger.Warn("error saving operations checkpoint", zap.Error(err))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !progress.To.Before(now) {
+		return progress.Items, nil
+	}
+
+	// the checkpoint is from the interrupted run, operations since then are needed too
+	var newer []*pb.OperationItem
+	req := &investgo.GetOperationsByCursorRequest{
+		AccountId: accountId,
+		From:      progress.To,
+		To:        now,
+		State:     pb.OperationState_OPERATION_STATE_EXECUTED,
+	}
+	err = fetchOperations(op, logger, req, func(items []*pb.OperationItem, cursor string, hasNext bool) error {
+		newer = append(newer, items...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(newer, progress.Items...), nil
+}
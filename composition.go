@@ -0,0 +1,130 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"maps"
+	"math/big"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+	"opensource.tbank.ru/invest/invest-go/investgo"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// assetUid -> instrument UID the asset was resolved from
+var instrumentUids = make(map[string]string)
+
+// assetUid -> country of risk
+var countries = make(map[string]string)
+
+// assetUid -> sector, loaded only for the composition report
+var sectors = make(map[string]string)
+
+func getSector(in *investgo.InstrumentsServiceClient, logger *zap.Logger, assetUid string) (string, error) {
+	if sector, ok := sectors[assetUid]; ok {
+		return sector, nil
+	}
+	instrumentUid := instrumentUids[assetUid]
+	logger.Debug("getting instrument sector",
+		zap.String("instrument", instrumentUid),
+		zap.String("ticker", tickers[assetUid]))
+	var sector string
+	switch kinds[assetUid] {
+	case pb.InstrumentType_INSTRUMENT_TYPE_SHARE:
+		AwaitQuota("ShareBy")
+		start := time.Now()
+		resp, err := in.ShareByUid(instrumentUid)
+		TraceCall("ShareBy", instrumentUid, start, resp, err)
+		if err != nil {
+			return "", err
+		}
+		sector = resp.Instrument.Sector
+	case pb.InstrumentType_INSTRUMENT_TYPE_BOND:
+		AwaitQuota("BondBy")
+		start := time.Now()
+		resp, err := in.BondByUid(instrumentUid)
+		TraceCall("BondBy", instrumentUid, start, resp, err)
+		if err != nil {
+			return "", err
+		}
+		sector = resp.Instrument.Sector
+	case pb.InstrumentType_INSTRUMENT_TYPE_ETF:
+		AwaitQuota("EtfBy")
+		start := time.Now()
+		resp, err := in.EtfByUid(instrumentUid)
+		TraceCall("EtfBy", instrumentUid, start, resp, err)
+		if err != nil {
+			return "", err
+		}
+		sector = resp.Instrument.Sector
+	case pb.InstrumentType_INSTRUMENT_TYPE_FUTURES:
+		AwaitQuota("FutureBy")
+		start := time.Now()
+		resp, err := in.FutureByUid(instrumentUid)
+		TraceCall("FutureBy", instrumentUid, start, resp, err)
+		if err != nil {
+			return "", err
+		}
+		sector = resp.Instrument.Sector
+	}
+	sectors[assetUid] = sector
+	return sector, nil
+}
+
+// LoadSectors gets sectors of all assets in the portfolios
+func LoadSectors(in *investgo.InstrumentsServiceClient, logger *zap.Logger, portfolios ...map[string]*big.Rat) error {
+	for _, portfolio := range portfolios {
+		for _, key := range slices.Sorted(maps.Keys(portfolio)) {
+			if _, ok := ExchangeRates[key]; ok {
+				continue
+			}
+			_, err := getSector(in, logger, key)
+			if err != nil {
+				logger.Error("error getting sector",
+					zap.String("asset", key),
+					zap.String("ticker", tickers[key]),
+					zap.Error(err))
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CountryName returns the country of risk of the portfolio key
+func CountryName(key string) string {
+	if _, ok := ExchangeRates[key]; ok {
+		return "cash"
+	}
+	if country := countries[key]; country != "" {
+		return country
+	}
+	return "unknown"
+}
+
+// SectorName returns the sector of the portfolio key
+func SectorName(key string) string {
+	if _, ok := ExchangeRates[key]; ok {
+		return "cash"
+	}
+	if sector := sectors[key]; sector != "" {
+		return sector
+	}
+	return "unknown"
+}
@@ -0,0 +1,164 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"maps"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"opensource.tbank.ru/invest/invest-go/investgo"
+)
+
+// Options are the evaluator settings, they are read from the same file as the SDK config
+type Options struct {
+	// use the sandbox endpoint, the token must be a sandbox one
+	Sandbox bool `yaml:"Sandbox"`
+	// refuse tokens which can trade on the evaluated accounts instead of warning about them
+	RequireReadOnly bool `yaml:"RequireReadOnly"`
+	// HTTP CONNECT or SOCKS5 proxy URL for the API connection, HTTPS_PROXY is used by gRPC if empty
+	Proxy string `yaml:"Proxy"`
+	// gRPC keepalive pings of the API connection, none if empty
+	Keepalive KeepaliveOptions `yaml:"Keepalive"`
+	// several accounts evaluated together, AccountId is used if empty
+	AccountIds []string `yaml:"AccountIds"`
+	// evaluate all accounts of the token which existed during the tax year instead of AccountIds
+	AllAccounts bool `yaml:"AllAccounts"`
+	// other persons with their own tokens, e.g. family members, each with a section of their accounts
+	Persons []PersonOptions `yaml:"Persons"`
+	// name of the owner of the configured token in the sections of the persons, Owner by default
+	Owner string `yaml:"Owner"`
+	// combine the accounts of all persons into the household maximum
+	Household bool `yaml:"Household"`
+	// reporting units: name -> accounts, e.g. an account with its invest boxes, with their combined maximum
+	Groups           map[string][]string `yaml:"Groups"`
+	CorporateActions []CorporateAction   `yaml:"CorporateActions"`
+	// count declared dividends as account assets between the record date and the payment
+	DividendReceivables bool `yaml:"DividendReceivables"`
+	// local and treaty rates for the foreign dividend withholding summary of -dividend-tax
+	DividendTax DividendTaxOptions `yaml:"DividendTax"`
+	// tickers or asset UIDs valued separately from the reported maximum
+	ExcludeAssets []string `yaml:"ExcludeAssets"`
+	// value held assets now by the last prices instead of the portfolio prices
+	LastPrices bool `yaml:"LastPrices"`
+	// valuation of blocked assets and assets without candles
+	BlockedAssets BlockedAssetsOptions `yaml:"BlockedAssets"`
+	// reporting thresholds, in USD unless the currency is set, FBAR by default
+	Thresholds []Threshold `yaml:"Thresholds"`
+	// account ID -> IIS type: A, B or 3 (the new IIS since 2024)
+	IISTypes map[string]string `yaml:"IISTypes"`
+	// gaps between candles logged as stale price intervals, 72h by default
+	StaleGap string `yaml:"StaleGap"`
+	// weekend (default) for the exchange sessions and the weekend trading, or exchange for the sessions only
+	CandleSource string `yaml:"CandleSource"`
+	// trading schedules of an exchange to annotate or skip the points outside the trading sessions
+	TradingCalendar TradingCalendarOptions `yaml:"TradingCalendar"`
+	// assets to download the candles of or to skip, all by default
+	CandleFilter CandleFilterOptions `yaml:"CandleFilter"`
+	// gRPC error code -> fail, skip or retry for candle fetch failures
+	CandleErrors CandleErrorPolicies `yaml:"CandleErrors"`
+	// analysis of sharp changes between consecutive points
+	Movers MoversOptions `yaml:"Movers"`
+	// language of the printed reports and error hints: en (default) or ru
+	Language string `yaml:"Language"`
+	// IANA name of the reporting timezone, e.g. Europe/Moscow, UTC by default
+	Timezone string `yaml:"Timezone"`
+	// decimal places in the summary, 2 by default
+	Decimals *int `yaml:"Decimals"`
+	// half-up, half-even or up
+	Rounding string `yaml:"Rounding"`
+	// net (default) to reduce the value by margin loans and short positions, or gross to count assets only
+	Liabilities string `yaml:"Liabilities"`
+	// percent of the maximum which counts as near the peak for the persistence of the peak, 95 by default
+	PeakBand string `yaml:"PeakBand"`
+	// keys of the assets in the exports: ticker (default), isin or uid
+	ExportKey string `yaml:"ExportKey"`
+	// operations per page of the cursor requests, the API default if zero
+	OperationsPageSize int `yaml:"OperationsPageSize"`
+	// requests per minute by service, the default API limits if zero
+	RateLimits RateLimitsOptions `yaml:"RateLimits"`
+	// space candle and instrument requests evenly when their estimate exceeds the rate limits
+	Throttle bool `yaml:"Throttle"`
+	// progress of an interrupted run, .checkpoint by default
+	CheckpointDir string `yaml:"CheckpointDir"`
+	// operations and candles of incremental runs, .cache by default
+	CacheDir string `yaml:"CacheDir"`
+	// SQLite database archiving fetched data and timelines
+	Database string `yaml:"Database"`
+	// history of run summaries, runs.json by default
+	HistoryFile string `yaml:"HistoryFile"`
+	// mail server for -email
+	SMTP SMTPOptions `yaml:"SMTP"`
+	// URLs notified when a run completes or a scheduled run finds a new yearly maximum
+	Webhooks []WebhookOptions `yaml:"Webhooks"`
+	// Google Sheet for -sheets
+	GoogleSheets SheetsOptions `yaml:"GoogleSheets"`
+	// OTLP/HTTP collector for traces and metrics, OTEL_EXPORTER_OTLP_ENDPOINT is used if empty
+	OTLPEndpoint string `yaml:"OTLPEndpoint"`
+}
+
+// CorporateAction describes a split or a ticker change that is missing from the operations log
+type CorporateAction struct {
+	Date time.Time `yaml:"Date"`
+	// ticker or asset UID
+	Asset string `yaml:"Asset"`
+	// ticker or asset UID that replaced Asset, empty for splits
+	NewAsset string `yaml:"NewAsset"`
+	// number of new shares for one old share, e.g. "10" or "1/10"
+	Ratio string `yaml:"Ratio"`
+}
+
+var UnknownProfileError = errors.New("unknown profile")
+
+// ReadConfig reads the config file, the settings of the profile from Profiles replace the top-level ones,
+// so one file keeps the tokens and accounts of several people
+func ReadConfig(filename, profile string) ([]byte, error) {
+	data, err := readConfigFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var document map[string]any
+	err = yaml.Unmarshal(data, &document)
+	if err != nil {
+		return nil, err
+	}
+	profiles, _ := document["Profiles"].(map[string]any)
+	delete(document, "Profiles")
+	if profile == "" {
+		return yaml.Marshal(document)
+	}
+	settings, ok := profiles[profile].(map[string]any)
+	if !ok {
+		return nil, UnknownProfileError
+	}
+	maps.Copy(document, settings)
+	return yaml.Marshal(document)
+}
+
+// ParseConfig parses the SDK config
+func ParseConfig(data []byte) (investgo.Config, error) {
+	var config investgo.Config
+	err := yaml.Unmarshal(data, &config)
+	return config, err
+}
+
+func LoadOptions(data []byte) (Options, error) {
+	var options Options
+	err := yaml.Unmarshal(data, &options)
+	return options, err
+}
@@ -0,0 +1,71 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"time"
+)
+
+// ConservativeResult is the maximum by the conservative rules
+type ConservativeResult struct {
+	Best Point
+	// gaps longer than this are valued at the lower of the prices around them
+	CarryLimit time.Duration
+}
+
+// ConservativeSeries returns copies of the series valued by the conservative rules: close prices instead
+// of the highs, and the last price is carried forward over gaps up to the limit only
+func ConservativeSeries(series []*PriceSeries, carryLimit time.Duration) []*PriceSeries {
+	result := make([]*PriceSeries, len(series))
+	for i, s := range series {
+		conservative := *s
+		conservative.Close = true
+		conservative.CarryLimit = carryLimit
+		result[i] = &conservative
+	}
+	return result
+}
+
+// ConservativeMaximum replays the account again by the conservative rules: the conservative series and
+// the liabilities netted, and returns the maximum point. Only the maximum is searched, the timeline and
+// the other trackers are left to the main replay.
+func ConservativeMaximum(evaluation *Evaluation, state *State, updates map[time.Time][]Update,
+	series []*PriceSeries, excluded map[string]bool) Point {
+	defer func(policy string) { LiabilitiesPolicy = policy }(LiabilitiesPolicy)
+	LiabilitiesPolicy = LiabilitiesNet
+	var best Point
+	stream := NewUpdateStream(updates, series)
+	state = state.Clone()
+	for {
+		date, ok := stream.Next(state)
+		if !ok {
+			break
+		}
+		if !InTaxYear(date) || date.Before(evaluation.Account.Start()) || date.After(evaluation.Account.End()) {
+			continue
+		}
+		local := date.In(Location)
+		if !countsForMaximum(local) {
+			continue
+		}
+		_, _, aggregate := Cost(state, excluded)
+		if best.Aggregate == nil || best.Aggregate.Cmp(aggregate) < 0 {
+			best = Point{Time: local, Aggregate: aggregate}
+		}
+	}
+	return best
+}
@@ -0,0 +1,66 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+func TestConservativeMaximum(t *testing.T) {
+	start := time.Date(TaxYear, 3, 2, 10, 0, 0, 0, time.UTC)
+	candle := func(hours, high, close int64) *pb.HistoricCandle {
+		return &pb.HistoricCandle{
+			Time:  timestamppb.New(start.Add(time.Duration(hours) * time.Hour)),
+			High:  &pb.Quotation{Units: high},
+			Close: &pb.Quotation{Units: close},
+		}
+	}
+	series := []*PriceSeries{{Asset: "a", Currency: "usd", Candles: []*pb.HistoricCandle{
+		candle(0, 30, 20), candle(1, 12, 11), candle(100, 10, 9),
+	}}}
+	state := &State{
+		Portfolio:  map[string]*big.Rat{"a": big.NewRat(1, 1)},
+		Prices:     make(map[string]*big.Rat),
+		Accrued:    make(map[string]*big.Rat),
+		Currencies: make(map[string]string),
+	}
+	evaluation := &Evaluation{}
+	updates := make(map[time.Time][]Update)
+
+	best := ConservativeMaximum(evaluation, state, updates, series, nil)
+	if best.Aggregate.Cmp(big.NewRat(30, 1)) != 0 {
+		t.Errorf("maximum by the highs = %v, want 30", best.Aggregate)
+	}
+	best = ConservativeMaximum(evaluation, state, updates, ConservativeSeries(series, 72*time.Hour), nil)
+	if best.Aggregate.Cmp(big.NewRat(20, 1)) != 0 || !best.Time.Equal(start.In(Location)) {
+		t.Errorf("conservative maximum = %v at %v, want 20 at the start", best.Aggregate, best.Time)
+	}
+	// the close price 11 is not carried over the long gap, the lower close price 9 is used in it
+	series[0].Candles[0] = candle(0, 5, 5)
+	best = ConservativeMaximum(evaluation, state, updates, ConservativeSeries(series, 72*time.Hour), nil)
+	if best.Aggregate.Cmp(big.NewRat(11, 1)) != 0 || !best.Time.Equal(start.Add(time.Hour).In(Location)) {
+		t.Errorf("conservative maximum = %v at %v, want 11 at the first hour", best.Aggregate, best.Time)
+	}
+	if len(series[0].Candles) != 3 || series[0].Close || series[0].CarryLimit != 0 {
+		t.Errorf("ConservativeSeries() changed the original series")
+	}
+}
@@ -0,0 +1,93 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var InvalidRatioError = errors.New("invalid corporate action ratio")
+var UnknownAssetError = errors.New("unknown asset")
+
+// CorporateActionToUpdate converts a corporate action to an update undoing it.
+// Before a split there were ratio times fewer shares, each ratio times more expensive.
+// Before a ticker change the whole position was held in the old asset.
+// UnknownAssetError is returned for the assets without operations or positions, e.g. the old ticker
+// of a position carried over from the previous year, the action is skipped then.
+func CorporateActionToUpdate(action CorporateAction) (Update, error) {
+	ratio, ok := (&big.Rat{}).SetString(action.Ratio)
+	if !ok || ratio.Sign() <= 0 {
+		return nil, InvalidRatioError
+	}
+	oldAsset, ok := FindAsset(action.Asset)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", UnknownAssetError, action.Asset)
+	}
+	if action.NewAsset == "" {
+		return func(state *State) {
+			if quantity, ok := state.Portfolio[oldAsset]; ok {
+				state.Portfolio[oldAsset] = (&big.Rat{}).Quo(quantity, ratio)
+			}
+			if price, ok := state.Prices[oldAsset]; ok {
+				state.Prices[oldAsset] = (&big.Rat{}).Mul(price, ratio)
+			}
+		}, nil
+	}
+	newAsset, ok := FindAsset(action.NewAsset)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", UnknownAssetError, action.NewAsset)
+	}
+	return func(state *State) {
+		if quantity, ok := state.Portfolio[newAsset]; ok {
+			state.Portfolio[oldAsset] = AddRat(state.Portfolio[oldAsset], (&big.Rat{}).Quo(quantity, ratio))
+			delete(state.Portfolio, newAsset)
+		}
+		if _, ok := state.Prices[oldAsset]; !ok {
+			if price, ok := state.Prices[newAsset]; ok {
+				state.Prices[oldAsset] = (&big.Rat{}).Mul(price, ratio)
+				state.Currencies[oldAsset] = state.Currencies[newAsset]
+			}
+		}
+	}, nil
+}
+
+// CorporateActionChange converts a corporate action to a change of the portfolio made by it
+func CorporateActionChange(action CorporateAction) (func(portfolio map[string]*big.Rat), error) {
+	ratio, ok := (&big.Rat{}).SetString(action.Ratio)
+	if !ok || ratio.Sign() <= 0 {
+		return nil, InvalidRatioError
+	}
+	oldAsset, ok := FindAsset(action.Asset)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", UnknownAssetError, action.Asset)
+	}
+	newAsset := oldAsset
+	if action.NewAsset != "" {
+		newAsset, ok = FindAsset(action.NewAsset)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", UnknownAssetError, action.NewAsset)
+		}
+	}
+	return func(portfolio map[string]*big.Rat) {
+		if quantity, ok := portfolio[oldAsset]; ok {
+			delete(portfolio, oldAsset)
+			portfolio[newAsset] = AddRat(portfolio[newAsset], (&big.Rat{}).Mul(quantity, ratio))
+		}
+	}, nil
+}
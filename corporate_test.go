@@ -0,0 +1,106 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// replayCorporateAction replays the state back through the corporate action and returns the state just before it
+func replayCorporateAction(t *testing.T, state *State, action CorporateAction, series []*PriceSeries) *State {
+	t.Helper()
+	update, err := CorporateActionToUpdate(action)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := zap.NewNop()
+	evaluation := &Evaluation{
+		BestAggregate: &big.Rat{},
+		Explanation:   &Explanation{Requested: action.Date},
+	}
+	updates := map[time.Time][]Update{action.Date: {update}}
+	var months MonthEnds
+	err = Replay(logger, evaluation, state, updates, series, nil, &months,
+		NewMoverTracker(logger, MoversOptions{}, nil), NewThresholdTracker(logger, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if evaluation.Explanation.State == nil {
+		t.Fatal("no state before the corporate action")
+	}
+	return evaluation.Explanation.State
+}
+
+func TestCorporateActionSplit(t *testing.T) {
+	tickers["split-asset"] = "SPLIT"
+	defer delete(tickers, "split-asset")
+	date := time.Date(TaxYear, 6, 1, 7, 0, 0, 0, time.UTC)
+	state := &State{
+		Portfolio:  map[string]*big.Rat{"split-asset": big.NewRat(100, 1)},
+		Prices:     map[string]*big.Rat{"split-asset": big.NewRat(10, 1)},
+		Accrued:    make(map[string]*big.Rat),
+		Currencies: map[string]string{"split-asset": "rub"},
+	}
+	before := replayCorporateAction(t, state, CorporateAction{Date: date, Asset: "SPLIT", Ratio: "10"}, nil)
+	if quantity := before.Portfolio["split-asset"]; quantity.Cmp(big.NewRat(10, 1)) != 0 {
+		t.Errorf("quantity before the split = %v, want 10", quantity)
+	}
+	if price := before.Prices["split-asset"]; price.Cmp(big.NewRat(100, 1)) != 0 {
+		t.Errorf("price before the split = %v, want 100", price)
+	}
+}
+
+func TestCorporateActionTickerChange(t *testing.T) {
+	tickers["old-asset"], tickers["new-asset"] = "OLD", "NEW"
+	defer func() { delete(tickers, "old-asset"); delete(tickers, "new-asset") }()
+	date := time.Date(TaxYear, 6, 1, 7, 0, 0, 0, time.UTC)
+	state := &State{
+		Portfolio:  map[string]*big.Rat{"new-asset": big.NewRat(10, 1)},
+		Prices:     map[string]*big.Rat{"new-asset": big.NewRat(50, 1)},
+		Accrued:    make(map[string]*big.Rat),
+		Currencies: map[string]string{"new-asset": "rub"},
+	}
+	latest := make(map[string]LatestPrice)
+	// the old asset is quoted before the change only
+	series := []*PriceSeries{NewPriceSeries(zap.NewNop(), latest, "old-asset", "rub", nil, nil, nil,
+		[]*pb.HistoricCandle{{Time: timestamppb.New(date.Add(-time.Hour)), High: &pb.Quotation{Units: 40}}},
+		defaultStaleGap)}
+	action := CorporateAction{Date: date, Asset: "OLD", NewAsset: "NEW", Ratio: "1"}
+	before := replayCorporateAction(t, state, action, series)
+	if _, ok := before.Portfolio["new-asset"]; ok {
+		t.Errorf("portfolio before the ticker change = %v, want the old asset only", before.Portfolio)
+	}
+	if quantity := before.Portfolio["old-asset"]; quantity.Cmp(big.NewRat(10, 1)) != 0 {
+		t.Errorf("quantity of the old asset = %v, want 10", quantity)
+	}
+	if price := before.Prices["old-asset"]; price.Cmp(big.NewRat(50, 1)) != 0 {
+		t.Errorf("price of the old asset after its candles = %v, want the new price 50", price)
+	}
+
+	// the old ticker of a position carried over from the previous year is not known
+	_, err := CorporateActionToUpdate(CorporateAction{Date: date, Asset: "GONE", NewAsset: "NEW", Ratio: "1"})
+	if !errors.Is(err, UnknownAssetError) {
+		t.Errorf("CorporateActionToUpdate() with an unknown ticker = %v, want UnknownAssetError", err)
+	}
+}
@@ -0,0 +1,55 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"strings"
+
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// currencyAliases maps obsolete and alternative currency codes to the ones used as portfolio keys
+var currencyAliases = map[string]string{
+	// the ruble before the 1998 redenomination, still used by some exchange systems
+	"rur": "rub",
+}
+
+// NormalizeCurrency converts a currency code to the lowercase ISO code used as the key
+// of portfolio and cost maps, so "RUB", "rub" and "RUR" are the same balance
+func NormalizeCurrency(currency string) string {
+	currency = strings.ToLower(strings.TrimSpace(currency))
+	if alias, ok := currencyAliases[currency]; ok {
+		return alias
+	}
+	return currency
+}
+
+// normalizeMoney normalizes the currency of the amount in place
+func normalizeMoney(money *pb.MoneyValue) {
+	if money != nil {
+		money.Currency = NormalizeCurrency(money.Currency)
+	}
+}
+
+// NormalizeOperationCurrencies normalizes the currencies of all amounts of the operation in place,
+// before any of them is used as a key
+func NormalizeOperationCurrencies(operation *pb.OperationItem) {
+	normalizeMoney(operation.Payment)
+	normalizeMoney(operation.Price)
+	normalizeMoney(operation.Commission)
+	normalizeMoney(operation.AccruedInt)
+}
@@ -0,0 +1,61 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+func TestNormalizeCurrency(t *testing.T) {
+	for _, test := range []struct {
+		currency, want string
+	}{
+		{"rub", "rub"},
+		{"RUB", "rub"},
+		{"RUR", "rub"},
+		{"rur", "rub"},
+		{" Usd ", "usd"},
+		{"hkd", "hkd"},
+		{"", ""},
+	} {
+		if got := NormalizeCurrency(test.currency); got != test.want {
+			t.Errorf("NormalizeCurrency(%q) = %q, want %q", test.currency, got, test.want)
+		}
+	}
+}
+
+func TestNormalizedPaymentsShareBalance(t *testing.T) {
+	state := &State{Portfolio: map[string]*big.Rat{"rub": big.NewRat(1000, 1)}}
+	for _, currency := range []string{"RUB", "rur", "rub"} {
+		operation := &pb.OperationItem{
+			Type:    pb.OperationType_OPERATION_TYPE_INPUT,
+			Payment: &pb.MoneyValue{Currency: currency, Units: 100},
+		}
+		NormalizeOperationCurrencies(operation)
+		update, err := OperationToUpdate(operation)
+		if err != nil {
+			t.Fatal(err)
+		}
+		update(state)
+	}
+	if len(state.Portfolio) != 1 || state.Portfolio["rub"].Cmp(big.NewRat(700, 1)) != 0 {
+		t.Errorf("portfolio = %v, want rub 700", state.Portfolio)
+	}
+}
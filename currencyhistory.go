@@ -0,0 +1,90 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"maps"
+	"slices"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// CurrencyChange is a trade of an instrument in the currency it was quoted in at that time
+type CurrencyChange struct {
+	Time     time.Time
+	Currency string
+}
+
+// CurrencyHistory is the trades of an instrument in ascending time order
+type CurrencyHistory []CurrencyChange
+
+// At returns the currency of the instrument at the time: the currency of the next trade,
+// or the current one after the last trade
+func (h CurrencyHistory) At(date time.Time, current string) string {
+	i := sort.Search(len(h), func(i int) bool {
+		return !h[i].Time.Before(date)
+	})
+	if i == len(h) {
+		return current
+	}
+	return h[i].Currency
+}
+
+// CurrencyHistories returns the trade currencies of the instruments which were traded in a currency
+// other than the current one, e.g. after a redenomination, candles of other instruments are quoted
+// in their current currency
+func CurrencyHistories(logger *zap.Logger, operations []*pb.OperationItem) map[string]CurrencyHistory {
+	histories := make(map[string]CurrencyHistory)
+	changed := make(map[string]bool)
+	for _, operation := range operations {
+		switch operation.Type {
+		case pb.OperationType_OPERATION_TYPE_BUY, pb.OperationType_OPERATION_TYPE_SELL:
+		default:
+			continue
+		}
+		current, ok := instrumentCurrencies[operation.InstrumentUid]
+		if !ok || operation.Price == nil || operation.Price.Currency == "" || IsBond(operation.AssetUid) {
+			continue
+		}
+		currency := NormalizeCurrency(operation.Price.Currency)
+		histories[operation.InstrumentUid] = append(histories[operation.InstrumentUid],
+			CurrencyChange{Time: operation.Date.AsTime(), Currency: currency})
+		if currency != current {
+			changed[operation.InstrumentUid] = true
+		}
+	}
+	for instrumentUid := range histories {
+		if !changed[instrumentUid] {
+			delete(histories, instrumentUid)
+		}
+	}
+	for _, instrumentUid := range slices.Sorted(maps.Keys(histories)) {
+		history := histories[instrumentUid]
+		slices.SortStableFunc(history, func(a, b CurrencyChange) int {
+			return a.Time.Compare(b.Time)
+		})
+		logger.Warn("instrument was traded in another currency, candles are valued in the currency of the trades",
+			zap.String("instrument", instrumentUid),
+			zap.String("ticker", tickers[assets[instrumentUid]]),
+			zap.String("currency", instrumentCurrencies[instrumentUid]),
+			zap.String("first_currency", history[0].Currency))
+	}
+	return histories
+}
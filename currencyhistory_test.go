@@ -0,0 +1,63 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+func TestCurrencyHistories(t *testing.T) {
+	defer func() { delete(instrumentCurrencies, "redenominated"); delete(instrumentCurrencies, "stable") }()
+	instrumentCurrencies["redenominated"] = "rub"
+	instrumentCurrencies["stable"] = "usd"
+	start := time.Date(TaxYear, 3, 2, 10, 0, 0, 0, time.UTC)
+	trade := func(instrumentUid string, days int, currency string) *pb.OperationItem {
+		return &pb.OperationItem{
+			Type:          pb.OperationType_OPERATION_TYPE_BUY,
+			InstrumentUid: instrumentUid,
+			Date:          timestamppb.New(start.AddDate(0, 0, days)),
+			Price:         &pb.MoneyValue{Currency: currency, Units: 1},
+		}
+	}
+	histories := CurrencyHistories(zap.NewNop(), []*pb.OperationItem{
+		trade("redenominated", 10, "rub"),
+		trade("redenominated", 0, "USD"),
+		trade("stable", 0, "usd"),
+	})
+	if _, ok := histories["stable"]; ok || len(histories) != 1 {
+		t.Fatalf("CurrencyHistories() = %v, want the redenominated instrument only", histories)
+	}
+	history := histories["redenominated"]
+	for _, test := range []struct {
+		days int
+		want string
+	}{
+		{-1, "usd"},
+		{0, "usd"},
+		{5, "rub"},
+		{20, "rub"},
+	} {
+		if got := history.At(start.AddDate(0, 0, test.days), "rub"); got != test.want {
+			t.Errorf("At(%d days) = %s, want %s", test.days, got, test.want)
+		}
+	}
+}
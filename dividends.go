@@ -0,0 +1,156 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"maps"
+	"math/big"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+	"opensource.tbank.ru/invest/invest-go/investgo"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// Dividends are looked for this long before the payment
+const maxDividendDelay = 90 * 24 * time.Hour
+
+// instrumentUid -> dividends calendar
+var dividends = make(map[string][]*pb.Dividend)
+
+func getDividends(in *investgo.InstrumentsServiceClient, logger *zap.Logger, instrumentUid string) ([]*pb.Dividend, error) {
+	if result, ok := dividends[instrumentUid]; ok {
+		return result, nil
+	}
+	logger.Debug("getting dividends", zap.String("instrument", instrumentUid))
+	AwaitQuota("GetDividends")
+	start := time.Now()
+	resp, err := in.GetDividents(instrumentUid,
+		time.Date(TaxYear-1, 1, 1, 0, 0, 0, 0, Location),
+		time.Date(TaxYear+2, 1, 1, 0, 0, 0, 0, Location))
+	TraceCall("GetDividends", instrumentUid, start, resp, err)
+	if err != nil {
+		return nil, err
+	}
+	dividends[instrumentUid] = resp.Dividends
+	return resp.Dividends, nil
+}
+
+// FindRecordDate finds the record date of the dividend paid at the given date.
+// Between the record date and the payment the declared dividend is a receivable of the account.
+func FindRecordDate(calendar []*pb.Dividend, paid time.Time) (time.Time, bool) {
+	var best time.Time
+	for _, dividend := range calendar {
+		if dividend.RecordDate == nil {
+			continue
+		}
+		record := dividend.RecordDate.AsTime()
+		if record.After(paid) || paid.Sub(record) > maxDividendDelay {
+			continue
+		}
+		if record.After(best) {
+			best = record
+		}
+	}
+	return best, !best.IsZero()
+}
+
+// DividendReceivables adds dividends declared for the assets held during the evaluated window, but not paid yet,
+// to the current state as cash at par and returns updates removing them at the record date. The paid dividends
+// are moved to their record dates with their operations, the record dates of those are in paid. A receivable is
+// the dividend of the quantity held at the record date, it is found by replaying the updates back from the
+// current state, so the shares sold since the record date are counted too.
+func DividendReceivables(in *investgo.InstrumentsServiceClient, logger *zap.Logger, instruments map[string]string,
+	paid map[string][]time.Time, state *State, updates map[time.Time][]Update, since, now time.Time) (map[time.Time][]Update, error) {
+	type receivable struct {
+		assetUid string
+		record   time.Time
+		payment  time.Time
+		dividend *pb.MoneyValue
+	}
+	var receivables []receivable
+	for _, instrumentUid := range slices.Sorted(maps.Keys(instruments)) {
+		assetUid := instruments[instrumentUid]
+		if IsBond(assetUid) || IsFutures(assetUid) {
+			continue
+		}
+		calendar, err := getDividends(in, logger, instrumentUid)
+		if err != nil {
+			return nil, err
+		}
+		for _, dividend := range calendar {
+			if dividend.RecordDate == nil || dividend.PaymentDate == nil || dividend.DividendNet == nil {
+				continue
+			}
+			record, payment := dividend.RecordDate.AsTime(), dividend.PaymentDate.AsTime()
+			// the dividends paid before the window have no operations to tell them from the unpaid ones
+			if record.After(now) || payment.Before(since) || slices.ContainsFunc(paid[instrumentUid], record.Equal) {
+				continue
+			}
+			receivables = append(receivables, receivable{assetUid, record, payment, dividend.DividendNet})
+		}
+	}
+	slices.SortStableFunc(receivables, func(x, y receivable) int {
+		return y.record.Compare(x.record)
+	})
+
+	result := make(map[time.Time][]Update)
+	// the quantities at the record dates, the updates are applied going back in time
+	replayed := state.Clone()
+	times := slices.SortedFunc(maps.Keys(updates), func(x, y time.Time) int {
+		return y.Compare(x)
+	})
+	for _, item := range receivables {
+		for len(times) > 0 && times[0].After(item.record) {
+			for _, update := range updates[times[0]] {
+				update(replayed)
+			}
+			times = times[1:]
+		}
+		quantity := replayed.Portfolio[item.assetUid]
+		if quantity == nil || quantity.Sign() <= 0 {
+			continue
+		}
+		currency := NormalizeCurrency(item.dividend.Currency)
+		if _, ok := ExchangeRates[currency]; !ok {
+			logger.Warn("declared dividend in an unknown currency, skipping it",
+				zap.String("ticker", tickers[item.assetUid]),
+				zap.String("currency", currency))
+			continue
+		}
+		amount := (&big.Rat{}).Mul(ToRat(item.dividend), quantity)
+		if !item.payment.After(now) {
+			logger.Warn("declared dividend was not paid by its payment date, counting it as unpaid",
+				zap.String("ticker", tickers[item.assetUid]),
+				zap.Time("record_date", item.record),
+				zap.Time("payment_date", item.payment))
+		}
+		logger.Info("adding declared dividend",
+			zap.String("ticker", tickers[item.assetUid]),
+			zap.Time("record_date", item.record),
+			zap.Time("payment_date", item.payment),
+			zap.Stringer("quantity", quantity),
+			zap.Stringer("amount", amount),
+			zap.String("currency", currency))
+		state.Portfolio[currency] = AddRat(state.Portfolio[currency], amount)
+		result[item.record] = append(result[item.record], func(state *State) {
+			state.Portfolio[currency] = SubRat(state.Portfolio[currency], amount)
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,87 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+func TestDividendReceivables(t *testing.T) {
+	now := time.Date(TaxYear, 7, 1, 0, 0, 0, 0, time.UTC)
+	since := time.Date(TaxYear, 1, 1, 0, 0, 0, 0, time.UTC)
+	record := now.AddDate(0, 0, -10)
+	paidRecord := now.AddDate(0, -3, 0)
+	dividends["instrument"] = []*pb.Dividend{{
+		DividendNet: &pb.MoneyValue{Currency: "rub", Units: 30},
+		RecordDate:  timestamppb.New(record),
+		PaymentDate: timestamppb.New(now.AddDate(0, 0, 10)),
+	}, {
+		// paid with an operation moved to its record date
+		DividendNet: &pb.MoneyValue{Currency: "rub", Units: 20},
+		RecordDate:  timestamppb.New(paidRecord),
+		PaymentDate: timestamppb.New(paidRecord.AddDate(0, 0, 10)),
+	}, {
+		// paid before the tax year
+		DividendNet: &pb.MoneyValue{Currency: "rub", Units: 10},
+		RecordDate:  timestamppb.New(since.AddDate(0, -2, 0)),
+		PaymentDate: timestamppb.New(since.AddDate(0, -1, 0)),
+	}}
+	defer delete(dividends, "instrument")
+	// all the shares were sold after the record date
+	state := &State{
+		Portfolio:  map[string]*big.Rat{"rub": big.NewRat(3000, 1)},
+		Prices:     make(map[string]*big.Rat),
+		Accrued:    make(map[string]*big.Rat),
+		Currencies: make(map[string]string),
+	}
+	sale := record.AddDate(0, 0, 5)
+	purchase := record.AddDate(0, 0, -5)
+	updates := map[time.Time][]Update{
+		sale: {func(state *State) {
+			state.Portfolio["share"] = AddRat(state.Portfolio["share"], big.NewRat(10, 1))
+			state.Portfolio["rub"] = SubRat(state.Portfolio["rub"], big.NewRat(3000, 1))
+		}},
+		// half of the shares were bought before the record date
+		purchase: {func(state *State) {
+			state.Portfolio["share"] = SubRat(state.Portfolio["share"], big.NewRat(5, 1))
+		}},
+	}
+	instruments := map[string]string{"instrument": "share"}
+	paid := map[string][]time.Time{"instrument": {paidRecord}}
+	receivables, err := DividendReceivables(nil, zap.NewNop(), instruments, paid, state, updates, since, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := big.NewRat(3300, 1); state.Portfolio["rub"].Cmp(want) != 0 {
+		t.Errorf("cash with the receivable = %v, want %v", state.Portfolio["rub"], want)
+	}
+	if len(receivables) != 1 || len(receivables[record]) != 1 {
+		t.Fatalf("DividendReceivables() = %v, want one update at the record date", receivables)
+	}
+	for _, update := range receivables[record] {
+		update(state)
+	}
+	if want := big.NewRat(3000, 1); state.Portfolio["rub"].Cmp(want) != 0 {
+		t.Errorf("cash at the record date = %v, want %v", state.Portfolio["rub"], want)
+	}
+}
@@ -0,0 +1,214 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Port of SMTP submission with implicit TLS, other ports use STARTTLS when the server supports it
+const smtpsPort = 465
+
+var NoRecipientsError = errors.New("no email recipients in SMTP.To")
+
+// SMTPOptions are the mail server settings for -email
+type SMTPOptions struct {
+	Host string `yaml:"Host"`
+	// 587 by default
+	Port     int    `yaml:"Port"`
+	Username string `yaml:"Username"`
+	// SMTP_PASSWORD is used if empty
+	Password string   `yaml:"Password"`
+	From     string   `yaml:"From"`
+	To       []string `yaml:"To"`
+}
+
+// Attachment is a file attached to the email
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// NewMessage builds a MIME message with the HTML body and the attachments
+func NewMessage(from string, to []string, subject string, html []byte, attachments []Attachment) ([]byte, error) {
+	var message bytes.Buffer
+	writer := multipart.NewWriter(&message)
+	fmt.Fprintf(&message, "From: %s\r\n", from)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&message, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", writer.Boundary())
+
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/html; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	body := quotedprintable.NewWriter(part)
+	_, err = body.Write(html)
+	if err != nil {
+		return nil, err
+	}
+	err = body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, attachment := range attachments {
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {attachment.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Name})},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoded := base64.StdEncoding.EncodeToString(attachment.Data)
+		for len(encoded) > 76 {
+			fmt.Fprintf(part, "%s\r\n", encoded[:76])
+			encoded = encoded[76:]
+		}
+		fmt.Fprintf(part, "%s\r\n", encoded)
+	}
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+	return message.Bytes(), nil
+}
+
+// SendEmail sends the message through the server, authenticating if the username is set
+func SendEmail(options SMTPOptions, message []byte) error {
+	if len(options.To) == 0 {
+		return NoRecipientsError
+	}
+	port := options.Port
+	if port == 0 {
+		port = 587
+	}
+	address := net.JoinHostPort(options.Host, strconv.Itoa(port))
+	tlsConfig := &tls.Config{ServerName: options.Host}
+	var client *smtp.Client
+	if port == smtpsPort {
+		conn, err := tls.Dial("tcp", address, tlsConfig)
+		if err != nil {
+			return err
+		}
+		client, err = smtp.NewClient(conn, options.Host)
+		if err != nil {
+			conn.Close()
+			return err
+		}
+	} else {
+		var err error
+		client, err = smtp.Dial(address)
+		if err != nil {
+			return err
+		}
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			err = client.StartTLS(tlsConfig)
+			if err != nil {
+				client.Close()
+				return err
+			}
+		}
+	}
+	defer client.Close()
+
+	if options.Username != "" {
+		password := options.Password
+		if password == "" {
+			password = os.Getenv("SMTP_PASSWORD")
+		}
+		err := client.Auth(smtp.PlainAuth("", options.Username, password, options.Host))
+		if err != nil {
+			return err
+		}
+	}
+	err := client.Mail(options.From)
+	if err != nil {
+		return err
+	}
+	for _, to := range options.To {
+		err = client.Rcpt(to)
+		if err != nil {
+			return err
+		}
+	}
+	data, err := client.Data()
+	if err != nil {
+		return err
+	}
+	_, err = data.Write(message)
+	if err != nil {
+		return err
+	}
+	err = data.Close()
+	if err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// EmailReport sends the HTML report with the PDF report and the JSON summary attached
+func EmailReport(options SMTPOptions, report Report, summary *Summary) error {
+	var html, pdf bytes.Buffer
+	err := report.WriteHTML(&html)
+	if err != nil {
+		return err
+	}
+	err = report.WritePDF(&pdf)
+	if err != nil {
+		return err
+	}
+	data, err := jsonIndent(summary.Redacted())
+	if err != nil {
+		return err
+	}
+	attachments := []Attachment{{
+		Name:        fmt.Sprintf("report-%d.pdf", report.TaxYear),
+		ContentType: "application/pdf",
+		Data:        pdf.Bytes(),
+	}, {
+		Name:        fmt.Sprintf("summary-%d.json", report.TaxYear),
+		ContentType: "application/json",
+		Data:        data,
+	}}
+	message, err := NewMessage(options.From, options.To, report.Subject(), html.Bytes(), attachments)
+	if err != nil {
+		return err
+	}
+	return SendEmail(options, message)
+}
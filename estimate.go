@@ -0,0 +1,90 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"time"
+)
+
+// Default API limits of requests per minute by service, RateLimits in config.yaml overrides them
+const (
+	OperationsRequestsPerMinute  = 200
+	InstrumentsRequestsPerMinute = 200
+	MarketDataRequestsPerMinute  = 600
+)
+
+// defaultOperationsPageSize is the page size of cursor requests without a limit
+const defaultOperationsPageSize = 100
+
+// candlesPerRequest is the longest period of hourly candles the SDK gets by a single request
+const candlesPerRequest = 7 * 24 * time.Hour
+
+// instrumentRequests is the number of instruments requests to resolve an instrument:
+// the instrument itself, its asset and the currency of the asset instruments
+const instrumentRequests = 3
+
+// APIEstimate is a rough number of API requests by service
+type APIEstimate struct {
+	Operations  int
+	Instruments int
+	MarketData  int
+}
+
+// EstimateRequests estimates the requests of an evaluation of the operations and instruments
+func EstimateRequests(operations, instruments int) APIEstimate {
+	pageSize := int(OperationsPageSize)
+	if pageSize == 0 {
+		pageSize = defaultOperationsPageSize
+	}
+	return APIEstimate{
+		// the portfolio and the pages of operations
+		Operations:  1 + (operations+pageSize-1)/pageSize,
+		Instruments: instruments * instrumentRequests,
+		MarketData:  instruments * candleRequests(time.Date(TaxYear, 1, 1, 0, 0, 0, 0, Location)),
+	}
+}
+
+// candleRequests is the number of requests the SDK makes for hourly candles of an instrument since the time
+func candleRequests(from time.Time) int {
+	return max(1, int((candlesTo().Sub(from)+candlesPerRequest-1)/candlesPerRequest))
+}
+
+// Add sums the estimates, e.g. of several accounts
+func (e APIEstimate) Add(other APIEstimate) APIEstimate {
+	return APIEstimate{
+		Operations:  e.Operations + other.Operations,
+		Instruments: e.Instruments + other.Instruments,
+		MarketData:  e.MarketData + other.MarketData,
+	}
+}
+
+// Total returns the number of requests of all services
+func (e APIEstimate) Total() int {
+	return e.Operations + e.Instruments + e.MarketData
+}
+
+// Duration returns the time the requests take at the rate limits, services are limited separately
+func (e APIEstimate) Duration() time.Duration {
+	return max(
+		minutes(e.Operations, limiters[ServiceOperations].PerMinute()),
+		minutes(e.Instruments, limiters[ServiceInstruments].PerMinute()),
+		minutes(e.MarketData, limiters[ServiceMarketData].PerMinute()))
+}
+
+func minutes(requests, perMinute int) time.Duration {
+	return time.Duration(requests) * time.Minute / time.Duration(perMinute)
+}
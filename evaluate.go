@@ -0,0 +1,673 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"maps"
+	"math/big"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/status"
+	"opensource.tbank.ru/invest/invest-go/investgo"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// Evaluation is the result of going back in time for a single account
+type Evaluation struct {
+	AccountId string
+	Account   AccountInfo
+	// the person of the account from Persons, empty for the configured token
+	Person string
+	// client of the token of the account
+	client *investgo.Client
+	// positions of the asset traced with -asset
+	Asset *AssetTrace
+	// the state at the moment explained with -explain
+	Explanation *Explanation
+	// processed operations
+	Operations []*pb.OperationItem
+	// aggregate values during the tax year
+	Timeline Timeline
+	// aggregate value now
+	Current      *big.Rat
+	CurrentState *State
+	// assets valued separately from the maximum
+	Excluded         map[string]bool
+	BestState        *State
+	BestCost         map[string]*big.Rat
+	BestExcludedCost map[string]*big.Rat
+	BestTime         time.Time
+	BestAggregate    *big.Rat
+	// maximum values of the securities and the cash alone, they may be reached at other moments
+	BestSecurities Point
+	BestCash       Point
+	// the first time each reporting threshold was exceeded
+	Thresholds []ThresholdCrossing
+	// how long the value stayed near the maximum
+	Persistence PeakPersistence
+	// the maximum was reached outside the trading sessions of the trading calendar
+	PeakOffSession bool
+	// the candles the assets were valued by
+	Series []*PriceSeries
+	// the states at the end of each month of the tax year, the first one is at its start
+	Months MonthEnds
+	// the maximum by the conservative rules, nil if it was not searched
+	Conservative *ConservativeResult
+	// some assets were valued without candles
+	Partial bool
+	// discrepancies found by reconciliation with reports
+	Mismatches int
+	// the latest point of the tax year
+	YearEndTime time.Time
+	YearEndCost map[string]*big.Rat
+}
+
+// Gaps between candles from this long are logged as stale price intervals
+const defaultStaleGap = 72 * time.Hour
+
+// instrumentUid -> candles, shared by all accounts
+var candleCache = make(map[string][]*pb.HistoricCandle)
+
+// candlesTo is the end of the candles range. There are some issues with future prices reuse as we are going
+// backwards in time, so it works better to have some extra data on the border to get the best possible approximation.
+func candlesTo() time.Time {
+	return time.Date(TaxYear+1, 2, 1, 0, 0, 0, 0, Location)
+}
+
+func fetchCandles(md *investgo.MarketDataServiceClient, instrumentUid string, from time.Time) ([]*pb.HistoricCandle, error) {
+	req := &investgo.GetHistoricCandlesRequest{
+		Instrument: instrumentUid,
+		Interval:   pb.CandleInterval_CANDLE_INTERVAL_HOUR,
+		From:       from,
+		To:         candlesTo(),
+		Source:     candleSources[CandleSource],
+	}
+	// the SDK splits the period to several requests
+	limiters[ServiceMarketData].Wait(candleRequests(from))
+	start := time.Now()
+	candles, err := md.GetHistoricCandles(req)
+	TraceCall("GetCandles", req, start, candles, err)
+	return candles, err
+}
+
+func getCandles(md *investgo.MarketDataServiceClient, instrumentUid string) ([]*pb.HistoricCandle, error) {
+	if *incremental {
+		return getCandlesIncremental(md, instrumentUid)
+	}
+	if candles, ok := candleCache[instrumentUid]; ok {
+		return candles, nil
+	}
+	name := "candles-" + candlesKey(instrumentUid) + ".json"
+	var candles []*pb.HistoricCandle
+	if ok, _ := loadCheckpoint(name, &candles); ok {
+		candleCache[instrumentUid] = candles
+		return candles, nil
+	}
+	from := time.Date(TaxYear, 1, 1, 0, 0, 0, 0, Location)
+	candles, ok, err := getStoredCandles(instrumentUid, from)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		candleCache[instrumentUid] = candles
+		return candles, nil
+	}
+	candles, err = fetchAndStoreCandles(md, instrumentUid, from)
+	if err != nil {
+		return nil, err
+	}
+	candleCache[instrumentUid] = candles
+	// a failed checkpoint only means the candles are downloaded again after an interruption
+	_ = saveCheckpoint(name, candles)
+	return candles, nil
+}
+
+func evaluate(client *investgo.Client, logger *zap.Logger, options Options, currencyInstruments map[string]string,
+	account AccountInfo, command string) (*Evaluation, error) {
+	accountId := account.Id
+	in := client.NewInstrumentsServiceClient()
+	op := client.NewOperationsServiceClient()
+	span := StartSpan("evaluate", StringAttribute("account", Redact("account", accountId)))
+	defer span.End()
+
+	phase := StartSpan("portfolio")
+	logger.Debug("getting portfolio", zap.String("account", accountId))
+	now := time.Now()
+	updates := make(map[time.Time][]Update)
+	AwaitQuota("GetPortfolio")
+	start := time.Now()
+	positions, err := op.GetPortfolio(accountId, pb.PortfolioRequest_RUB)
+	TraceCall("GetPortfolio", accountId, start, positions, err)
+	if err != nil {
+		logger.Error("error getting portfolio", zap.Error(err))
+		return nil, err
+	}
+
+	state := &State{
+		Portfolio:  make(map[string]*big.Rat, len(positions.Positions)),
+		Prices:     make(map[string]*big.Rat, len(positions.Positions)),
+		Accrued:    make(map[string]*big.Rat),
+		Currencies: make(map[string]string, len(positions.Positions)),
+	}
+	// assets blocked by the broker or without candles
+	affected := make(map[string]bool)
+	// assetUid -> instrumentUid of the held assets with prices
+	held := make(map[string]string)
+	// instrumentUid -> assetUid of the assets held during the evaluated window
+	heldDuring := make(map[string]string)
+	logger.Debug("processing portfolio positions")
+	for _, position := range positions.Positions {
+		var key string
+		if currency, ok := currencyInstruments[position.PositionUid]; ok {
+			key = currency
+		} else {
+			var err error
+			key, err = getAssetUid(in, logger, position.InstrumentUid)
+			if err != nil {
+				logger.Error("error getting instrument for position",
+					zap.String("position", position.Figi),
+					zap.Error(err))
+				return nil, err
+			}
+			heldDuring[position.InstrumentUid] = key
+			if !IsFutures(key) && position.CurrentPrice == nil {
+				logger.Warn("position without current price, valuing it as a blocked asset",
+					zap.String("position", position.PositionUid),
+					zap.String("instrument", position.InstrumentUid),
+					zap.String("ticker", tickers[key]))
+				affected[key] = true
+			} else if !IsFutures(key) {
+				state.Prices[key] = ToRat(position.CurrentPrice)
+				state.Currencies[key] = NormalizeCurrency(position.CurrentPrice.Currency)
+				held[key] = position.InstrumentUid
+			}
+			if IsBond(key) {
+				state.Accrued[key] = ToRat(position.CurrentNkd)
+			}
+			if position.Blocked {
+				affected[key] = true
+			}
+		}
+		state.Portfolio[key] = AddRat(state.Portfolio[key], ToRat(position.Quantity))
+	}
+	if options.LastPrices {
+		err = ApplyLastPrices(client.NewMarketDataServiceClient(), in, logger, state, held)
+		if err != nil {
+			return nil, err
+		}
+	}
+	cost := maps.Clone(state.Portfolio)
+	SellAll(cost, state)
+	current := SubRat(Aggregate(cost), uncountedLiabilities(state, nil))
+	logger.Info("current portfolio",
+		zap.Any("portfolio", ToTickers(state.Portfolio)),
+		zap.Any("cost", FormatCost(cost)),
+		zap.String("aggregate", FormatUSD(current)))
+	phase.End()
+
+	evaluation := &Evaluation{
+		AccountId:     accountId,
+		Account:       account,
+		client:        client,
+		Current:       current,
+		BestState:     &State{},
+		BestAggregate: &big.Rat{},
+	}
+
+	var dividendOperations []*pb.OperationItem
+	// instrumentUid -> record dates of the paid dividends
+	paidDividends := make(map[string][]time.Time)
+	phase = StartSpan("operations")
+	logger.Debug("getting operations")
+	var operations []*pb.OperationItem
+	if *incremental {
+		operations, err = getOperationsIncremental(op, logger, accountId, account.Start(), now)
+	} else {
+		operations, err = getOperations(op, logger, accountId, account.Start(), now)
+	}
+	if err != nil {
+		return nil, err
+	}
+	unknown := make(map[string]bool)
+	for _, operation := range operations {
+		if _, ok := tickers[operation.AssetUid]; !ok && operation.InstrumentUid != "" {
+			unknown[operation.InstrumentUid] = true
+		}
+	}
+	EstimateQuota(logger, "instruments", len(unknown)*instrumentRequests, ServiceInstruments, options.Throttle)
+	for _, operation := range operations {
+		if operation.Payment == nil && operation.Type != pb.OperationType_OPERATION_TYPE_INPUT_SECURITIES {
+			logger.Warn("operation without payment, its cash movement is ignored",
+				zap.String("operation", operation.Id),
+				zap.Stringer("type", operation.Type),
+				zap.String("figi", operation.Figi),
+				zap.String("name", operation.Name),
+				zap.Time("date", operation.Date.AsTime()))
+		}
+		NormalizeOperationCurrencies(operation)
+		if _, ok := tickers[operation.AssetUid]; !ok {
+			_, err = getAssetUid(in, logger, operation.InstrumentUid)
+			if err != nil {
+				logger.Error("error getting instrument for operation",
+					zap.String("figi", operation.Figi),
+					zap.String("name", operation.Name),
+					zap.String("description", operation.Description),
+					zap.Error(err))
+				return nil, err
+			}
+		}
+		if operation.AssetUid != "" {
+			assets[operation.InstrumentUid] = operation.AssetUid
+			heldDuring[operation.InstrumentUid] = operation.AssetUid
+		}
+		update, err := OperationToUpdate(operation)
+		if err != nil {
+			logger.Error("cannot process operation",
+				zap.Error(err),
+				zap.Any("operation", operation))
+			return nil, err
+		}
+		evaluation.Operations = append(evaluation.Operations, operation)
+		date := operation.Date.AsTime()
+		if InTaxYear(date) && operation.Type == pb.OperationType_OPERATION_TYPE_DIVIDEND {
+			dividendOperations = append(dividendOperations, operation)
+		}
+		if options.DividendReceivables && operation.Type == pb.OperationType_OPERATION_TYPE_DIVIDEND {
+			calendar, err := getDividends(in, logger, operation.InstrumentUid)
+			if err != nil {
+				logger.Error("error getting dividends for operation",
+					zap.String("figi", operation.Figi),
+					zap.String("name", operation.Name),
+					zap.Error(err))
+				return nil, err
+			}
+			// the paid dividend was a receivable since the record date
+			if record, ok := FindRecordDate(calendar, date); ok {
+				date = record
+				paidDividends[operation.InstrumentUid] = append(paidDividends[operation.InstrumentUid], record)
+			}
+		}
+		updates[date] = append(updates[date], update)
+	}
+	logger.Info("instruments", zap.Any("assets", assets), zap.Any("tickers", tickers))
+	if FollowsStrategy(evaluation.Operations) {
+		evaluation.Account.Strategy = true
+		logger.Warn("account follows an auto-following strategy, its positions are managed by the strategy author "+
+			"and may change without operations visible here, check the result against the broker report",
+			zap.String("account", accountId))
+	}
+	phase.SetAttributes(IntAttribute("operations", len(evaluation.Operations)))
+	phase.End()
+
+	if *reconcileDividends {
+		mismatches, err := ReconcileDividends(in, op, logger, accountId, dividendOperations,
+			time.Date(TaxYear, 1, 1, 0, 0, 0, 0, Location), now)
+		if err != nil {
+			logger.Error("error reconciling dividends", zap.Error(err))
+			return nil, err
+		}
+		logger.Info("dividends reconciled",
+			zap.Int("operations", len(dividendOperations)),
+			zap.Int("mismatches", mismatches))
+		evaluation.Mismatches += mismatches
+	}
+
+	for _, action := range options.CorporateActions {
+		update, err := CorporateActionToUpdate(action)
+		if errors.Is(err, UnknownAssetError) {
+			logger.Warn("corporate action of an asset without operations or positions, skipping it",
+				zap.Error(err),
+				zap.Time("date", action.Date))
+			continue
+		}
+		if err != nil {
+			logger.Error("cannot process corporate action",
+				zap.Error(err),
+				zap.Any("action", action))
+			return nil, err
+		}
+		updates[action.Date] = append(updates[action.Date], update)
+	}
+
+	excluded := make(map[string]bool, len(options.ExcludeAssets))
+	for _, id := range options.ExcludeAssets {
+		assetUid, ok := FindAsset(id)
+		if !ok {
+			logger.Warn("cannot find excluded asset", zap.String("asset", id))
+			continue
+		}
+		excluded[assetUid] = true
+	}
+
+	OvernightReceivables(evaluation.Operations, state)
+	if options.DividendReceivables {
+		receivables, err := DividendReceivables(in, logger, heldDuring, paidDividends, state, updates,
+			account.Start(), now)
+		if err != nil {
+			logger.Error("error getting declared dividends", zap.Error(err))
+			return nil, err
+		}
+		for date, receivable := range receivables {
+			updates[date] = append(updates[date], receivable...)
+		}
+	}
+
+	latest := make(map[string]LatestPrice)
+	var series []*PriceSeries
+	var skipped []SkippedInstrument
+	md := client.NewMarketDataServiceClient()
+	staleGap := defaultStaleGap
+	if options.StaleGap != "" {
+		staleGap, err = time.ParseDuration(options.StaleGap)
+		if err != nil {
+			logger.Error("invalid stale gap", zap.String("gap", options.StaleGap), zap.Error(err))
+			return nil, err
+		}
+	}
+	phase = StartSpan("candles")
+	instruments := SortedInstruments()
+	if *fast {
+		logger.Info("fast mode, prices are taken from trades and the current portfolio instead of candles")
+		TradePrices(evaluation.Operations, updates, latest)
+		instruments = nil
+	}
+	traded := TradedCurrencies(evaluation.Operations)
+	venues := Venues(logger, held, evaluation.Operations, traded)
+	histories := CurrencyHistories(logger, evaluation.Operations)
+	// cached and stored candles are not known here, so it is the upper bound
+	uncached := 0
+	for _, instrumentUid := range instruments {
+		venue, ok := venues[assets[instrumentUid]]
+		if _, cached := candleCache[instrumentUid]; !cached && !IsFutures(assets[instrumentUid]) && (!ok || venue == instrumentUid) {
+			uncached++
+		}
+	}
+	EstimateQuota(logger, "candles", uncached*candleRequests(time.Date(TaxYear, 1, 1, 0, 0, 0, 0, Location)),
+		ServiceMarketData, options.Throttle)
+	for _, instrumentUid := range instruments {
+		assetUid := assets[instrumentUid]
+		if IsFutures(assetUid) {
+			logger.Debug("skipping candles for futures",
+				zap.String("instrument", instrumentUid),
+				zap.String("asset", assetUid),
+				zap.String("ticker", tickers[assetUid]))
+			continue
+		}
+		if venue, ok := venues[assetUid]; ok && venue != instrumentUid {
+			logger.Debug("skipping candles of another venue",
+				zap.String("instrument", instrumentUid),
+				zap.String("venue", venue),
+				zap.String("asset", assetUid),
+				zap.String("ticker", tickers[assetUid]))
+			continue
+		}
+		if !options.CandleFilter.Allows(assetUid, instrumentUid) {
+			logger.Info("skipping candles by the candle filter",
+				zap.String("instrument", instrumentUid),
+				zap.String("asset", assetUid),
+				zap.String("ticker", tickers[assetUid]))
+			continue
+		}
+		logger.Debug("getting candles",
+			zap.String("instrument", instrumentUid),
+			zap.String("asset", assetUid),
+			zap.String("ticker", tickers[assetUid]))
+		candles, skippedErr, err := getCandlesWithPolicy(md, logger, options.CandleErrors, instrumentUid)
+		if err != nil {
+			logger.Error("error getting candles for instrument",
+				zap.String("instrument", instrumentUid),
+				zap.String("asset", assetUid),
+				zap.String("ticker", tickers[assetUid]),
+				zap.Error(err))
+			return nil, err
+		}
+		if skippedErr != nil || len(candles) == 0 {
+			if fallback := getFallbackCandles(md, logger, instrumentUid); len(fallback) > 0 {
+				candles, skippedErr = fallback, nil
+				candleCache[instrumentUid] = fallback
+			}
+		}
+		if skippedErr != nil {
+			logger.Warn("skipping candles for instrument",
+				zap.String("instrument", instrumentUid),
+				zap.String("asset", assetUid),
+				zap.String("ticker", tickers[assetUid]),
+				zap.Error(skippedErr))
+			skipped = append(skipped, SkippedInstrument{
+				InstrumentUid: instrumentUid,
+				AssetUid:      assetUid,
+				Code:          status.Code(skippedErr),
+			})
+			continue
+		}
+		logger.Debug("processing candles",
+			zap.String("instrument", instrumentUid),
+			zap.String("asset", assetUid),
+			zap.String("ticker", tickers[assetUid]))
+		asset := assetUid
+		currency := instrumentCurrencies[instrumentUid]
+		var nominal *big.Rat
+		var nominals NominalHistory
+		var interests []*pb.AccruedInterest
+		if IsBond(assetUid) {
+			logger.Debug("getting bond nominal",
+				zap.String("instrument", instrumentUid),
+				zap.String("asset", assetUid),
+				zap.String("ticker", tickers[assetUid]))
+			bondNominal, err := getNominal(in, instrumentUid)
+			if err != nil {
+				logger.Error("error getting bond for instrument",
+					zap.String("instrument", instrumentUid),
+					zap.String("asset", assetUid),
+					zap.String("ticker", tickers[assetUid]),
+					zap.Error(err))
+				return nil, err
+			}
+			nominal = ToRat(bondNominal)
+			currency = NormalizeCurrency(bondNominal.Currency)
+
+			logger.Debug("getting accrued interest",
+				zap.String("instrument", instrumentUid),
+				zap.String("asset", assetUid),
+				zap.String("ticker", tickers[assetUid]))
+			AwaitQuota("GetAccruedInterests")
+			start := time.Now()
+			response, err := in.GetAccruedInterests(instrumentUid,
+				time.Date(TaxYear, 1, 1, 0, 0, 0, 0, Location),
+				time.Date(TaxYear+1, 2, 1, 0, 0, 0, 0, Location))
+			TraceCall("GetAccruedInterests", instrumentUid, start, response, err)
+			if err != nil {
+				logger.Error("error getting accrued interest for instrument",
+					zap.String("instrument", instrumentUid),
+					zap.String("asset", assetUid),
+					zap.String("ticker", tickers[assetUid]),
+					zap.Error(err))
+				return nil, err
+			}
+			interests = response.AccruedInterests
+			// amortizing bonds are priced by the nominal at the candle date
+			nominals = NewNominalHistory(interests)
+		}
+		priceSeries := NewPriceSeries(logger, latest, asset, currency, nominal, nominals, histories[instrumentUid],
+			candles, staleGap)
+		priceSeries.Instrument = instrumentUid
+		series = append(series, priceSeries)
+
+		for _, interest := range interests {
+			date := interest.Date.AsTime()
+			value := ToRat(interest.Value)
+			updates[date] = append(updates[date], func(state *State) {
+				state.Accrued[asset] = value
+			})
+		}
+	}
+
+	for _, assetUid := range assets {
+		// assets without trades keep their current prices in the fast mode
+		if _, ok := latest[assetUid]; !ok && !IsFutures(assetUid) && !*fast {
+			affected[assetUid] = true
+		}
+	}
+	phase.SetAttributes(IntAttribute("series", len(series)), IntAttribute("skipped", len(skipped)))
+	phase.End()
+	evaluation.Series = series
+	ApplyBlockedPolicy(logger, options.BlockedAssets, state, affected, latest, traded)
+	ReportSkipped(logger, skipped, state, excluded)
+	evaluation.Partial = len(affected) > 0
+	evaluation.CurrentState = state.Clone()
+	evaluation.Excluded = excluded
+
+	if *whatIfFile != "" {
+		whatIfs, err := LoadWhatIfs(*whatIfFile)
+		if err != nil {
+			logger.Error("error loading hypothetical operations", zap.Error(err))
+			return nil, err
+		}
+		for _, whatIf := range whatIfs {
+			if _, ok := state.Prices[whatIf.Asset]; !ok {
+				if assetUid, ok := FindAsset(whatIf.Asset); ok {
+					if last, ok := latest[assetUid]; ok {
+						state.Prices[assetUid] = last.Price
+						state.Currencies[assetUid] = last.Currency
+					}
+				}
+			}
+			update, err := ApplyWhatIf(whatIf, state)
+			if err != nil {
+				logger.Error("cannot apply hypothetical operation",
+					zap.Error(err),
+					zap.Any("operation", whatIf))
+				return nil, err
+			}
+			updates[whatIf.Date] = append(updates[whatIf.Date], update)
+			logger.Info("applied hypothetical operation", zap.Any("operation", whatIf))
+		}
+		// evaluate the projected portfolio after all hypothetical operations
+		yearEnd := time.Date(TaxYear+1, 1, 1, 0, 0, 0, 0, Location).Add(-time.Nanosecond)
+		if yearEnd.After(now) {
+			updates[yearEnd] = append(updates[yearEnd], func(*State) {})
+		}
+	}
+
+	var months MonthEnds
+	thresholds := NewThresholdTracker(logger, options.Thresholds)
+	movers := NewMoverTracker(logger, options.Movers, excluded)
+	if *traceAsset != "" {
+		evaluation.Asset = NewAssetTrace(logger, *traceAsset)
+	}
+	if !ExplainTime.IsZero() {
+		evaluation.Explanation = &Explanation{Requested: ExplainTime}
+	}
+
+	logger.Info("going back in time", zap.Uint("tax_year", TaxYear))
+	phase = StartSpan("replay")
+	err = Replay(logger, evaluation, state, updates, series, excluded, &months, movers, thresholds)
+	if err != nil {
+		phase.Fail(err.Error())
+		return nil, err
+	}
+	phase.SetAttributes(IntAttribute("points", len(evaluation.Timeline)))
+	phase.End()
+	slices.Reverse(evaluation.Timeline)
+	if evaluation.Asset != nil {
+		slices.Reverse(evaluation.Asset.Points)
+	}
+	evaluation.Months = months
+	if *conservative {
+		logger.Info("going back in time by the conservative rules")
+		phase = StartSpan("conservative")
+		best := ConservativeMaximum(evaluation, state, updates, ConservativeSeries(series, staleGap), excluded)
+		phase.End()
+		evaluation.Conservative = &ConservativeResult{Best: best, CarryLimit: staleGap}
+		logger.Info("conservative best portfolio",
+			zap.String("account", accountId),
+			zap.Time("time", best.Time),
+			zap.String("aggregate", FormatUSD(best.Aggregate)))
+	}
+	evaluation.Persistence = Persistence(evaluation.Timeline, evaluation.BestTime, evaluation.BestAggregate)
+	evaluation.PeakOffSession = outsideSessions(evaluation.BestTime)
+	if evaluation.PeakOffSession {
+		logger.Warn("maximum is outside the trading sessions",
+			zap.String("account", accountId),
+			zap.String("exchange", tradingCalendar.Exchange),
+			zap.Time("time", evaluation.BestTime))
+	}
+	for _, age := range PriceAges(series, evaluation.BestState.Portfolio, evaluation.BestTime) {
+		if age.Age >= staleDays {
+			logger.Warn("price at the maximum is days stale",
+				zap.String("account", accountId),
+				zap.String("asset", age.Asset),
+				zap.String("ticker", tickers[age.Asset]),
+				zap.Time("candle", age.Candle),
+				zap.Duration("age", age.Age))
+		}
+	}
+	logger.Info("best portfolio",
+		zap.String("account", accountId),
+		zap.String("name", account.Name),
+		zap.String("type", account.Type),
+		zap.Time("time", evaluation.BestTime),
+		zap.String("aggregate", FormatUSD(evaluation.BestAggregate)))
+	logger.Debug("best portfolio holdings",
+		zap.String("account", accountId),
+		zap.Any("portfolio", ToTickers(evaluation.BestState.Portfolio)),
+		zap.Any("prices", ToTickers(evaluation.BestState.Prices)),
+		zap.Any("cost", FormatCost(evaluation.BestCost)))
+	thresholds.Report(logger)
+	evaluation.Thresholds = thresholds.Crossings()
+	if len(excluded) > 0 {
+		logger.Debug("excluded assets at best time",
+			zap.Any("portfolio", ToTickers(Exclude(maps.Clone(evaluation.BestState.Portfolio), excluded))),
+			zap.Any("cost", FormatCost(evaluation.BestExcludedCost)),
+			zap.String("aggregate", FormatUSD(Aggregate(evaluation.BestExcludedCost))))
+	}
+
+	if *forwardFile != "" {
+		snapshots, err := LoadSnapshots(*forwardFile)
+		if err != nil {
+			logger.Error("error loading snapshots", zap.Error(err))
+			return nil, err
+		}
+		if snapshot, ok := snapshots[accountId]; ok {
+			forward, err := ForwardReplay(snapshot, evaluation.Operations, options.CorporateActions, now)
+			if err != nil {
+				logger.Error("error replaying operations forward", zap.Error(err))
+				return nil, err
+			}
+			mismatches := CompareForward(logger, forward, &months)
+			logger.Info("forward replay compared", zap.Int("mismatches", mismatches))
+			evaluation.Mismatches += mismatches
+		} else {
+			logger.Warn("no snapshot for account", zap.String("account", accountId))
+		}
+	}
+
+	if command == "broker-report" {
+		mismatches, err := CompareBrokerReports(op, logger, accountId, &months, evaluation.Operations,
+			options.CorporateActions, now)
+		if err != nil {
+			logger.Error("error comparing with broker reports", zap.Error(err))
+			return nil, err
+		}
+		logger.Info("broker reports compared", zap.Int("mismatches", mismatches))
+		evaluation.Mismatches += mismatches
+	}
+	return evaluation, nil
+}
@@ -0,0 +1,67 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"math/big"
+
+	"google.golang.org/grpc/status"
+)
+
+// Exit codes for scripts
+const (
+	ExitSuccess = 0
+	// errors other than listed below, e.g. writing files or unsupported operations
+	ExitFailure = 1
+	// some assets were valued without candles
+	ExitPartialData = 2
+	// reconciliation with reports found discrepancies
+	ExitMismatch = 3
+	ExitConfig   = 4
+	ExitAPI      = 5
+	// the maximum exceeds the -threshold value
+	ExitThreshold = 10
+)
+
+// ExitCode classifies the error that stopped the run
+func ExitCode(err error) int {
+	if errors.Is(err, NoAccountAccessError) || errors.Is(err, TradingTokenError) {
+		return ExitConfig
+	}
+	if _, ok := status.FromError(err); ok && err != nil {
+		return ExitAPI
+	}
+	return ExitFailure
+}
+
+// ResultCode returns the exit code of a completed run
+func ResultCode(evaluations []*Evaluation, maximum, threshold *big.Rat) int {
+	code := ExitSuccess
+	for _, evaluation := range evaluations {
+		switch {
+		case evaluation.Mismatches > 0:
+			code = ExitMismatch
+		case evaluation.Partial && code == ExitSuccess:
+			code = ExitPartialData
+		}
+	}
+	if code == ExitSuccess && threshold != nil && maximum.Cmp(threshold) > 0 {
+		code = ExitThreshold
+	}
+	return code
+}
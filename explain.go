@@ -0,0 +1,228 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math/big"
+	"slices"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+var InvalidExplainTimeError = errors.New("invalid time to explain, e.g. 2025-03-14T10:00Z expected")
+
+// Layouts of the explained time, the ones without a zone are in the reporting timezone
+var explainLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04Z07:00", "2006-01-02T15:04:05", "2006-01-02T15:04",
+	time.DateTime, "2006-01-02 15:04", time.DateOnly}
+
+// ExplainTime is the moment explained with -explain, zero if none
+var ExplainTime time.Time
+
+// ParseExplainTime parses the moment to explain with or without seconds and the timezone
+func ParseExplainTime(value string) (time.Time, error) {
+	for _, layout := range explainLayouts {
+		if date, err := time.ParseInLocation(layout, value, Location); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: %q", InvalidExplainTimeError, value)
+}
+
+// Explanation is the state of the account at the explained moment, the state of the last point at or before it
+type Explanation struct {
+	Requested time.Time
+	// zero if there is no point of the tax year at or before the moment
+	Time  time.Time
+	State *State
+}
+
+// Observe keeps the first state at or before the explained moment, the points come back in time
+func (e *Explanation) Observe(date time.Time, state *State) {
+	if e == nil || e.State != nil || date.After(e.Requested) {
+		return
+	}
+	e.Time, e.State = date, state
+}
+
+// explainedPosition is the value of a position in the explanation
+type explainedPosition struct {
+	key      string
+	quantity *big.Rat
+	// price with the accrued interest, nil for currencies
+	price    *big.Rat
+	accrued  *big.Rat
+	currency string
+	// in the currency, nil if the position is not counted
+	amount *big.Rat
+	usd    *big.Rat
+	source string
+}
+
+// priceSource is the candle of a series a price at some time comes from
+type priceSource struct {
+	series *PriceSeries
+	// the first candle at or after the time
+	index int
+	// the price of the previous candle is carried forward over the gap before the candle
+	carried bool
+	// when the price was set going back in time
+	applied time.Time
+}
+
+// sourceAt finds the candle the price of the series at the time comes from the way the replay sets it,
+// false after the last candle when the price of the portfolio is used
+func sourceAt(s *PriceSeries, date time.Time) (priceSource, bool) {
+	i := sort.Search(len(s.Candles), func(i int) bool {
+		return !s.Candles[i].Time.AsTime().Before(date)
+	})
+	if i == len(s.Candles) {
+		return priceSource{}, false
+	}
+	source := priceSource{series: s, index: i, applied: s.Candles[i].Time.AsTime()}
+	if source.applied.After(date) && s.gapBefore(i) {
+		source.carried = true
+		source.applied = source.applied.Add(-time.Nanosecond)
+	}
+	return source, true
+}
+
+func (p priceSource) String() string {
+	field := T("high")
+	if p.series.Close {
+		field = T("close")
+	}
+	candle := func(i int) string {
+		return p.series.Candles[i].Time.AsTime().In(Location).Format(time.DateTime)
+	}
+	instrument := Redact("instrument", p.series.Instrument)
+	switch {
+	case p.carried && p.series.beyondCarryLimit(p.index):
+		return fmt.Sprintf(T("lower %s of the candles at %s and %s of %s"), field, candle(p.index-1),
+			candle(p.index), instrument)
+	case p.carried:
+		return fmt.Sprintf(T("%s of the candle at %s of %s carried over the gap"), field, candle(p.index-1),
+			instrument)
+	}
+	return fmt.Sprintf(T("%s of the candle at %s of %s"), field, candle(p.index), instrument)
+}
+
+// explainPositions values each position the way Cost does and tells where its price comes from
+func explainPositions(state *State, date time.Time, series []*PriceSeries, excluded map[string]bool) []explainedPosition {
+	// the price of an asset with several instruments comes from the candle set last going back in time
+	sources := make(map[string]priceSource)
+	for _, s := range series {
+		source, ok := sourceAt(s, date)
+		if current, found := sources[s.Asset]; ok && (!found || source.applied.Before(current.applied)) {
+			sources[s.Asset] = source
+		}
+	}
+	var positions []explainedPosition
+	for _, key := range slices.SortedFunc(maps.Keys(state.Portfolio), ByTicker) {
+		quantity := state.Portfolio[key]
+		position := explainedPosition{key: key, quantity: quantity}
+		if _, ok := ExchangeRates[key]; ok {
+			position.currency = key
+			position.amount = quantity
+			position.source = T("currency")
+		} else if price, ok := state.Prices[key]; ok {
+			position.currency = state.Currencies[key]
+			position.accrued = state.Accrued[key]
+			position.price = AddRat(price, position.accrued)
+			position.amount = (&big.Rat{}).Mul(position.price, quantity)
+			position.source = T("price of the portfolio")
+			if source, ok := sources[key]; ok {
+				position.source = source.String()
+			}
+		} else {
+			position.source = T("no price")
+		}
+		switch {
+		case IsFutures(key):
+			position.amount = nil
+			position.source = T("futures are not counted")
+		case excluded[key]:
+			position.amount = nil
+			position.source = T("excluded from the maximum")
+		}
+		if rate, ok := ExchangeRates[position.currency]; ok && position.amount != nil {
+			position.usd = (&big.Rat{}).Quo(position.amount, rate)
+		}
+		positions = append(positions, position)
+	}
+	return positions
+}
+
+// PrintExplanation prints the positions at the explained moment with their prices, their sources, the exchange
+// rates and the sum producing the aggregate value
+func PrintExplanation(w io.Writer, evaluation *Evaluation) error {
+	explanation := evaluation.Explanation
+	if explanation == nil {
+		return nil
+	}
+	if explanation.State == nil {
+		fmt.Fprintf(w, T("No point of the tax year at or before %s\n"),
+			explanation.Requested.In(Location).Format(time.DateTime))
+		return nil
+	}
+	state := explanation.State
+	fmt.Fprintf(w, T("State at %s of the point at %s\n"), explanation.Requested.In(Location).Format(time.DateTime),
+		explanation.Time.In(Location).Format(time.DateTime))
+	tw := NewTable(w, tabwriter.AlignRight)
+	fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\tUSD\t%s\t\n", T("TICKER"), T("QUANTITY"), T("PRICE"), T("ACCRUED"),
+		T("CURRENCY"), T("AMOUNT"), T("PER USD"), T("SOURCE"))
+	sum := &big.Rat{}
+	for _, position := range explainPositions(state, explanation.Time, evaluation.Series, evaluation.Excluded) {
+		name := position.key
+		if ticker, ok := tickers[position.key]; ok {
+			name = ticker
+		}
+		price, accrued, amount, rate, usd := "", "", "-", "", "-"
+		if position.price != nil {
+			price = formatDecimal(position.price)
+		}
+		if position.accrued != nil {
+			accrued = formatDecimal(position.accrued)
+		}
+		if position.amount != nil {
+			amount = FormatMoney(position.amount, position.currency)
+		}
+		if exchangeRate, ok := ExchangeRates[position.currency]; ok {
+			rate = formatDecimal(exchangeRate)
+		}
+		if position.usd != nil {
+			usd = FormatUSD(position.usd)
+			sum = AddRat(sum, position.usd)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n", redactKey(name),
+			formatDecimal(ScaleAmount(position.quantity)), price, accrued, position.currency, amount, rate, usd,
+			position.source)
+	}
+	tw.Total()
+	fmt.Fprintf(tw, "%s\t\t\t\t\t\t\t%s\t\t\n", T("total"), FormatUSD(sum))
+	if liabilities := uncountedLiabilities(state, evaluation.Excluded); liabilities.Sign() != 0 {
+		fmt.Fprintf(tw, "%s\t\t\t\t\t\t\t%s\t\t\n", T("liabilities"),
+			FormatUSD((&big.Rat{}).Neg(liabilities)))
+	}
+	_, _, aggregate := Cost(state, evaluation.Excluded)
+	fmt.Fprintf(tw, "%s\t\t\t\t\t\t\t%s\t\t\n", T("aggregate"), FormatUSD(aggregate))
+	return tw.Flush()
+}
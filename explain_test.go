@@ -0,0 +1,102 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+func TestParseExplainTime(t *testing.T) {
+	defer func(location *time.Location) { Location = location }(Location)
+	Location = time.FixedZone("MSK", 3*60*60)
+	for value, expected := range map[string]time.Time{
+		"2025-03-14T10:00Z":         time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC),
+		"2025-03-14T10:00:30+03:00": time.Date(2025, 3, 14, 7, 0, 30, 0, time.UTC),
+		"2025-03-14T10:00":          time.Date(2025, 3, 14, 7, 0, 0, 0, time.UTC),
+		"2025-03-14 10:00:00":       time.Date(2025, 3, 14, 7, 0, 0, 0, time.UTC),
+		"2025-03-14":                time.Date(2025, 3, 13, 21, 0, 0, 0, time.UTC),
+	} {
+		date, err := ParseExplainTime(value)
+		if err != nil {
+			t.Errorf("%s: %v", value, err)
+		} else if !date.Equal(expected) {
+			t.Errorf("%s: expected %s, got %s", value, expected, date)
+		}
+	}
+	if _, err := ParseExplainTime("14.03.2025"); !errors.Is(err, InvalidExplainTimeError) {
+		t.Errorf("expected an invalid time error, got %v", err)
+	}
+}
+
+func TestExplanationObserve(t *testing.T) {
+	date := time.Date(TaxYear, 3, 14, 10, 0, 0, 0, time.UTC)
+	explanation := &Explanation{Requested: date}
+	state := func(quantity int64) *State {
+		return &State{Portfolio: map[string]*big.Rat{"usd": big.NewRat(quantity, 1)}}
+	}
+	// back in time, the first point at or before the moment is kept
+	explanation.Observe(date.Add(time.Hour), state(3))
+	explanation.Observe(date.Add(-time.Hour), state(2))
+	explanation.Observe(date.Add(-2*time.Hour), state(1))
+	if !explanation.Time.Equal(date.Add(-time.Hour)) || explanation.State.Portfolio["usd"].Cmp(big.NewRat(2, 1)) != 0 {
+		t.Errorf("expected the point an hour before, got %s %v", explanation.Time, explanation.State.Portfolio)
+	}
+	var none *Explanation
+	none.Observe(date, state(1))
+}
+
+func TestSourceAt(t *testing.T) {
+	start := time.Date(TaxYear, 3, 2, 10, 0, 0, 0, time.UTC)
+	candle := func(hours, price int64) *pb.HistoricCandle {
+		return &pb.HistoricCandle{
+			Time: timestamppb.New(start.Add(time.Duration(hours) * time.Hour)),
+			High: &pb.Quotation{Units: price},
+		}
+	}
+	series := &PriceSeries{Asset: "a", Instrument: "i", Currency: "rub",
+		Candles: []*pb.HistoricCandle{candle(0, 10), candle(1, 11), candle(5, 15)}}
+	for _, test := range []struct {
+		hours   float64
+		index   int
+		carried bool
+		found   bool
+	}{
+		{-1, 0, false, true},
+		{0, 0, false, true},
+		// the next candle within an hour
+		{0.5, 1, false, true},
+		{1, 1, false, true},
+		// the price before the gap is carried forward
+		{3, 2, true, true},
+		{5, 2, false, true},
+		// the price of the portfolio after the last candle
+		{6, 0, false, false},
+	} {
+		date := start.Add(time.Duration(test.hours * float64(time.Hour)))
+		source, found := sourceAt(series, date)
+		if found != test.found || found && (source.index != test.index || source.carried != test.carried) {
+			t.Errorf("%v hours: expected %d carried %v found %v, got %d carried %v found %v", test.hours,
+				test.index, test.carried, test.found, source.index, source.carried, found)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"math/big"
+	"slices"
+	"text/tabwriter"
+)
+
+// percent formats the share of the total
+func percent(value, total *big.Rat) string {
+	if total.Sign() == 0 {
+		return "-"
+	}
+	share := (&big.Rat{}).Quo(value, total)
+	return share.Mul(share, big.NewRat(100, 1)).FloatString(1) + "%"
+}
+
+// PrintExposure prints the value by the currency each instrument trades in,
+// the cost is the portfolio with all assets sold in their trading currencies
+func PrintExposure(w io.Writer, cost map[string]*big.Rat) error {
+	total := Aggregate(cost)
+	tw := NewTable(w, tabwriter.AlignRight)
+	fmt.Fprintf(tw, "%s\t%s\tUSD\t%s\t\n", T("CURRENCY"), T("AMOUNT"), T("SHARE"))
+	for _, currency := range slices.Sorted(maps.Keys(cost)) {
+		usd := Aggregate(map[string]*big.Rat{currency: cost[currency]})
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\n", currency,
+			FormatMoney(cost[currency], currency), FormatUSD(usd), percent(usd, total))
+	}
+	tw.Total()
+	fmt.Fprintf(tw, "%s\t\t%s\t%s\t\n", T("total"), FormatUSD(total), percent(total, total))
+	return tw.Flush()
+}
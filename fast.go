@@ -0,0 +1,49 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"time"
+
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// TradePrices adds updates setting the prices of traded assets to their trade prices,
+// it replaces candles in the fast mode, other assets keep their current prices
+func TradePrices(operations []*pb.OperationItem, updates map[time.Time][]Update, latest map[string]LatestPrice) {
+	for _, operation := range operations {
+		switch operation.Type {
+		case pb.OperationType_OPERATION_TYPE_BUY, pb.OperationType_OPERATION_TYPE_SELL:
+		default:
+			continue
+		}
+		if operation.Price == nil || operation.AssetUid == "" || IsFutures(operation.AssetUid) {
+			continue
+		}
+		date := operation.Date.AsTime()
+		asset := operation.AssetUid
+		price := ToRat(operation.Price)
+		currency := operation.Price.Currency
+		updates[date] = append(updates[date], func(state *State) {
+			state.Prices[asset] = price
+			state.Currencies[asset] = currency
+		})
+		if date.After(latest[asset].Time) {
+			latest[asset] = LatestPrice{Time: date, Price: price, Currency: currency}
+		}
+	}
+}
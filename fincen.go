@@ -0,0 +1,98 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"time"
+)
+
+// FinancialInstitution is the institution where the account is held
+type FinancialInstitution struct {
+	Name       string `json:"name"`
+	Address    string `json:"address"`
+	City       string `json:"city"`
+	PostalCode string `json:"postal_code"`
+	Country    string `json:"country"`
+}
+
+// TBank is the broker of T-Bank Invest accounts
+var TBank = FinancialInstitution{
+	Name:       "JSC T-Bank",
+	Address:    "38A 2nd Khutorskaya St.",
+	City:       "Moscow",
+	PostalCode: "127287",
+	Country:    "RU",
+}
+
+// FBARAccount is a filled Part II of FinCEN Form 114 for a separately owned account
+type FBARAccount struct {
+	// maximum value rounded up to the next whole dollar
+	MaximumValue  string     `json:"maximum_value"`
+	MaximumTime   time.Time  `json:"maximum_time"`
+	AccountType   string     `json:"account_type"`
+	AccountNumber string     `json:"account_number"`
+	AccountName   string     `json:"account_name,omitempty"`
+	OpenedDate    *time.Time `json:"opened_date,omitempty"`
+	ClosedDate    *time.Time `json:"closed_date,omitempty"`
+	// Form 8938 asks whether the account was opened or closed during the tax year
+	OpenedDuringYear bool                 `json:"opened_during_year,omitempty"`
+	ClosedDuringYear bool                 `json:"closed_during_year,omitempty"`
+	Institution      FinancialInstitution `json:"institution"`
+}
+
+// FBARReport is a fill-ready structure of the foreign accounts section of FinCEN Form 114
+type FBARReport struct {
+	CalendarYear int    `json:"calendar_year"`
+	RateSource   string `json:"rate_source"`
+	// number of accounts with the same institution is reported in Part I
+	NumberOfAccounts int           `json:"number_of_accounts"`
+	Accounts         []FBARAccount `json:"accounts"`
+	Provenance       Provenance    `json:"provenance"`
+}
+
+// FBARValue rounds the value up to the next whole dollar as FinCEN Form 114 requires
+func FBARValue(value *big.Rat) string {
+	return Round(value, 0, RoundUp).FloatString(0)
+}
+
+// NewFBARReport fills the report for the evaluated accounts, the account numbers and names are replaced
+// with pseudonyms and the values are scaled when redacting
+func NewFBARReport(evaluations []*Evaluation, provenance Provenance) *FBARReport {
+	report := &FBARReport{
+		CalendarYear:     TaxYear,
+		RateSource:       "Treasury Reporting Rates of Exchange",
+		NumberOfAccounts: len(evaluations),
+		Provenance:       provenance,
+	}
+	for _, evaluation := range evaluations {
+		entry := FBARAccount{
+			MaximumValue:     FBARValue(ScaleAmount(evaluation.BestAggregate)),
+			MaximumTime:      evaluation.BestTime,
+			AccountType:      "Securities",
+			AccountNumber:    Redact("account", evaluation.AccountId),
+			AccountName:      Redact("name", evaluation.Account.Name),
+			OpenedDate:       evaluation.Account.OpenedDate,
+			ClosedDate:       evaluation.Account.ClosedDate,
+			OpenedDuringYear: evaluation.Account.OpenedInTaxYear(),
+			ClosedDuringYear: evaluation.Account.ClosedInTaxYear(),
+			Institution:      TBank,
+		}
+		report.Accounts = append(report.Accounts, entry)
+	}
+	return report
+}
@@ -0,0 +1,194 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math"
+	"math/big"
+	"math/bits"
+)
+
+// Fixed is an amount in billionths, the fixed-point alternative to big.Rat for the hot replay loop.
+// Amounts from about 9.2 billion overflow it, the operations report that, so the exact values are used instead.
+type Fixed int64
+
+const fixedScale = 1_000_000_000
+
+var bigFixedScale = big.NewInt(fixedScale)
+
+// FixedFromRat rounds the value half away from zero to billionths, false if it does not fit
+func FixedFromRat(value *big.Rat) (Fixed, bool) {
+	if value == nil {
+		return 0, true
+	}
+	num := (&big.Int{}).Mul(value.Num(), bigFixedScale)
+	num.Mul(num, big.NewInt(2))
+	num.Add(num, (&big.Int{}).Mul(value.Denom(), big.NewInt(int64(value.Sign()))))
+	denom := (&big.Int{}).Mul(value.Denom(), big.NewInt(2))
+	num.Quo(num, denom)
+	if !num.IsInt64() {
+		return 0, false
+	}
+	return Fixed(num.Int64()), true
+}
+
+// Rat returns the exact value of the fixed-point amount
+func (f Fixed) Rat() *big.Rat {
+	return big.NewRat(int64(f), fixedScale)
+}
+
+// abs returns the absolute value and the sign of the amount
+func (f Fixed) abs() (uint64, bool) {
+	if f < 0 {
+		return -uint64(f), true
+	}
+	return uint64(f), false
+}
+
+// muldiv computes x * y / z rounded half away from zero, the product may exceed 64 bits,
+// false if the result does not fit
+func muldiv(x, y Fixed, z uint64) (Fixed, bool) {
+	ax, nx := x.abs()
+	ay, ny := y.abs()
+	hi, lo := bits.Mul64(ax, ay)
+	// the quotient would not fit in 64 bits, Div64 panics then
+	if hi >= z {
+		return 0, false
+	}
+	q, r := bits.Div64(hi, lo, z)
+	if r >= z-r {
+		q++
+	}
+	if q > math.MaxInt64 {
+		return 0, false
+	}
+	if nx != ny {
+		return -Fixed(q), true
+	}
+	return Fixed(q), true
+}
+
+// Mul returns the product of the amounts, false on overflow
+func (f Fixed) Mul(y Fixed) (Fixed, bool) {
+	return muldiv(f, y, fixedScale)
+}
+
+// Div returns the quotient of the amounts, false on overflow, the divisor must not be zero
+func (f Fixed) Div(y Fixed) (Fixed, bool) {
+	ay, ny := y.abs()
+	q, ok := muldiv(f, fixedScale, ay)
+	if ny {
+		return -q, ok
+	}
+	return q, ok
+}
+
+// Add returns the sum of the amounts, false on overflow
+func (f Fixed) Add(y Fixed) (Fixed, bool) {
+	sum := f + y
+	// the sum of two amounts of the same sign has their sign unless it overflows
+	if (f >= 0) == (y >= 0) && (sum >= 0) != (f >= 0) {
+		return 0, false
+	}
+	return sum, true
+}
+
+// FixedEngine values states in fixed point. Prices and quantities are shared between cloned states,
+// so each of them is converted once.
+type FixedEngine struct {
+	converted map[*big.Rat]Fixed
+	rates     map[string]Fixed
+}
+
+func NewFixedEngine() *FixedEngine {
+	rates := make(map[string]Fixed, len(ExchangeRates))
+	for currency, rate := range ExchangeRates {
+		// a rate that does not fit is left out, the states with its currency are valued exactly
+		if fixed, ok := FixedFromRat(rate); ok {
+			rates[currency] = fixed
+		}
+	}
+	return &FixedEngine{converted: make(map[*big.Rat]Fixed), rates: rates}
+}
+
+// convert returns the cached fixed-point value, the values that do not fit are not cached
+func (e *FixedEngine) convert(value *big.Rat) (Fixed, bool) {
+	if value == nil {
+		return 0, true
+	}
+	if result, ok := e.converted[value]; ok {
+		return result, true
+	}
+	result, ok := FixedFromRat(value)
+	if ok {
+		e.converted[value] = result
+	}
+	return result, ok
+}
+
+// Aggregate returns the value of the state in USD without the excluded assets, like Aggregate after SellAll,
+// false if it overflows on the way, the state is then valued exactly by Cost
+func (e *FixedEngine) Aggregate(state *State, excluded map[string]bool) (Fixed, bool) {
+	var sum Fixed
+	for key, quantity := range state.Portfolio {
+		if excluded[key] || IsFutures(key) {
+			continue
+		}
+		value, ok := e.convert(quantity)
+		if !ok {
+			return 0, false
+		}
+		currency := key
+		if price, found := state.Prices[key]; found {
+			fixedPrice, priceOk := e.convert(price)
+			accrued, accruedOk := e.convert(state.Accrued[key])
+			if !priceOk || !accruedOk {
+				return 0, false
+			}
+			fixedPrice, ok = fixedPrice.Add(accrued)
+			if !ok {
+				return 0, false
+			}
+			value, ok = fixedPrice.Mul(value)
+			if !ok {
+				return 0, false
+			}
+			currency = state.Currencies[key]
+		}
+		// liabilities are only counted in the net value
+		if value < 0 && LiabilitiesPolicy == LiabilitiesGross {
+			continue
+		}
+		rate := e.rates[currency]
+		if rate == 0 {
+			// the rate does not fit
+			if _, known := ExchangeRates[currency]; known {
+				return 0, false
+			}
+			continue
+		}
+		usd, ok := value.Div(rate)
+		if !ok {
+			return 0, false
+		}
+		sum, ok = sum.Add(usd)
+		if !ok {
+			return 0, false
+		}
+	}
+	return sum, true
+}
@@ -0,0 +1,177 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+func rat(s string) *big.Rat {
+	r, ok := (&big.Rat{}).SetString(s)
+	if !ok {
+		panic("invalid rational " + s)
+	}
+	return r
+}
+
+// fixed converts the value that must fit
+func fixed(s string) Fixed {
+	f, ok := FixedFromRat(rat(s))
+	if !ok {
+		panic("fixed-point overflow " + s)
+	}
+	return f
+}
+
+func TestFixedFromRat(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Fixed
+	}{
+		{"0", 0},
+		{"1", 1_000_000_000},
+		{"-2.5", -2_500_000_000},
+		{"1/3", 333_333_333},
+		{"2/3", 666_666_667},
+		{"-2/3", -666_666_667},
+		{"0.0000000005", 1},
+		{"-0.0000000005", -1},
+		{"123456789.123456789", 123_456_789_123_456_789},
+	}
+	for _, test := range tests {
+		if got, ok := FixedFromRat(rat(test.value)); !ok || got != test.want {
+			t.Errorf("FixedFromRat(%s) = %d, %v, want %d", test.value, got, ok, test.want)
+		}
+	}
+	if got, ok := FixedFromRat(nil); !ok || got != 0 {
+		t.Errorf("FixedFromRat(nil) = %d, %v, want 0", got, ok)
+	}
+	for _, value := range []string{"9300000000", "-9300000000"} {
+		if _, ok := FixedFromRat(rat(value)); ok {
+			t.Errorf("FixedFromRat(%s) fits, want an overflow", value)
+		}
+	}
+}
+
+func TestFixedArithmetic(t *testing.T) {
+	tests := []struct {
+		x, y      string
+		mul, quot string
+	}{
+		{"2", "3", "6", "0.666666667"},
+		{"-2", "3", "-6", "-0.666666667"},
+		{"2", "-3", "-6", "-0.666666667"},
+		{"1500.25", "100", "150025", "15.0025"},
+		// the product exceeds 64 bits before scaling
+		{"9000000", "1000", "9000000000", "9000"},
+		{"0.000000001", "0.5", "0.000000001", "0.000000002"},
+	}
+	for _, test := range tests {
+		x, y := fixed(test.x), fixed(test.y)
+		if got, ok := x.Mul(y); !ok || got != fixed(test.mul) {
+			t.Errorf("%s * %s = %s, %v, want %s", test.x, test.y, got.Rat().FloatString(9), ok, test.mul)
+		}
+		if got, ok := x.Div(y); !ok || got != fixed(test.quot) {
+			t.Errorf("%s / %s = %s, %v, want %s", test.x, test.y, got.Rat().FloatString(9), ok, test.quot)
+		}
+	}
+}
+
+func TestFixedOverflow(t *testing.T) {
+	// the high word of the product reaches the divisor
+	if _, ok := fixed("5000000000").Mul(fixed("5000000000")); ok {
+		t.Error("5e9 * 5e9 fits, want an overflow")
+	}
+	// the quotient fits in 64 bits but not in int64
+	if _, ok := fixed("9000000000").Mul(fixed("1.5")); ok {
+		t.Error("9e9 * 1.5 fits, want an overflow")
+	}
+	if _, ok := fixed("9000000000").Div(fixed("0.5")); ok {
+		t.Error("9e9 / 0.5 fits, want an overflow")
+	}
+	if _, ok := fixed("9000000000").Add(fixed("9000000000")); ok {
+		t.Error("9e9 + 9e9 fits, want an overflow")
+	}
+	if got, ok := fixed("-9000000000").Add(fixed("9000000000")); !ok || got != 0 {
+		t.Errorf("-9e9 + 9e9 = %d, %v, want 0", got, ok)
+	}
+	// a large balance in a high-rate currency is left to the exact valuation
+	state := &State{Portfolio: map[string]*big.Rat{"usd": rat("1"), "uzs": rat("10000000000")}}
+	if _, ok := NewFixedEngine().Aggregate(state, nil); ok {
+		t.Error("aggregate of 1e10 UZS fits, want an overflow")
+	}
+}
+
+func TestFixedEngineAggregate(t *testing.T) {
+	kinds["futures"] = pb.InstrumentType_INSTRUMENT_TYPE_FUTURES
+	defer delete(kinds, "futures")
+	states := []*State{
+		{
+			Portfolio: map[string]*big.Rat{"usd": rat("1234.56")},
+		},
+		{
+			Portfolio: map[string]*big.Rat{
+				"rub":   rat("-15000.33"),
+				"eur":   rat("17.01"),
+				"share": rat("37"),
+				"bond":  rat("12"),
+			},
+			Prices: map[string]*big.Rat{
+				"share": rat("283.17"),
+				"bond":  BondPrice(rat("97.345"), rat("1000")),
+			},
+			Accrued:    map[string]*big.Rat{"bond": rat("12.87")},
+			Currencies: map[string]string{"share": "rub", "bond": "rub"},
+		},
+		{
+			Portfolio: map[string]*big.Rat{
+				"hkd":      rat("1/3"),
+				"share":    rat("1000000"),
+				"excluded": rat("500"),
+				"futures":  rat("3"),
+			},
+			Prices: map[string]*big.Rat{
+				"share":    rat("1.0001"),
+				"excluded": rat("99.99"),
+				"futures":  rat("123456"),
+			},
+			Currencies: map[string]string{"share": "cny", "excluded": "usd", "futures": "rub"},
+		},
+	}
+	excluded := map[string]bool{"excluded": true}
+	engine := NewFixedEngine()
+	for i, state := range states {
+		// the engine is reused like in the replay, so conversions come from its cache the second time
+		for range 2 {
+			_, _, want := Cost(state, excluded)
+			aggregate, ok := engine.Aggregate(state, excluded)
+			if !ok {
+				t.Fatalf("state %d: fixed aggregate overflows", i)
+			}
+			got := aggregate.Rat()
+			for _, policy := range []string{RoundHalfUp, RoundHalfEven, RoundUp} {
+				if Round(got, MoneyDecimals, policy).Cmp(Round(want, MoneyDecimals, policy)) != 0 {
+					t.Errorf("state %d: fixed aggregate %s, exact %s with %s rounding",
+						i, got.FloatString(9), want.FloatString(9), policy)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,191 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"math/big"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// Asset classes of the Interactive Brokers Flex queries
+var flexAssetClasses = map[pb.InstrumentType]string{
+	pb.InstrumentType_INSTRUMENT_TYPE_SHARE:    "STK",
+	pb.InstrumentType_INSTRUMENT_TYPE_ETF:      "STK",
+	pb.InstrumentType_INSTRUMENT_TYPE_BOND:     "BOND",
+	pb.InstrumentType_INSTRUMENT_TYPE_FUTURES:  "FUT",
+	pb.InstrumentType_INSTRUMENT_TYPE_OPTION:   "OPT",
+	pb.InstrumentType_INSTRUMENT_TYPE_CURRENCY: "CASH",
+}
+
+// Cash transaction types of the Interactive Brokers Flex queries, fees are added by flexCashType
+var flexCashTypes = map[pb.OperationType]string{
+	pb.OperationType_OPERATION_TYPE_DIVIDEND:     "Dividends",
+	pb.OperationType_OPERATION_TYPE_DIVIDEND_TAX: "Withholding Tax",
+	pb.OperationType_OPERATION_TYPE_COUPON:       "Bond Interest Received",
+	pb.OperationType_OPERATION_TYPE_BOND_TAX:     "Withholding Tax",
+	pb.OperationType_OPERATION_TYPE_INPUT:        "Deposits/Withdrawals",
+	pb.OperationType_OPERATION_TYPE_OUTPUT:       "Deposits/Withdrawals",
+}
+
+func flexCashType(kind pb.OperationType) (string, bool) {
+	if slices.Contains(FeeTypes, kind) {
+		return "Other Fees", true
+	}
+	name, ok := flexCashTypes[kind]
+	return name, ok
+}
+
+// FlexEntry is an operation in the schema of the Interactive Brokers Flex trades and cash transactions,
+// trades have BuySell and cash transactions have Type
+type FlexEntry struct {
+	Account     string
+	Currency    string
+	AssetClass  string
+	Symbol      string
+	Description string
+	Isin        string
+	Date        time.Time
+	Quantity    int64
+	Price       *big.Rat
+	Proceeds    *big.Rat
+	Commission  *big.Rat
+	BuySell     string
+	Type        string
+	Id          string
+}
+
+// NetCash returns the cash change of the entry with the commission
+func (e FlexEntry) NetCash() *big.Rat {
+	return AddRat(e.Proceeds, e.Commission)
+}
+
+// NewFlexEntry maps the operation to the Flex schema, it returns false for operations without a counterpart
+func NewFlexEntry(accountId string, operation *pb.OperationItem) (FlexEntry, bool) {
+	if operation.Payment == nil {
+		return FlexEntry{}, false
+	}
+	entry := FlexEntry{
+		Account:     accountId,
+		Currency:    strings.ToUpper(operation.Payment.Currency),
+		AssetClass:  flexAssetClasses[operation.InstrumentKind],
+		Symbol:      tickers[operation.AssetUid],
+		Description: cmp.Or(names[operation.AssetUid], operation.Name),
+		Isin:        isins[operation.AssetUid],
+		Date:        operation.Date.AsTime().In(Location),
+		Proceeds:    ToRat(operation.Payment),
+		Commission:  &big.Rat{},
+		Id:          operation.Id,
+	}
+	switch operation.Type {
+	case pb.OperationType_OPERATION_TYPE_BUY:
+		entry.BuySell = "BUY"
+		entry.Quantity = operation.Quantity
+	case pb.OperationType_OPERATION_TYPE_SELL:
+		entry.BuySell = "SELL"
+		entry.Quantity = -operation.Quantity
+	default:
+		kind, ok := flexCashType(operation.Type)
+		if !ok {
+			return FlexEntry{}, false
+		}
+		entry.Type = kind
+		return entry, true
+	}
+	if operation.Price != nil {
+		entry.Price = ToRat(operation.Price)
+	}
+	if operation.Commission != nil {
+		entry.Commission = (&big.Rat{}).Neg((&big.Rat{}).Abs(ToRat(operation.Commission)))
+	}
+	return entry, true
+}
+
+// Flex maps the operations of the tax year of the evaluated accounts to the Flex schema
+func Flex(evaluations []*Evaluation) []FlexEntry {
+	var entries []FlexEntry
+	for _, evaluation := range evaluations {
+		for _, operation := range evaluation.Operations {
+			if !InTaxYear(operation.Date.AsTime()) {
+				continue
+			}
+			if entry, ok := NewFlexEntry(evaluation.AccountId, operation); ok {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	slices.SortStableFunc(entries, func(a, b FlexEntry) int {
+		return cmp.Or(a.Date.Compare(b.Date), strings.Compare(a.Account, b.Account), strings.Compare(a.Id, b.Id))
+	})
+	return entries
+}
+
+// WriteFlex writes the entries as CSV with the column names of the Flex queries
+func WriteFlex(filename string, entries []FlexEntry) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := csv.NewWriter(file)
+	err = w.Write([]string{"ClientAccountID", "CurrencyPrimary", "AssetClass", "Symbol", "Description", "ISIN",
+		"DateTime", "TradeDate", "Quantity", "TradePrice", "Proceeds", "IBCommission", "NetCash", "Buy/Sell", "Type",
+		"TransactionID"})
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		price := ""
+		if entry.Price != nil {
+			price = entry.Price.FloatString(4)
+		}
+		err = w.Write([]string{
+			entry.Account,
+			entry.Currency,
+			entry.AssetClass,
+			entry.Symbol,
+			entry.Description,
+			entry.Isin,
+			entry.Date.Format("20060102;150405"),
+			entry.Date.Format("20060102"),
+			strconv.FormatInt(entry.Quantity, 10),
+			price,
+			entry.Proceeds.FloatString(2),
+			entry.Commission.FloatString(2),
+			entry.NetCash().FloatString(2),
+			entry.BuySell,
+			entry.Type,
+			entry.Id,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	err = w.Error()
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
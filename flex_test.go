@@ -0,0 +1,59 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+func TestNewFlexEntry(t *testing.T) {
+	date := timestamppb.New(time.Date(TaxYear, 3, 1, 12, 0, 0, 0, time.UTC))
+	sell, ok := NewFlexEntry("1", &pb.OperationItem{
+		Type:           pb.OperationType_OPERATION_TYPE_SELL,
+		InstrumentKind: pb.InstrumentType_INSTRUMENT_TYPE_ETF,
+		Date:           date,
+		Quantity:       3,
+		Price:          &pb.MoneyValue{Currency: "usd", Units: 10},
+		Payment:        &pb.MoneyValue{Currency: "usd", Units: 30},
+		Commission:     &pb.MoneyValue{Currency: "usd", Units: 1},
+	})
+	if !ok || sell.BuySell != "SELL" || sell.Quantity != -3 || sell.AssetClass != "STK" || sell.Currency != "USD" ||
+		sell.NetCash().Cmp(big.NewRat(29, 1)) != 0 {
+		t.Errorf("NewFlexEntry(sell) = %+v, %v", sell, ok)
+	}
+	fee, ok := NewFlexEntry("1", &pb.OperationItem{
+		Type:    pb.OperationType_OPERATION_TYPE_BROKER_FEE,
+		Date:    date,
+		Payment: &pb.MoneyValue{Currency: "rub", Units: -5},
+	})
+	if !ok || fee.Type != "Other Fees" || fee.BuySell != "" || fee.NetCash().Cmp(big.NewRat(-5, 1)) != 0 {
+		t.Errorf("NewFlexEntry(fee) = %+v, %v", fee, ok)
+	}
+	_, ok = NewFlexEntry("1", &pb.OperationItem{
+		Type:    pb.OperationType_OPERATION_TYPE_INPUT_SECURITIES,
+		Date:    date,
+		Payment: &pb.MoneyValue{Currency: "rub"},
+	})
+	if ok {
+		t.Error("NewFlexEntry(transfer) = true, want no counterpart")
+	}
+}
@@ -0,0 +1,139 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"strings"
+)
+
+// MoneyDecimals is the number of decimal places in formatted amounts
+var MoneyDecimals = 2
+
+var currencySymbols = map[string]string{
+	"amd": "֏",
+	"chf": "CHF ",
+	"cny": "CN¥",
+	"eur": "€",
+	"gbp": "£",
+	"hkd": "HK$",
+	"jpy": "¥",
+	"kzt": "₸",
+	"rub": "₽",
+	"try": "₺",
+	"usd": "$",
+}
+
+// FormatMoney formats the amount with thousands separators and the currency symbol, e.g. "$1,234.56",
+// it is scaled when redacting
+func FormatMoney(value *big.Rat, currency string) string {
+	sign, number := formatNumber(value)
+	if symbol, ok := currencySymbols[currency]; ok {
+		return sign + symbol + number
+	}
+	return sign + number + " " + strings.ToUpper(currency)
+}
+
+// FormatMoneyCode formats the amount with the currency code instead of the symbol, e.g. "1,234.56 USD",
+// for fonts without the currency symbols
+func FormatMoneyCode(value *big.Rat, currency string) string {
+	sign, number := formatNumber(value)
+	return sign + number + " " + strings.ToUpper(currency)
+}
+
+// formatNumber rounds the absolute amount and adds thousands separators
+func formatNumber(value *big.Rat) (string, string) {
+	if value == nil {
+		value = &big.Rat{}
+	}
+	value = ScaleAmount(value)
+	number := Round(value, MoneyDecimals, Rounding).FloatString(MoneyDecimals)
+	sign := ""
+	if strings.HasPrefix(number, "-") {
+		sign = "-"
+		number = number[1:]
+	}
+	integer, fraction, hasFraction := strings.Cut(number, ".")
+	var b strings.Builder
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	if hasFraction {
+		b.WriteByte('.')
+		b.WriteString(fraction)
+	}
+	return sign, b.String()
+}
+
+// FormatUSD formats the aggregate value
+func FormatUSD(value *big.Rat) string {
+	return FormatMoney(value, "usd")
+}
+
+// formatDecimal formats quantities and prices without trailing zeros
+func formatDecimal(value *big.Rat) string {
+	return strings.TrimSuffix(strings.TrimRight(value.FloatString(9), "0"), ".")
+}
+
+// FormatCost formats amounts in all currencies
+func FormatCost(cost map[string]*big.Rat) map[string]string {
+	result := make(map[string]string, len(cost))
+	for currency, value := range cost {
+		result[currency] = FormatMoney(value, currency)
+	}
+	return result
+}
+
+// Rounding policies applied to the reported values
+const (
+	// round half away from zero
+	RoundHalfUp = "half-up"
+	// round half to even, also known as banker's rounding
+	RoundHalfEven = "half-even"
+	// always round up, as FBAR requires for the maximum value
+	RoundUp = "up"
+)
+
+// Rounding is the rounding policy of formatted amounts
+var Rounding = RoundHalfUp
+
+// Round rounds the value to the given number of decimal places using the policy
+func Round(value *big.Rat, decimals int, policy string) *big.Rat {
+	scale := (&big.Int{}).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	scaled := (&big.Rat{}).Mul(value, (&big.Rat{}).SetInt(scale))
+	// the denominator is always positive, so this is the floor division
+	quotient, remainder := (&big.Int{}).DivMod(scaled.Num(), scaled.Denom(), &big.Int{})
+	if remainder.Sign() != 0 {
+		half := (&big.Int{}).Lsh(remainder, 1).Cmp(scaled.Denom())
+		roundUp := false
+		switch policy {
+		case RoundUp:
+			roundUp = true
+		case RoundHalfEven:
+			roundUp = half > 0 || half == 0 && quotient.Bit(0) == 1
+		default:
+			roundUp = half > 0 || half == 0 && scaled.Sign() > 0
+		}
+		if roundUp {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+	return (&big.Rat{}).SetFrac(quotient, scale)
+}
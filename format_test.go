@@ -0,0 +1,65 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestRound(t *testing.T) {
+	for _, test := range []struct {
+		value    string
+		decimals int
+		policy   string
+		want     string
+	}{
+		{"2.5", 0, RoundHalfUp, "3"},
+		{"-2.5", 0, RoundHalfUp, "-3"},
+		{"2.4", 0, RoundHalfUp, "2"},
+		{"-2.4", 0, RoundHalfUp, "-2"},
+		{"-2.6", 0, RoundHalfUp, "-3"},
+		{"1.005", 2, RoundHalfUp, "1.01"},
+		{"-1.005", 2, RoundHalfUp, "-1.01"},
+		{"2.5", 0, RoundHalfEven, "2"},
+		{"3.5", 0, RoundHalfEven, "4"},
+		{"-2.5", 0, RoundHalfEven, "-2"},
+		{"-3.5", 0, RoundHalfEven, "-4"},
+		{"2.51", 0, RoundHalfEven, "3"},
+		{"-2.51", 0, RoundHalfEven, "-3"},
+		{"1.025", 2, RoundHalfEven, "1.02"},
+		{"1.035", 2, RoundHalfEven, "1.04"},
+		{"2.1", 0, RoundUp, "3"},
+		{"2.5", 0, RoundUp, "3"},
+		{"-2.1", 0, RoundUp, "-2"},
+		{"-2.9", 0, RoundUp, "-2"},
+		{"1.001", 2, RoundUp, "1.01"},
+		// exact values are kept by every policy
+		{"2", 0, RoundUp, "2"},
+		{"-1.25", 2, RoundHalfEven, "-1.25"},
+		{"0", 2, RoundHalfUp, "0"},
+		// unknown policies round half up
+		{"-0.5", 0, "", "-1"},
+	} {
+		value, _ := (&big.Rat{}).SetString(test.value)
+		want, _ := (&big.Rat{}).SetString(test.want)
+		if got := Round(value, test.decimals, test.policy); got.Cmp(want) != 0 {
+			t.Errorf("Round(%s, %d, %q) = %s, want %s", test.value, test.decimals, test.policy,
+				got.RatString(), test.want)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"maps"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+var InvalidSnapshotQuantityError = errors.New("invalid snapshot quantity")
+
+// Snapshot is a known portfolio of an account, e.g. from the broker report
+type Snapshot struct {
+	// the portfolio is before all operations since this time, start of the tax year by default
+	Date time.Time `yaml:"Date"`
+	// ticker, asset UID or currency -> quantity
+	Portfolio map[string]string `yaml:"Portfolio"`
+}
+
+// LoadSnapshots reads snapshots by account ID
+func LoadSnapshots(filename string) (map[string]Snapshot, error) {
+	var snapshots map[string]Snapshot
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	err = yaml.Unmarshal(data, &snapshots)
+	return snapshots, err
+}
+
+// Resolve converts the snapshot portfolio to the same keys as in the state
+func (s Snapshot) Resolve() (map[string]*big.Rat, error) {
+	portfolio := make(map[string]*big.Rat, len(s.Portfolio))
+	for id, value := range s.Portfolio {
+		quantity, ok := parseRat(value)
+		if !ok {
+			return nil, InvalidSnapshotQuantityError
+		}
+		key := strings.ToLower(id)
+		if _, ok := ExchangeRates[key]; !ok {
+			key, ok = FindAsset(id)
+			if !ok {
+				return nil, UnknownAssetError
+			}
+		}
+		portfolio[key] = AddRat(portfolio[key], quantity)
+	}
+	return portfolio, nil
+}
+
+// OperationDelta returns the changes of the portfolio made by the operation.
+// Updates of operations only add and subtract, so the delta is the negated update of an empty portfolio.
+func OperationDelta(operation *pb.OperationItem) (map[string]*big.Rat, error) {
+	update, err := OperationToUpdate(operation)
+	if err != nil {
+		return nil, err
+	}
+	state := &State{Portfolio: make(map[string]*big.Rat)}
+	update(state)
+	for _, value := range state.Portfolio {
+		value.Neg(value)
+	}
+	return state.Portfolio, nil
+}
+
+// addDelta adds the changes to the portfolio, removing the emptied positions
+func addDelta(portfolio map[string]*big.Rat, delta map[string]*big.Rat) {
+	for key, value := range delta {
+		portfolio[key] = AddRat(portfolio[key], value)
+		if portfolio[key].Sign() == 0 {
+			delete(portfolio, key)
+		}
+	}
+}
+
+// ForwardReplay applies operations and corporate actions to the snapshot in chronological order
+// and returns the portfolio at the start of each month till now
+func ForwardReplay(snapshot Snapshot, operations []*pb.OperationItem, actions []CorporateAction,
+	now time.Time) (*MonthEnds, error) {
+	portfolio, err := snapshot.Resolve()
+	if err != nil {
+		return nil, err
+	}
+	start := snapshot.Date
+	if start.IsZero() {
+		start = time.Date(TaxYear, 1, 1, 0, 0, 0, 0, Location)
+	}
+
+	changes := make(map[time.Time][]func(map[string]*big.Rat))
+	for _, operation := range operations {
+		date := operation.Date.AsTime()
+		if date.Before(start) {
+			continue
+		}
+		delta, err := OperationDelta(operation)
+		if err != nil {
+			return nil, err
+		}
+		changes[date] = append(changes[date], func(portfolio map[string]*big.Rat) {
+			addDelta(portfolio, delta)
+		})
+	}
+	for _, action := range actions {
+		if action.Date.Before(start) {
+			continue
+		}
+		change, err := CorporateActionChange(action)
+		// skipped with a warning by the backward replay too
+		if errors.Is(err, UnknownAssetError) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		changes[action.Date] = append(changes[action.Date], change)
+	}
+
+	var months MonthEnds
+	month := 0
+	observe := func(until time.Time) {
+		for ; month < len(months); month++ {
+			boundary := time.Date(TaxYear, time.Month(month+1), 1, 0, 0, 0, 0, Location)
+			if boundary.After(until) {
+				return
+			}
+			if !boundary.Before(start) {
+				months[month] = &State{Portfolio: maps.Clone(portfolio)}
+			}
+		}
+	}
+	for _, date := range slices.SortedFunc(maps.Keys(changes), time.Time.Compare) {
+		observe(date)
+		for _, change := range changes[date] {
+			change(portfolio)
+		}
+	}
+	observe(now)
+	return &months, nil
+}
+
+// CompareForward compares the forward replayed portfolio with the backward reconstruction
+// at the start of each month, it returns the number of discrepancies found
+func CompareForward(logger *zap.Logger, forward, backward *MonthEnds) int {
+	mismatches := 0
+	for month := range forward {
+		if forward[month] == nil || backward[month] == nil {
+			continue
+		}
+		forwardPortfolio := ToTickers(forward[month].Portfolio)
+		backwardPortfolio := ToTickers(backward[month].Portfolio)
+		for _, ticker := range diffRats(forwardPortfolio, backwardPortfolio) {
+			mismatches++
+			logger.Warn("forward replay differs from backward reconstruction",
+				zap.Int("month", month+1),
+				zap.String("ticker", ticker),
+				zap.Stringer("forward", AddRat(forwardPortfolio[ticker], nil)),
+				zap.Stringer("backward", AddRat(backwardPortfolio[ticker], nil)))
+		}
+	}
+	return mismatches
+}
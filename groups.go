@@ -0,0 +1,121 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/zap"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// GroupSummary is the combined maximum of a reporting unit, e.g. an account with its invest boxes
+type GroupSummary struct {
+	Name       string    `json:"name"`
+	AccountIds []string  `json:"account_ids"`
+	BestTime   time.Time `json:"best_time"`
+	Best       Amount    `json:"best"`
+}
+
+// AllAccountIds returns the accounts of the token which existed during the tax year and can be read
+func AllAccountIds(logger *zap.Logger, accounts []*pb.Account) []string {
+	var accountIds []string
+	for _, account := range accounts {
+		info := NewAccountInfo(account)
+		if account.AccessLevel == pb.AccessLevel_ACCOUNT_ACCESS_LEVEL_NO_ACCESS ||
+			!info.End().After(time.Date(TaxYear, 1, 1, 0, 0, 0, 0, Location)) ||
+			!info.Start().Before(time.Date(TaxYear+1, 1, 1, 0, 0, 0, 0, Location)) {
+			continue
+		}
+		logger.Info("found account",
+			zap.String("id", account.Id),
+			zap.String("name", account.Name),
+			zap.String("type", info.Type))
+		accountIds = append(accountIds, account.Id)
+	}
+	return accountIds
+}
+
+// WithGroupAccounts adds the accounts of the groups missing from the list
+func WithGroupAccounts(accountIds []string, groups map[string][]string) []string {
+	for _, name := range slices.Sorted(maps.Keys(groups)) {
+		for _, accountId := range groups[name] {
+			if !slices.Contains(accountIds, accountId) {
+				accountIds = append(accountIds, accountId)
+			}
+		}
+	}
+	return accountIds
+}
+
+// CombineGroups finds the combined maximum of the evaluated accounts of each group
+func CombineGroups(logger *zap.Logger, groups map[string][]string, evaluations []*Evaluation) []GroupSummary {
+	var summaries []GroupSummary
+	for _, name := range slices.Sorted(maps.Keys(groups)) {
+		var members []*Evaluation
+		var accountIds []string
+		for _, evaluation := range evaluations {
+			if slices.Contains(groups[name], evaluation.AccountId) {
+				members = append(members, evaluation)
+				accountIds = append(accountIds, evaluation.AccountId)
+			}
+		}
+		if len(members) == 0 {
+			logger.Warn("no evaluated accounts in group", zap.String("group", name))
+			continue
+		}
+		best := Combine(members).Best()
+		logger.Info("best group value",
+			zap.String("group", name),
+			zap.Strings("accounts", accountIds),
+			zap.Time("time", best.Time),
+			zap.String("aggregate", FormatUSD(best.Aggregate)))
+		summaries = append(summaries, GroupSummary{
+			Name:       name,
+			AccountIds: accountIds,
+			BestTime:   best.Time,
+			Best:       NewAmount(best.Aggregate, "usd"),
+		})
+	}
+	return summaries
+}
+
+// PrintGroups prints the combined maximum of each group with the maxima of its accounts
+func PrintGroups(w io.Writer, groups []GroupSummary, evaluations []*Evaluation) error {
+	if len(groups) == 0 {
+		return nil
+	}
+	tw := NewTable(w, tabwriter.AlignRight)
+	fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\n", T("GROUP"), T("ACCOUNT"), T("MAXIMUM"), T("TIME"))
+	for _, group := range groups {
+		for _, evaluation := range evaluations {
+			if slices.Contains(group.AccountIds, evaluation.AccountId) {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\n", group.Name, Redact("account", evaluation.AccountId),
+					FormatUSD(evaluation.BestAggregate), evaluation.BestTime.Format(time.DateTime))
+			}
+		}
+		tw.Total()
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\n", group.Name, T("combined"),
+			FormatUSD(exact(group.Best)), group.BestTime.In(Location).Format(time.DateTime))
+	}
+	return tw.Flush()
+}
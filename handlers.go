@@ -0,0 +1,186 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"math/big"
+	"slices"
+
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+var UnsupportedOperationError = errors.New("unsupported operation type")
+
+// OperationHandler returns the update reverting the operation, as the portfolio is reconstructed back in time
+type OperationHandler func(operation *pb.OperationItem) Update
+
+// StrategyOperationTypes are the management and result fees of auto-following strategies
+var StrategyOperationTypes = []pb.OperationType{
+	pb.OperationType_OPERATION_TYPE_TRACK_MFEE,
+	pb.OperationType_OPERATION_TYPE_TRACK_PFEE,
+}
+
+// operationHandlers are the handlers of the supported operation types
+var operationHandlers = make(map[pb.OperationType]OperationHandler)
+
+// RegisterOperationHandler sets the handler of the operation types, replacing the built-in one if there is any.
+// The package is the program itself, so handlers are registered by dropping a file into this build
+// whose init function calls it; they are not safe to change during the run.
+func RegisterOperationHandler(handler OperationHandler, operationTypes ...pb.OperationType) {
+	for _, operationType := range operationTypes {
+		operationHandlers[operationType] = handler
+	}
+}
+
+// revertPayment takes the payment back from the cash balance, a missing payment changes nothing
+func revertPayment(state *State, payment *pb.MoneyValue) {
+	if payment == nil {
+		return
+	}
+	state.Portfolio[payment.Currency] = SubRat(state.Portfolio[payment.Currency], ToRat(payment))
+	if state.Portfolio[payment.Currency].Cmp(&big.Rat{}) == 0 {
+		delete(state.Portfolio, payment.Currency)
+	}
+}
+
+// revertQuantity changes the quantity of the asset by the given amount, removing the emptied position
+func revertQuantity(state *State, assetUid string, quantity int64) {
+	state.Portfolio[assetUid] = AddRat(state.Portfolio[assetUid], big.NewRat(quantity, 1))
+	if state.Portfolio[assetUid].Cmp(&big.Rat{}) == 0 {
+		delete(state.Portfolio, assetUid)
+	}
+}
+
+func handleBuy(operation *pb.OperationItem) Update {
+	return func(state *State) {
+		revertQuantity(state, operation.AssetUid, -operation.Quantity)
+		revertPayment(state, operation.Payment)
+	}
+}
+
+func handleSell(operation *pb.OperationItem) Update {
+	return func(state *State) {
+		revertQuantity(state, operation.AssetUid, operation.Quantity)
+		revertPayment(state, operation.Payment)
+	}
+}
+
+func handleCash(operation *pb.OperationItem) Update {
+	return func(state *State) {
+		revertPayment(state, operation.Payment)
+	}
+}
+
+func handleInputSecurities(operation *pb.OperationItem) Update {
+	return func(state *State) {
+		revertQuantity(state, operation.AssetUid, -operation.Quantity)
+		// there is a payment, but it looks like it is for information purposes only
+	}
+}
+
+// OvernightOperationTypes place the cash overnight (repo) and return it, by the sign of the payment
+var OvernightOperationTypes = []pb.OperationType{
+	pb.OperationType_OPERATION_TYPE_OVER_PLACEMENT,
+	pb.OperationType_OPERATION_TYPE_OVERNIGHT,
+}
+
+// OvernightKey is the portfolio key of the cash placed overnight in the currency
+func OvernightKey(currency string) string {
+	return "overnight:" + currency
+}
+
+// handleOvernight moves the placed cash to the placement position and back when it returns
+func handleOvernight(operation *pb.OperationItem) Update {
+	return func(state *State) {
+		if operation.Payment == nil {
+			return
+		}
+		revertPayment(state, operation.Payment)
+		key := OvernightKey(operation.Payment.Currency)
+		state.Portfolio[key] = AddRat(state.Portfolio[key], ToRat(operation.Payment))
+		if state.Portfolio[key].Sign() == 0 {
+			delete(state.Portfolio, key)
+		}
+	}
+}
+
+// OvernightReceivables adds the cash placed overnight and not returned yet to the current state, the positions
+// of the placements are receivables of the account valued at par until the cash returns.
+// Returns of placements made before the operations are not counted as placed now.
+func OvernightReceivables(operations []*pb.OperationItem, state *State) {
+	placed := make(map[string]*big.Rat)
+	sorted := slices.SortedStableFunc(slices.Values(operations), func(x, y *pb.OperationItem) int {
+		return x.Date.AsTime().Compare(y.Date.AsTime())
+	})
+	for _, operation := range sorted {
+		if !slices.Contains(OvernightOperationTypes, operation.Type) || operation.Payment == nil {
+			continue
+		}
+		currency := operation.Payment.Currency
+		placed[currency] = SubRat(placed[currency], ToRat(operation.Payment))
+		if placed[currency].Sign() < 0 {
+			placed[currency] = &big.Rat{}
+		}
+	}
+	for currency, amount := range placed {
+		key := OvernightKey(currency)
+		state.Prices[key] = big.NewRat(1, 1)
+		state.Currencies[key] = currency
+		if amount.Sign() > 0 {
+			state.Portfolio[key] = AddRat(state.Portfolio[key], amount)
+		}
+	}
+}
+
+func init() {
+	RegisterOperationHandler(handleBuy, pb.OperationType_OPERATION_TYPE_BUY)
+	RegisterOperationHandler(handleSell, pb.OperationType_OPERATION_TYPE_SELL)
+	RegisterOperationHandler(handleCash,
+		pb.OperationType_OPERATION_TYPE_BROKER_FEE,
+		pb.OperationType_OPERATION_TYPE_DIVIDEND,
+		pb.OperationType_OPERATION_TYPE_DIVIDEND_TAX,
+		pb.OperationType_OPERATION_TYPE_INPUT,
+		pb.OperationType_OPERATION_TYPE_OUTPUT,
+		pb.OperationType_OPERATION_TYPE_TAX,
+		pb.OperationType_OPERATION_TYPE_TAX_CORRECTION,
+		pb.OperationType_OPERATION_TYPE_ACCRUING_VARMARGIN,
+		pb.OperationType_OPERATION_TYPE_WRITING_OFF_VARMARGIN,
+		pb.OperationType_OPERATION_TYPE_MARGIN_FEE,
+		// income, fees and taxes of overnight placements (repo)
+		pb.OperationType_OPERATION_TYPE_OVER_INCOME,
+		pb.OperationType_OPERATION_TYPE_OVER_COM,
+		pb.OperationType_OPERATION_TYPE_TAX_REPO,
+		pb.OperationType_OPERATION_TYPE_TAX_REPO_HOLD,
+		pb.OperationType_OPERATION_TYPE_TAX_REPO_REFUND,
+		pb.OperationType_OPERATION_TYPE_TAX_REPO_PROGRESSIVE,
+		pb.OperationType_OPERATION_TYPE_TAX_REPO_HOLD_PROGRESSIVE,
+		pb.OperationType_OPERATION_TYPE_TAX_REPO_REFUND_PROGRESSIVE)
+	RegisterOperationHandler(handleInputSecurities, pb.OperationType_OPERATION_TYPE_INPUT_SECURITIES)
+	RegisterOperationHandler(handleOvernight, OvernightOperationTypes...)
+	// trades of auto-following strategies are usual ones, only their fees are specific
+	RegisterOperationHandler(handleCash, StrategyOperationTypes...)
+}
+
+// OperationToUpdate returns the update of the registered handler of the operation type
+func OperationToUpdate(operation *pb.OperationItem) (Update, error) {
+	handler, ok := operationHandlers[operation.Type]
+	if !ok {
+		return nil, UnsupportedOperationError
+	}
+	return handler(operation), nil
+}
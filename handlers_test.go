@@ -0,0 +1,87 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"maps"
+	"math/big"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+func TestRegisterOperationHandler(t *testing.T) {
+	operation := &pb.OperationItem{
+		Type:    pb.OperationType_OPERATION_TYPE_SERVICE_FEE,
+		Payment: &pb.MoneyValue{Currency: "rub", Units: -99},
+	}
+	if _, err := OperationToUpdate(operation); !errors.Is(err, UnsupportedOperationError) {
+		t.Fatalf("OperationToUpdate() error = %v, want %v", err, UnsupportedOperationError)
+	}
+	RegisterOperationHandler(handleCash, operation.Type)
+	defer delete(operationHandlers, operation.Type)
+	update, err := OperationToUpdate(operation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := &State{Portfolio: map[string]*big.Rat{"rub": big.NewRat(1, 1)}}
+	update(state)
+	if state.Portfolio["rub"].Cmp(big.NewRat(100, 1)) != 0 {
+		t.Errorf("rub = %v, want 100", state.Portfolio["rub"])
+	}
+}
+
+func TestOvernightPlacement(t *testing.T) {
+	placement := func(day int, units int64) *pb.OperationItem {
+		return &pb.OperationItem{
+			Type:    pb.OperationType_OPERATION_TYPE_OVER_PLACEMENT,
+			Date:    timestamppb.New(time.Date(TaxYear, 1, day, 19, 0, 0, 0, Location)),
+			Payment: &pb.MoneyValue{Currency: "rub", Units: units},
+		}
+	}
+	// the last placement is not returned yet, the current cash does not include it
+	operations := []*pb.OperationItem{placement(20, -500), placement(11, 1000), placement(10, -1000)}
+	state := &State{
+		Portfolio:  map[string]*big.Rat{"rub": big.NewRat(1200, 1)},
+		Prices:     make(map[string]*big.Rat),
+		Accrued:    make(map[string]*big.Rat),
+		Currencies: make(map[string]string),
+	}
+	OvernightReceivables(operations, state)
+	key := OvernightKey("rub")
+	if state.Portfolio[key].Cmp(big.NewRat(500, 1)) != 0 {
+		t.Fatalf("placed now = %v, want 500", state.Portfolio[key])
+	}
+	for _, operation := range operations {
+		update, err := OperationToUpdate(operation)
+		if err != nil {
+			t.Fatal(err)
+		}
+		update(state)
+		cost := maps.Clone(state.Portfolio)
+		SellAll(cost, state)
+		if len(cost) != 1 || cost["rub"].Cmp(big.NewRat(1700, 1)) != 0 {
+			t.Errorf("value before %v = %v, want 1700 rub", operation.Date.AsTime(), cost)
+		}
+	}
+	if _, ok := state.Portfolio[key]; ok {
+		t.Errorf("placed before the first placement = %v, want none", state.Portfolio[key])
+	}
+}
@@ -0,0 +1,186 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"math/big"
+	"os"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Only the latest runs are kept in the history
+const maxHistory = 100
+
+// AccountRecord is the summary of a single account evaluation in the run history
+type AccountRecord struct {
+	AccountId     string    `json:"account_id"`
+	BestTime      time.Time `json:"best_time"`
+	Best          string    `json:"best"`
+	PortfolioHash string    `json:"portfolio_hash"`
+	Operations    []string  `json:"operations"`
+}
+
+// RunRecord is the summary of a run stored to explain changes in the next runs
+type RunRecord struct {
+	Time      time.Time       `json:"time"`
+	RatesHash string          `json:"rates_hash"`
+	Accounts  []AccountRecord `json:"accounts"`
+	// instrumentUid -> hash of candles complete at the run time
+	Candles map[string]string `json:"candles"`
+}
+
+func hashRats(values map[string]*big.Rat) string {
+	h := sha256.New()
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		fmt.Fprintf(h, "%s=%s\n", key, values[key].RatString())
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// hashCandles hashes the candles complete before the given time
+func hashCandles(instrumentUid string, before time.Time) string {
+	h := sha256.New()
+	for _, candle := range candleCache[instrumentUid] {
+		date := candle.Time.AsTime()
+		if date.Add(time.Hour).After(before) {
+			continue
+		}
+		fmt.Fprintf(h, "%s=%s\n", date.Format(time.RFC3339), ToRat(candle.High).RatString())
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func NewRunRecord(now time.Time, evaluations []*Evaluation) RunRecord {
+	record := RunRecord{
+		Time:      now,
+		RatesHash: hashRats(ExchangeRates),
+		Candles:   make(map[string]string, len(candleCache)),
+	}
+	for _, evaluation := range evaluations {
+		account := AccountRecord{
+			AccountId:     evaluation.AccountId,
+			BestTime:      evaluation.BestTime,
+			Best:          evaluation.BestAggregate.RatString(),
+			PortfolioHash: hashRats(evaluation.BestState.Portfolio),
+		}
+		for _, operation := range evaluation.Operations {
+			account.Operations = append(account.Operations, operation.Id)
+		}
+		record.Accounts = append(record.Accounts, account)
+	}
+	for instrumentUid := range candleCache {
+		record.Candles[instrumentUid] = hashCandles(instrumentUid, now)
+	}
+	return record
+}
+
+// HistoryFile is the run history of the profile
+func HistoryFile(options Options) string {
+	switch {
+	case options.HistoryFile != "":
+		return options.HistoryFile
+	case *profile != "":
+		return "runs-" + *profile + ".json"
+	default:
+		return "runs.json"
+	}
+}
+
+func LoadHistory(filename string) ([]RunRecord, error) {
+	var history []RunRecord
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &history)
+	return history, err
+}
+
+func SaveHistory(filename string, history []RunRecord) error {
+	if len(history) > maxHistory {
+		history = history[len(history)-maxHistory:]
+	}
+	return WriteJSON(filename, history)
+}
+
+// ExplainChanges logs what has changed since the previous run
+func ExplainChanges(logger *zap.Logger, previous, current RunRecord) {
+	logger.Info("comparing with previous run", zap.Time("previous_run", previous.Time))
+	if previous.RatesHash != current.RatesHash {
+		logger.Info("exchange rates have changed")
+	}
+	for instrumentUid, hash := range previous.Candles {
+		if _, ok := candleCache[instrumentUid]; !ok {
+			continue
+		}
+		if hashCandles(instrumentUid, previous.Time) != hash {
+			logger.Info("candles have been revised",
+				zap.String("instrument", instrumentUid),
+				zap.String("ticker", tickers[assets[instrumentUid]]))
+		}
+	}
+	for _, account := range current.Accounts {
+		index := slices.IndexFunc(previous.Accounts, func(a AccountRecord) bool {
+			return a.AccountId == account.AccountId
+		})
+		if index < 0 {
+			logger.Info("new account", zap.String("account", account.AccountId))
+			continue
+		}
+		before := previous.Accounts[index]
+		known := make(map[string]bool, len(before.Operations))
+		for _, id := range before.Operations {
+			known[id] = true
+		}
+		newOperations := 0
+		for _, id := range account.Operations {
+			if !known[id] {
+				newOperations++
+			}
+			delete(known, id)
+		}
+		logger.Info("account changes",
+			zap.String("account", account.AccountId),
+			zap.Int("new_operations", newOperations),
+			zap.Int("removed_operations", len(known)),
+			zap.Bool("best_portfolio_changed", before.PortfolioHash != account.PortfolioHash))
+		previousBest, _ := (&big.Rat{}).SetString(before.Best)
+		best, _ := (&big.Rat{}).SetString(account.Best)
+		if previousBest != nil && best != nil && (previousBest.Cmp(best) != 0 || !before.BestTime.Equal(account.BestTime)) {
+			logger.Info("maximum has changed",
+				zap.String("account", account.AccountId),
+				zap.String("previous", FormatUSD(previousBest)),
+				zap.Time("previous_time", before.BestTime),
+				zap.String("current", FormatUSD(best)),
+				zap.Time("current_time", account.BestTime),
+				zap.String("difference", FormatUSD(SubRat(best, previousBest))))
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// holding is a position of the portfolio valued in USD
+type holding struct {
+	// assetUid or currency
+	key      string
+	name     string
+	title    string
+	isin     string
+	quantity *big.Rat
+	price    *big.Rat
+	currency string
+	// nil for assets without a price, e.g. futures
+	usd *big.Rat
+}
+
+// holdings values the positions of the portfolio at the state prices, largest first
+func holdings(state *State, portfolio map[string]*big.Rat) ([]holding, *big.Rat) {
+	holdings := make([]holding, 0, len(portfolio))
+	total := &big.Rat{}
+	for key, quantity := range portfolio {
+		row := holding{key: key, name: key, quantity: quantity}
+		if ticker, ok := tickers[key]; ok {
+			row.name = ticker
+			row.title, row.isin = names[key], isins[key]
+		}
+		if _, ok := ExchangeRates[key]; ok {
+			row.currency = key
+			row.usd = Aggregate(map[string]*big.Rat{key: quantity})
+		} else if price, ok := state.Prices[key]; ok && !IsFutures(key) {
+			row.price = AddRat(price, state.Accrued[key])
+			row.currency = state.Currencies[key]
+			row.usd = Aggregate(map[string]*big.Rat{row.currency: (&big.Rat{}).Mul(row.price, quantity)})
+		}
+		if row.usd != nil {
+			total = AddRat(total, row.usd)
+		}
+		holdings = append(holdings, row)
+	}
+	slices.SortFunc(holdings, func(x, y holding) int {
+		switch {
+		case x.usd == nil && y.usd != nil:
+			return 1
+		case x.usd != nil && y.usd == nil:
+			return -1
+		case x.usd != nil && y.usd != nil && x.usd.Cmp(y.usd) != 0:
+			return y.usd.Cmp(x.usd)
+		}
+		return strings.Compare(x.name, y.name)
+	})
+	return holdings, total
+}
+
+// PrintHoldings prints the positions of the portfolio with their prices and USD values, largest first
+func PrintHoldings(w io.Writer, state *State, portfolio map[string]*big.Rat) error {
+	holdings, total := holdings(state, portfolio)
+	tw := NewTable(w, tabwriter.AlignRight)
+	fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\tUSD\t\n",
+		T("TICKER"), T("NAME"), T("ISIN"), T("QUANTITY"), T("PRICE"), T("CURRENCY"))
+	for _, row := range holdings {
+		price, usd := "", "-"
+		if row.price != nil {
+			price = formatDecimal(row.price)
+		}
+		if row.usd != nil {
+			usd = FormatUSD(row.usd)
+		}
+		title, isin := InstrumentName(row.key)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n", redactKey(row.name), title, isin,
+			formatDecimal(ScaleAmount(row.quantity)), price, row.currency, usd)
+	}
+	tw.Total()
+	fmt.Fprintf(tw, "%s\t\t\t\t\t\t%s\t\n", T("total"), FormatUSD(total))
+	return tw.Flush()
+}
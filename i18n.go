@@ -0,0 +1,196 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import "errors"
+
+// Languages of the printed reports
+const (
+	LanguageEnglish = "en"
+	LanguageRussian = "ru"
+)
+
+var UnknownLanguageError = errors.New("unknown language")
+
+// translations are keyed by the English text, missing ones are printed in English
+var translations = map[string]map[string]string{
+	LanguageRussian: {
+		// report headers
+		"Account %s %q, %s":                             "Счёт %s %q, %s",
+		" type %s":                                      " тип %s",
+		", opened %s":                                   ", открыт %s",
+		", closed %s":                                   ", закрыт %s",
+		", auto-following strategy":                     ", автоследование",
+		"Account %s maximum value %s at %s\n":           "Счёт %s: максимальная стоимость %s на %s\n",
+		"Account %s holdings at peak %s\n":              "Счёт %s: позиции на пике %s\n",
+		"Account %s price age at peak %s\n":             "Счёт %s: возраст цен на пике %s\n",
+		"Account %s excluded assets at peak %s\n":       "Счёт %s: исключённые активы на пике %s\n",
+		"Account %s currency exposure at peak %s\n":     "Счёт %s: валютная структура на пике %s\n",
+		"Account %s currency exposure at year end %s\n": "Счёт %s: валютная структура на конец года %s\n",
+		"Account %s yearly totals\n":                    "Счёт %s: итоги за год\n",
+		"Account %s asset classes %s\n":                 "Счёт %s: классы активов %s\n",
+		"Account %s countries %s\n":                     "Счёт %s: страны %s\n",
+		"Account %s sectors %s\n":                       "Счёт %s: секторы %s\n",
+		"Account %s\n":                                  "Счёт %s\n",
+		"at peak %s":                                    "на пике %s",
+		"now":                                           "сейчас",
+		"closed":                                        "закрыт",
+		"Maximum account value":                         "Максимальная стоимость счёта",
+		"Combined maximum":                              "Общий максимум",
+		"Group":                                         "Группа",
+		"Account":                                       "Счёт",
+		"Maximum value":                                 "Максимальная стоимость",
+		"Maximum time":                                  "Время максимума",
+		"Current value":                                 "Текущая стоимость",
+		"Excluded assets at maximum":                    "Исключённые активы на максимуме",
+		"Generated":                                     "Сформирован",
+		"Rate per USD":                                  "Курс за USD",
+		"Provenance":                                    "Происхождение данных",
+		"Configuration is valid, the token has access to the selected accounts": "Конфигурация верна, у токена есть доступ к выбранным счетам",
+		// securities and cash
+		"Account %s maximum securities value %s at %s\n": "Счёт %s: максимальная стоимость ценных бумаг %s на %s\n",
+		"Account %s maximum cash value %s at %s\n":       "Счёт %s: максимальный остаток денежных средств %s на %s\n",
+		// persistence of the peak
+		"Account %s value stayed above %s of the maximum for %s around the peak, %s in total\n": "Счёт %s: стоимость была выше %s максимума %s подряд вокруг пика, всего %s\n",
+		// conservative rules
+		"Account %s conservative maximum value %s at %s by close prices, carryforward up to %s and liabilities netted\n": "Счёт %s: консервативная максимальная стоимость %s на %s по ценам закрытия, с переносом цен до %s и за вычетом обязательств\n",
+		// maxima by period
+		"Account %s daily maximum values\n":  "Счёт %s: максимальная стоимость по дням\n",
+		"Account %s weekly maximum values\n": "Счёт %s: максимальная стоимость по неделям\n",
+		// trading calendar
+		"Account %s maximum is outside the trading sessions of %s\n": "Счёт %s: максимум вне торговых сессий %s\n",
+		// attribution
+		"Account %s yearly result by asset\n": "Счёт %s: результат года по активам\n",
+		// dividend withholding
+		"Account %s foreign dividend withholding\n": "Счёт %s: удержание налога с иностранных дивидендов\n",
+		// thresholds
+		"Account %s first threshold crossings\n": "Счёт %s: первые превышения порогов\n",
+		"Combined first threshold crossings\n":   "Все счета вместе: первые превышения порогов\n",
+		// liabilities
+		"Account %s liabilities at peak %s are included in the maximum\n":     "Счёт %s: обязательства на пике %s учтены в максимуме\n",
+		"Account %s liabilities at peak %s are not included in the maximum\n": "Счёт %s: обязательства на пике %s не учтены в максимуме\n",
+		// table headers and labels
+		"CLASS":                     "КЛАСС",
+		"COUNTRY":                   "СТРАНА",
+		"SECTOR":                    "СЕКТОР",
+		"CURRENCY":                  "ВАЛЮТА",
+		"TICKER":                    "ТИКЕР",
+		"NAME":                      "НАЗВАНИЕ",
+		"QUANTITY":                  "КОЛИЧЕСТВО",
+		"PRICE":                     "ЦЕНА",
+		"AMOUNT":                    "СУММА",
+		"SHARE":                     "ДОЛЯ",
+		"TOTAL":                     "ИТОГ",
+		"TYPE":                      "ТИП",
+		"SUPPORTED":                 "ПОДДЕРЖАН",
+		"STATE":                     "СОСТОЯНИЕ",
+		"COUNT":                     "КОЛИЧЕСТВО",
+		"TOTALS":                    "СУММЫ",
+		"total":                     "итого",
+		"ACCOUNT":                   "СЧЁТ",
+		"GROUP":                     "ГРУППА",
+		"MAXIMUM":                   "МАКСИМУМ",
+		"TIME":                      "ВРЕМЯ",
+		"THRESHOLD":                 "ПОРОГ",
+		"PERIOD":                    "ПЕРИОД",
+		"RATE":                      "СТАВКА",
+		"START":                     "НАЧАЛО",
+		"END":                       "КОНЕЦ",
+		"REALIZED":                  "РЕАЛИЗОВАННЫЙ",
+		"UNREALIZED":                "НЕРЕАЛИЗОВАННЫЙ",
+		"INCOME":                    "ДОХОД",
+		"FEES":                      "КОМИССИИ",
+		"PAYMENTS":                  "ВЫПЛАТЫ",
+		"GROSS":                     "ДО НАЛОГА",
+		"WITHHELD":                  "УДЕРЖАНО",
+		"ABOVE TREATY":              "СВЕРХ СОГЛАШЕНИЯ",
+		"LOCAL TAX":                 "НАЛОГ К ДОПЛАТЕ",
+		"CANDLE":                    "СВЕЧА",
+		"AGE":                       "ВОЗРАСТ",
+		"next candle":               "следующая свеча",
+		"ACCRUED":                   "НКД",
+		"PER USD":                   "ЗА USD",
+		"SOURCE":                    "ИСТОЧНИК",
+		"aggregate":                 "совокупно",
+		"liabilities":               "обязательства",
+		"currency":                  "валюта",
+		"high":                      "максимум",
+		"close":                     "закрытие",
+		"no price":                  "нет цены",
+		"price of the portfolio":    "цена из портфеля",
+		"futures are not counted":   "фьючерсы не учитываются",
+		"excluded from the maximum": "исключён из максимума",
+		"hours stale":               "устарела на часы",
+		"days stale":                "устарела на дни",
+		"non-trading":               "неторговый",
+		"FIRST CROSSED":             "ВПЕРВЫЕ ПРЕВЫШЕН",
+		"never":                     "никогда",
+		"combined":                  "вместе",
+		"OPERATIONS":                "ОПЕРАЦИИ",
+		"INSTRUMENTS":               "ИНСТРУМЕНТЫ",
+		"REQUESTS":                  "ЗАПРОСЫ",
+		"DURATION":                  "ВРЕМЯ",
+		"%d min":                    "%d мин",
+		"yes":                       "да",
+		"NO":                        "НЕТ",
+		"deposits":                  "пополнения",
+		"iis contributions":         "взносы на ИИС",
+		"withdrawals":               "выводы",
+		"fees":                      "комиссии",
+		"taxes":                     "налоги",
+		// error hints
+		"token is invalid or expired":        "токен неверный или истёк",
+		"token lacks %s scope":               "у токена нет доступа к %s",
+		"error checking %s access":           "ошибка проверки доступа к %s",
+		"token has no access to the account": "у токена нет доступа к счёту",
+		"set one of these accounts as AccountId or several as AccountIds in config.yaml": "укажите один из этих счетов в AccountId или несколько в AccountIds в config.yaml",
+		"proxy must be an HTTP CONNECT or SOCKS5 proxy URL, e.g. socks5://host:1080":     "прокси должен быть URL HTTP CONNECT или SOCKS5 прокси, например socks5://host:1080",
+		// single asset trace
+		"Account %s asset %s\n":      "Счёт %s, актив %s\n",
+		"%d candles from %s to %s\n": "%d свечей с %s по %s\n",
+		"At the maximum on %s the asset was worth %s, %s of the account\n": "В максимуме %s актив стоил %s, %s счёта\n",
+		// read-only tokens
+		"token can trade on the account, a read-only token is enough": "токен может торговать на счёте, достаточно токена только для чтения",
+		// explanation
+		"Account %s at %s\n":                                "Счёт %s на %s\n",
+		"State at %s of the point at %s\n":                  "Состояние на %s по точке %s\n",
+		"No point of the tax year at or before %s\n":        "Нет точки налогового года на %s или раньше\n",
+		"%s of the candle at %s of %s":                      "%s свечи %s инструмента %s",
+		"%s of the candle at %s of %s carried over the gap": "%s свечи %s инструмента %s, перенесён через разрыв",
+		"lower %s of the candles at %s and %s of %s":        "%s, меньший из свечей %s и %s инструмента %s",
+	},
+}
+
+// Language of the printed reports and error hints, English by default
+var Language = LanguageEnglish
+
+// SetLanguage selects the language by its code
+func SetLanguage(language string) error {
+	if _, ok := translations[language]; !ok && language != LanguageEnglish {
+		return UnknownLanguageError
+	}
+	Language = language
+	return nil
+}
+
+// T translates the text, which may be a format string, to the selected language
+func T(text string) string {
+	if translated, ok := translations[Language][text]; ok {
+		return translated
+	}
+	return text
+}
@@ -0,0 +1,123 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	"opensource.tbank.ru/invest/invest-go/investgo"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// CacheDir keeps operations and candles between incremental runs
+var CacheDir = ".cache"
+
+// Operations may be booked a few days after they happened, so they are fetched again
+const operationsOverlap = 7 * 24 * time.Hour
+
+// operationsCache is the operations of an account up to the high-water mark
+type operationsCache struct {
+	To    time.Time
+	Items []*pb.OperationItem
+}
+
+// getOperationsIncremental gets only operations newer than the cached ones and merges them
+func getOperationsIncremental(op *investgo.OperationsServiceClient, logger *zap.Logger,
+	accountId string, from, now time.Time) ([]*pb.OperationItem, error) {
+	name := "operations-" + accountId + ".json"
+	var cache operationsCache
+	ok, err := loadJSON(CacheDir, name, &cache)
+	if err != nil {
+		logger.Warn("ignoring broken operations cache", zap.String("account", accountId), zap.Error(err))
+	}
+	if !ok || cache.To.Before(from) {
+		items, err := getOperations(op, logger, accountId, from, now)
+		if err != nil {
+			return nil, err
+		}
+		cache = operationsCache{To: now, Items: items}
+	} else {
+		since := cache.To.Add(-operationsOverlap)
+		if since.Before(from) {
+			since = from
+		}
+		logger.Info("getting new operations",
+			zap.String("account", accountId),
+			zap.Time("since", since),
+			zap.Int("cached", len(cache.Items)))
+		fresh := make(map[string]bool)
+		var items []*pb.OperationItem
+		req := &investgo.GetOperationsByCursorRequest{
+			AccountId: accountId,
+			From:      since,
+			To:        now,
+			State:     pb.OperationState_OPERATION_STATE_EXECUTED,
+		}
+		err = fetchOperations(op, logger, req, func(operations []*pb.OperationItem, cursor string, hasNext bool) error {
+			for _, operation := range operations {
+				fresh[operation.Id] = true
+				items = append(items, operation)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, operation := range cache.Items {
+			if !fresh[operation.Id] && operation.Date.AsTime().Before(since) {
+				items = append(items, operation)
+			}
+		}
+		cache = operationsCache{To: now, Items: items}
+	}
+	err = saveJSON(CacheDir, name, cache)
+	if err != nil {
+		logger.Warn("error saving operations cache", zap.Error(err))
+	}
+	return cache.Items, nil
+}
+
+// getCandlesIncremental gets only candles since the latest cached one, which may have been incomplete
+func getCandlesIncremental(md *investgo.MarketDataServiceClient, instrumentUid string) ([]*pb.HistoricCandle, error) {
+	if candles, ok := candleCache[instrumentUid]; ok {
+		return candles, nil
+	}
+	name := "candles-" + candlesKey(instrumentUid) + ".json"
+	var cached []*pb.HistoricCandle
+	// a broken cache is downloaded again
+	_, _ = loadJSON(CacheDir, name, &cached)
+	from := time.Date(TaxYear, 1, 1, 0, 0, 0, 0, Location)
+	if len(cached) > 0 {
+		from = cached[len(cached)-1].Time.AsTime()
+	}
+	var candles []*pb.HistoricCandle
+	if from.Before(candlesTo()) {
+		var err error
+		candles, err = fetchCandles(md, instrumentUid, from)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for len(cached) > 0 && !cached[len(cached)-1].Time.AsTime().Before(from) {
+		cached = cached[:len(cached)-1]
+	}
+	candles = append(cached, candles...)
+	candleCache[instrumentUid] = candles
+	_ = saveJSON(CacheDir, name, candles)
+	return candles, nil
+}
@@ -0,0 +1,98 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"maps"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+	"opensource.tbank.ru/invest/invest-go/investgo"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// bond instrumentUid -> nominal, shared by all accounts
+var nominals = make(map[string]*pb.MoneyValue)
+
+func getNominal(in *investgo.InstrumentsServiceClient, instrumentUid string) (*pb.MoneyValue, error) {
+	if nominal, ok := nominals[instrumentUid]; ok {
+		return nominal, nil
+	}
+	AwaitQuota("BondBy")
+	start := time.Now()
+	bond, err := in.BondByUid(instrumentUid)
+	TraceCall("BondBy", instrumentUid, start, bond, err)
+	if err != nil {
+		return nil, err
+	}
+	nominals[instrumentUid] = bond.Instrument.Nominal
+	return bond.Instrument.Nominal, nil
+}
+
+// ApplyLastPrices replaces the current prices of the held assets (assetUid -> instrumentUid)
+// with the last prices from a single batch call
+func ApplyLastPrices(md *investgo.MarketDataServiceClient, in *investgo.InstrumentsServiceClient, logger *zap.Logger,
+	state *State, held map[string]string) error {
+	if len(held) == 0 {
+		return nil
+	}
+	assetUids := make(map[string]string, len(held))
+	for assetUid, instrumentUid := range held {
+		assetUids[instrumentUid] = assetUid
+	}
+	logger.Debug("getting last prices", zap.Int("instruments", len(held)))
+	instrumentUids := slices.Sorted(maps.Keys(assetUids))
+	AwaitQuota("GetLastPrices")
+	start := time.Now()
+	resp, err := md.GetLastPrices(instrumentUids)
+	TraceCall("GetLastPrices", instrumentUids, start, resp, err)
+	if err != nil {
+		logger.Error("error getting last prices", zap.Error(err))
+		return err
+	}
+	for _, last := range resp.LastPrices {
+		assetUid, ok := assetUids[last.InstrumentUid]
+		if !ok || last.Price == nil {
+			continue
+		}
+		price := ToRat(last.Price)
+		currency := instrumentCurrencies[last.InstrumentUid]
+		if IsBond(assetUid) {
+			nominal, err := getNominal(in, last.InstrumentUid)
+			if err != nil {
+				logger.Error("error getting bond for instrument",
+					zap.String("instrument", last.InstrumentUid),
+					zap.String("ticker", tickers[assetUid]),
+					zap.Error(err))
+				return err
+			}
+			price = BondPrice(price, ToRat(nominal))
+			currency = NormalizeCurrency(nominal.Currency)
+		}
+		if price.Sign() == 0 {
+			continue
+		}
+		logger.Debug("using last price",
+			zap.String("ticker", tickers[assetUid]),
+			zap.Stringer("portfolio_price", AddRat(state.Prices[assetUid], nil)),
+			zap.Stringer("last_price", price))
+		state.Prices[assetUid] = price
+		state.Currencies[assetUid] = currency
+	}
+	return nil
+}
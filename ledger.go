@@ -0,0 +1,154 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// LedgerEntry is a processed operation with its payment converted to USD
+type LedgerEntry struct {
+	Account string    `json:"account"`
+	Id      string    `json:"id"`
+	Date    time.Time `json:"date"`
+	Type    string    `json:"type"`
+	// ticker, ISIN or UID by ExportKey
+	Asset     string `json:"asset,omitempty"`
+	Ticker    string `json:"ticker"`
+	Name      string `json:"name,omitempty"`
+	Isin      string `json:"isin,omitempty"`
+	Quantity  int64  `json:"quantity"`
+	Payment   string `json:"payment"`
+	Currency  string `json:"currency"`
+	USD       string `json:"usd"`
+	USDExact  string `json:"usd_exact"`
+	RateToUSD string `json:"rate_to_usd"`
+}
+
+// NewLedgerEntry converts the operation, the identifiers are replaced with pseudonyms and the amounts
+// are scaled when redacting
+func NewLedgerEntry(accountId string, operation *pb.OperationItem) LedgerEntry {
+	name, isin := InstrumentName(operation.AssetUid)
+	entry := LedgerEntry{
+		Account:  Redact("account", accountId),
+		Id:       Redact("operation", operation.Id),
+		Date:     operation.Date.AsTime().In(Location),
+		Type:     operation.Type.String(),
+		Ticker:   Redact("asset", tickers[operation.AssetUid]),
+		Name:     name,
+		Isin:     isin,
+		Quantity: scaleQuantity(operation.Quantity),
+	}
+	if operation.AssetUid != "" {
+		entry.Asset = Redact("asset", AssetKey(operation.AssetUid))
+	}
+	if operation.Payment != nil {
+		payment := ScaleAmount(ToRat(operation.Payment))
+		entry.Payment = payment.FloatString(2)
+		entry.Currency = operation.Payment.Currency
+		if rate, ok := ExchangeRates[entry.Currency]; ok {
+			usd := (&big.Rat{}).Quo(payment, rate)
+			entry.USD = Round(usd, MoneyDecimals, Rounding).FloatString(MoneyDecimals)
+			entry.USDExact = usd.String()
+			entry.RateToUSD = rate.RatString()
+		}
+	}
+	return entry
+}
+
+// scaleQuantity scales the quantity when redacting, rounded to whole units
+func scaleQuantity(quantity int64) int64 {
+	if redactor == nil {
+		return quantity
+	}
+	scaled := Round(ScaleAmount(big.NewRat(quantity, 1)), 0, RoundHalfEven)
+	return scaled.Num().Int64()
+}
+
+// Ledger lists all processed operations of the evaluated accounts
+func Ledger(evaluations []*Evaluation) []LedgerEntry {
+	var ledger []LedgerEntry
+	for _, evaluation := range evaluations {
+		for _, operation := range evaluation.Operations {
+			ledger = append(ledger, NewLedgerEntry(evaluation.AccountId, operation))
+		}
+	}
+	slices.SortStableFunc(ledger, func(a, b LedgerEntry) int {
+		return cmp.Or(a.Date.Compare(b.Date), strings.Compare(a.Account, b.Account), strings.Compare(a.Id, b.Id))
+	})
+	return ledger
+}
+
+// WriteLedger writes the ledger as JSON or CSV depending on the file extension
+func WriteLedger(filename string, ledger []LedgerEntry) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if filepath.Ext(filename) == ".json" {
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(ledger)
+		if err != nil {
+			return err
+		}
+		return file.Close()
+	}
+	w := csv.NewWriter(file)
+	err = w.Write([]string{"account", "id", "date", "type", "asset", "ticker", "name", "isin", "quantity", "payment", "currency", "usd", "rate_to_usd"})
+	if err != nil {
+		return err
+	}
+	for _, entry := range ledger {
+		err = w.Write([]string{
+			entry.Account,
+			entry.Id,
+			entry.Date.Format(time.RFC3339),
+			entry.Type,
+			entry.Asset,
+			entry.Ticker,
+			entry.Name,
+			entry.Isin,
+			strconv.FormatInt(entry.Quantity, 10),
+			entry.Payment,
+			entry.Currency,
+			entry.USD,
+			entry.RateToUSD,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	err = w.Error()
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,109 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"opensource.tbank.ru/invest/invest-go/investgo"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// legacyOperationsWindow is the period of a single legacy operations request,
+// the legacy method has no pagination and long periods may time out
+const legacyOperationsWindow = 30 * 24 * time.Hour
+
+// cursorUnsupported tells if the cursor-based operations method is unavailable for the account,
+// e.g. for older account types
+func cursorUnsupported(err error) bool {
+	switch status.Code(err) {
+	case codes.Unimplemented, codes.FailedPrecondition:
+		return true
+	}
+	return false
+}
+
+// legacyOperationItem converts an operation of the legacy method to the cursor-based form
+func legacyOperationItem(operation *pb.Operation) *pb.OperationItem {
+	return &pb.OperationItem{
+		Id:                operation.Id,
+		ParentOperationId: operation.ParentOperationId,
+		Date:              operation.Date,
+		Type:              operation.OperationType,
+		Description:       operation.Type,
+		State:             operation.State,
+		InstrumentUid:     operation.InstrumentUid,
+		Figi:              operation.Figi,
+		InstrumentType:    operation.InstrumentType,
+		PositionUid:       operation.PositionUid,
+		Payment:           operation.Payment,
+		Price:             operation.Price,
+		Quantity:          operation.Quantity,
+		QuantityRest:      operation.QuantityRest,
+		QuantityDone:      operation.Quantity - operation.QuantityRest,
+		AssetUid:          operation.AssetUid,
+	}
+}
+
+// getLegacyOperations gets operations of the period with the legacy method by date windows,
+// newest first like the cursor-based method
+func getLegacyOperations(op *investgo.OperationsServiceClient, logger *zap.Logger,
+	accountId string, state pb.OperationState, from, to time.Time) ([]*pb.OperationItem, error) {
+	var items []*pb.OperationItem
+	// operations at the boundaries of windows may come twice
+	seen := make(map[string]bool)
+	for end := to; end.After(from); end = end.Add(-legacyOperationsWindow) {
+		begin := end.Add(-legacyOperationsWindow)
+		if begin.Before(from) {
+			begin = from
+		}
+		req := &investgo.GetOperationsRequest{
+			AccountId: accountId,
+			State:     state,
+			From:      begin,
+			To:        end,
+		}
+		AwaitQuota("GetOperations")
+		start := time.Now()
+		operations, err := op.GetOperations(req)
+		TraceCall("GetOperations", req, start, operations, err)
+		if err != nil {
+			logger.Error("error getting legacy operations",
+				zap.Any("request", req),
+				zap.Error(err))
+			return nil, err
+		}
+		window := make([]*pb.OperationItem, 0, len(operations.Operations))
+		for _, operation := range operations.Operations {
+			if !seen[operation.Id] {
+				seen[operation.Id] = true
+				window = append(window, legacyOperationItem(operation))
+			}
+		}
+		slices.SortStableFunc(window, func(x, y *pb.OperationItem) int {
+			return cmp.Compare(y.Date.AsTime().UnixNano(), x.Date.AsTime().UnixNano())
+		})
+		items = append(items, window...)
+		logger.Debug("getting legacy operations", zap.Time("from", req.From), zap.Int("operations", len(window)))
+	}
+	return items, nil
+}
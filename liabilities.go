@@ -0,0 +1,60 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+)
+
+// Policies for liabilities: negative cash balances of margin accounts and short positions
+const (
+	// liabilities reduce the value, it is the net value of the account
+	LiabilitiesNet = "net"
+	// only assets are counted, liabilities are reported separately
+	LiabilitiesGross = "gross"
+)
+
+// LiabilitiesPolicy tells whether liabilities reduce the reportable value
+var LiabilitiesPolicy = LiabilitiesNet
+
+// Liabilities returns the value of the negative positions in USD, e.g. margin loans and short positions,
+// as a negative amount
+func Liabilities(state *State, excluded map[string]bool) *big.Rat {
+	sum := &big.Rat{}
+	for key, quantity := range state.Portfolio {
+		if quantity.Sign() >= 0 || excluded[key] || IsFutures(key) {
+			continue
+		}
+		value, currency := quantity, key
+		if price, ok := state.Prices[key]; ok {
+			value = (&big.Rat{}).Mul(AddRat(price, state.Accrued[key]), quantity)
+			currency = state.Currencies[key]
+		}
+		if rate, ok := ExchangeRates[currency]; ok {
+			sum = AddRat(sum, (&big.Rat{}).Quo(value, rate))
+		}
+	}
+	return sum
+}
+
+// uncountedLiabilities returns the liabilities which do not reduce the reportable value by the policy
+func uncountedLiabilities(state *State, excluded map[string]bool) *big.Rat {
+	if LiabilitiesPolicy != LiabilitiesGross {
+		return &big.Rat{}
+	}
+	return Liabilities(state, excluded)
+}
@@ -0,0 +1,54 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestLiabilitiesPolicy(t *testing.T) {
+	defer func() { LiabilitiesPolicy = LiabilitiesNet }()
+	state := &State{
+		Portfolio: map[string]*big.Rat{
+			"usd":   rat("-300"),
+			"long":  rat("10"),
+			"short": rat("-2"),
+		},
+		Prices:     map[string]*big.Rat{"long": rat("50"), "short": rat("25")},
+		Accrued:    map[string]*big.Rat{},
+		Currencies: map[string]string{"long": "usd", "short": "usd"},
+	}
+	if liabilities := Liabilities(state, nil); liabilities.Cmp(rat("-350")) != 0 {
+		t.Errorf("Liabilities() = %v, want -350", liabilities)
+	}
+	for _, test := range []struct {
+		policy string
+		want   *big.Rat
+	}{
+		{LiabilitiesNet, rat("150")},
+		{LiabilitiesGross, rat("500")},
+	} {
+		LiabilitiesPolicy = test.policy
+		if _, _, aggregate := Cost(state, nil); aggregate.Cmp(test.want) != 0 {
+			t.Errorf("%s Cost() aggregate = %v, want %v", test.policy, aggregate, test.want)
+		}
+		if aggregate, ok := NewFixedEngine().Aggregate(state, nil); !ok || aggregate.Rat().Cmp(test.want) != 0 {
+			t.Errorf("%s fixed aggregate = %v, %v, want %v", test.policy, aggregate.Rat(), ok, test.want)
+		}
+	}
+}
@@ -0,0 +1,349 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"math/big"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"opensource.tbank.ru/invest/invest-go/investgo"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// RateFunc returns the official RUB rate of the currency set for the date
+type RateFunc func(currency string, date time.Time) (*big.Rat, error)
+
+// Lot is a purchase of a security that is still held, with its cost in the trade currency and in RUB and USD
+// at the official rates of the trade date, commissions included
+type Lot struct {
+	Asset    string
+	Date     time.Time
+	Quantity int64
+	Currency string
+	Cost     *big.Rat
+	CostRUB  *big.Rat
+	CostUSD  *big.Rat
+	purchase int
+}
+
+// split takes the part of the lot with the quantity, the lot keeps the rest
+func (l *Lot) split(quantity int64) Lot {
+	part := *l
+	part.Quantity = quantity
+	share := big.NewRat(quantity, l.Quantity)
+	part.Cost = (&big.Rat{}).Mul(l.Cost, share)
+	part.CostRUB = (&big.Rat{}).Mul(l.CostRUB, share)
+	part.CostUSD = (&big.Rat{}).Mul(l.CostUSD, share)
+	l.Quantity -= quantity
+	l.Cost = SubRat(l.Cost, part.Cost)
+	l.CostRUB = SubRat(l.CostRUB, part.CostRUB)
+	l.CostUSD = SubRat(l.CostUSD, part.CostUSD)
+	return part
+}
+
+// RealizedLot is a lot, or the part of it, that was sold, the proceeds are net of the commission
+type RealizedLot struct {
+	Lot
+	SellDate    time.Time
+	Proceeds    *big.Rat
+	ProceedsRUB *big.Rat
+	ProceedsUSD *big.Rat
+	// Repurchase is the nearest other purchase of the asset within WashSaleWindow of a sale at a loss
+	Repurchase *time.Time
+}
+
+// WashSaleWindow is how far before or after a sale at a loss a purchase of the same asset makes it a wash sale
+const WashSaleWindow = 30 * 24 * time.Hour
+
+// GainRUB returns the taxable gain in RUB, it includes the revaluation of the currency between the trade dates
+func (r RealizedLot) GainRUB() *big.Rat {
+	return SubRat(r.ProceedsRUB, r.CostRUB)
+}
+
+// GainUSD returns the gain in USD, a loss is negative
+func (r RealizedLot) GainUSD() *big.Rat {
+	return SubRat(r.ProceedsUSD, r.CostUSD)
+}
+
+// absRat returns the absolute value of the amount, zero if there is none
+func absRat(value *pb.MoneyValue) *big.Rat {
+	if value == nil {
+		return &big.Rat{}
+	}
+	return (&big.Rat{}).Abs(ToRat(value))
+}
+
+// convertLot returns the amount in RUB and USD at the official rates of the date
+func convertLot(rate RateFunc, amount *big.Rat, currency string, date time.Time) (*big.Rat, *big.Rat, error) {
+	rub, err := rate(currency, date)
+	if err != nil {
+		return nil, nil, err
+	}
+	usd, err := rate("usd", date)
+	if err != nil {
+		return nil, nil, err
+	}
+	amountRUB := (&big.Rat{}).Mul(amount, rub)
+	return amountRUB, (&big.Rat{}).Quo(amountRUB, usd), nil
+}
+
+// TrackLots matches the sales to the purchases first in, first out over the whole history of the account and
+// returns the lots still held and the realized ones. Securities transferred in have no known cost and are skipped
+// with a warning, so are the sales of them.
+func TrackLots(logger *zap.Logger, operations []*pb.OperationItem, rate RateFunc) ([]Lot, []RealizedLot, error) {
+	operations = slices.Clone(operations)
+	slices.SortStableFunc(operations, func(a, b *pb.OperationItem) int {
+		return a.Date.AsTime().Compare(b.Date.AsTime())
+	})
+	held := make(map[string][]Lot)
+	purchases := make(map[string][]Lot)
+	var realized []RealizedLot
+	for _, operation := range operations {
+		if operation.AssetUid == "" || operation.Quantity == 0 {
+			continue
+		}
+		date := operation.Date.AsTime().In(Location)
+		switch operation.Type {
+		case pb.OperationType_OPERATION_TYPE_BUY:
+			currency := operation.Payment.GetCurrency()
+			cost := AddRat(absRat(operation.Payment), absRat(operation.Commission))
+			costRUB, costUSD, err := convertLot(rate, cost, currency, date)
+			if err != nil {
+				logger.Error("error getting official rate",
+					zap.String("currency", currency),
+					zap.Time("date", date),
+					zap.Error(err))
+				return nil, nil, err
+			}
+			lot := Lot{
+				Asset:    operation.AssetUid,
+				Date:     date,
+				Quantity: operation.Quantity,
+				Currency: currency,
+				Cost:     cost,
+				CostRUB:  costRUB,
+				CostUSD:  costUSD,
+				purchase: len(purchases[operation.AssetUid]),
+			}
+			held[operation.AssetUid] = append(held[operation.AssetUid], lot)
+			purchases[operation.AssetUid] = append(purchases[operation.AssetUid], lot)
+		case pb.OperationType_OPERATION_TYPE_SELL:
+			currency := operation.Payment.GetCurrency()
+			proceeds := SubRat(absRat(operation.Payment), absRat(operation.Commission))
+			proceedsRUB, proceedsUSD, err := convertLot(rate, proceeds, currency, date)
+			if err != nil {
+				logger.Error("error getting official rate",
+					zap.String("currency", currency),
+					zap.Time("date", date),
+					zap.Error(err))
+				return nil, nil, err
+			}
+			remaining := operation.Quantity
+			lots := held[operation.AssetUid]
+			for remaining > 0 && len(lots) > 0 {
+				quantity := min(remaining, lots[0].Quantity)
+				share := big.NewRat(quantity, operation.Quantity)
+				realized = append(realized, RealizedLot{
+					Lot:         lots[0].split(quantity),
+					SellDate:    date,
+					Proceeds:    (&big.Rat{}).Mul(proceeds, share),
+					ProceedsRUB: (&big.Rat{}).Mul(proceedsRUB, share),
+					ProceedsUSD: (&big.Rat{}).Mul(proceedsUSD, share),
+				})
+				if lots[0].Quantity == 0 {
+					lots = lots[1:]
+				}
+				remaining -= quantity
+			}
+			held[operation.AssetUid] = lots
+			if remaining > 0 {
+				logger.Warn("sale without purchased lots, its cost is unknown",
+					zap.String("asset", operation.AssetUid),
+					zap.Time("date", date),
+					zap.Int64("quantity", remaining))
+			}
+		case pb.OperationType_OPERATION_TYPE_INPUT_SECURITIES:
+			logger.Warn("securities transferred in have no known cost",
+				zap.String("asset", operation.AssetUid),
+				zap.Time("date", date),
+				zap.Int64("quantity", operation.Quantity))
+		}
+	}
+	for i := range realized {
+		realized[i].Repurchase = repurchase(realized[i], purchases[realized[i].Asset])
+	}
+	var open []Lot
+	for _, lots := range held {
+		open = append(open, lots...)
+	}
+	slices.SortStableFunc(open, func(a, b Lot) int {
+		return cmp.Or(a.Date.Compare(b.Date), cmp.Compare(a.Asset, b.Asset))
+	})
+	return open, realized, nil
+}
+
+// repurchase returns the date of the purchase nearest to the sale at a loss within WashSaleWindow,
+// the purchase of the sold lot itself does not count
+func repurchase(lot RealizedLot, purchases []Lot) *time.Time {
+	if lot.GainUSD().Sign() >= 0 {
+		return nil
+	}
+	var nearest *time.Time
+	for _, purchase := range purchases {
+		if purchase.purchase == lot.purchase {
+			continue
+		}
+		distance := purchase.Date.Sub(lot.SellDate).Abs()
+		if distance > WashSaleWindow || nearest != nil && distance >= nearest.Sub(lot.SellDate).Abs() {
+			continue
+		}
+		date := purchase.Date
+		nearest = &date
+	}
+	return nearest
+}
+
+// AccountLots is the cost basis of the securities of an account
+type AccountLots struct {
+	Account  string
+	Open     []Lot
+	Realized []RealizedLot
+}
+
+// TaxableGainRUB returns the gain in RUB of the lots sold during the tax year
+func (a AccountLots) TaxableGainRUB() *big.Rat {
+	gain := &big.Rat{}
+	for _, lot := range a.Realized {
+		if InTaxYear(lot.SellDate) {
+			gain = AddRat(gain, lot.GainRUB())
+		}
+	}
+	return gain
+}
+
+// WashSales returns the lots sold at a loss during the tax year with a repurchase around the sale
+func (a AccountLots) WashSales() []RealizedLot {
+	var sales []RealizedLot
+	for _, lot := range a.Realized {
+		if lot.Repurchase != nil && InTaxYear(lot.SellDate) {
+			sales = append(sales, lot)
+		}
+	}
+	return sales
+}
+
+// GetLots tracks the lots over the operations of the account since it was opened
+func GetLots(op *investgo.OperationsServiceClient, logger *zap.Logger, account AccountInfo, now time.Time) (AccountLots, error) {
+	from := account.Start()
+	if account.OpenedDate != nil {
+		from = *account.OpenedDate
+	}
+	var operations []*pb.OperationItem
+	req := &investgo.GetOperationsByCursorRequest{
+		AccountId: account.Id,
+		From:      from,
+		To:        now,
+		State:     pb.OperationState_OPERATION_STATE_EXECUTED,
+	}
+	err := fetchOperations(op, logger, req, func(items []*pb.OperationItem, cursor string, hasNext bool) error {
+		operations = append(operations, items...)
+		return nil
+	})
+	if err != nil {
+		return AccountLots{}, err
+	}
+	for _, operation := range operations {
+		NormalizeOperationCurrencies(operation)
+	}
+	open, realized, err := TrackLots(logger, operations, CBRRate)
+	if err != nil {
+		return AccountLots{}, err
+	}
+	return AccountLots{Account: account.Id, Open: open, Realized: realized}, nil
+}
+
+// WriteLots writes the open lots and the lots realized during the tax year as CSV,
+// open lots have no sale date and proceeds. The identifiers are replaced with pseudonyms
+// and the amounts are scaled when redacting.
+func WriteLots(filename string, accounts []AccountLots) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := csv.NewWriter(file)
+	err = w.Write([]string{"account", "asset", "ticker", "buy_date", "sell_date", "quantity", "currency",
+		"cost", "cost_rub", "cost_usd", "proceeds", "proceeds_rub", "proceeds_usd", "gain_rub", "repurchase"})
+	if err != nil {
+		return err
+	}
+	amount := func(value *big.Rat) string {
+		return ScaleAmount(value).FloatString(2)
+	}
+	write := func(account string, lot Lot, sellDate string, sale ...string) error {
+		return w.Write(append([]string{
+			Redact("account", account),
+			Redact("asset", lot.Asset),
+			Redact("asset", tickers[lot.Asset]),
+			lot.Date.Format(time.DateOnly),
+			sellDate,
+			strconv.FormatInt(scaleQuantity(lot.Quantity), 10),
+			lot.Currency,
+			amount(lot.Cost),
+			amount(lot.CostRUB),
+			amount(lot.CostUSD),
+		}, sale...))
+	}
+	for _, account := range accounts {
+		for _, lot := range account.Realized {
+			if !InTaxYear(lot.SellDate) {
+				continue
+			}
+			repurchase := ""
+			if lot.Repurchase != nil {
+				repurchase = lot.Repurchase.Format(time.DateOnly)
+			}
+			err = write(account.Account, lot.Lot, lot.SellDate.Format(time.DateOnly),
+				amount(lot.Proceeds),
+				amount(lot.ProceedsRUB),
+				amount(lot.ProceedsUSD),
+				amount(lot.GainRUB()),
+				repurchase)
+			if err != nil {
+				return err
+			}
+		}
+		for _, lot := range account.Open {
+			err = write(account.Account, lot, "", "", "", "", "", "")
+			if err != nil {
+				return err
+			}
+		}
+	}
+	w.Flush()
+	err = w.Error()
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,142 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+func TestTrackLots(t *testing.T) {
+	date := time.Date(TaxYear, 3, 1, 12, 0, 0, 0, time.UTC)
+	operation := func(kind pb.OperationType, days int, quantity, units int64) *pb.OperationItem {
+		return &pb.OperationItem{
+			Type:       kind,
+			AssetUid:   "a",
+			Date:       timestamppb.New(date.AddDate(0, 0, days)),
+			Quantity:   quantity,
+			Payment:    &pb.MoneyValue{Currency: "usd", Units: units},
+			Commission: &pb.MoneyValue{Currency: "usd", Units: -1},
+		}
+	}
+	// the dollar is 80 rubles before the purchases and 100 since the sale
+	rate := func(currency string, at time.Time) (*big.Rat, error) {
+		if currency == "rub" {
+			return big.NewRat(1, 1), nil
+		}
+		if at.Before(date.AddDate(0, 0, 10)) {
+			return big.NewRat(80, 1), nil
+		}
+		return big.NewRat(100, 1), nil
+	}
+	// newest first, as the operations are fetched
+	operations := []*pb.OperationItem{
+		operation(pb.OperationType_OPERATION_TYPE_SELL, 10, 15, 151),
+		operation(pb.OperationType_OPERATION_TYPE_BUY, 0, 10, -99),
+		operation(pb.OperationType_OPERATION_TYPE_BUY, -400, 10, -99),
+	}
+	open, realized, err := TrackLots(zap.NewNop(), operations, rate)
+	if err != nil {
+		t.Fatalf("TrackLots() error = %v", err)
+	}
+	if len(open) != 1 || open[0].Quantity != 5 || open[0].Cost.Cmp(big.NewRat(50, 1)) != 0 ||
+		open[0].CostRUB.Cmp(big.NewRat(4000, 1)) != 0 {
+		t.Fatalf("TrackLots() open = %+v, want 5 left from the second purchase", open)
+	}
+	if len(realized) != 2 || realized[0].Quantity != 10 || realized[1].Quantity != 5 {
+		t.Fatalf("TrackLots() realized = %+v, want the first purchase and half of the second", realized)
+	}
+	// 10 bought for 100 dollars at 80 and sold for 100 dollars at 100 are a gain in rubles only
+	first := realized[0]
+	for _, test := range []struct {
+		name      string
+		got, want *big.Rat
+	}{
+		{"cost", first.Cost, big.NewRat(100, 1)},
+		{"cost_rub", first.CostRUB, big.NewRat(8000, 1)},
+		{"cost_usd", first.CostUSD, big.NewRat(100, 1)},
+		{"proceeds", first.Proceeds, big.NewRat(100, 1)},
+		{"proceeds_rub", first.ProceedsRUB, big.NewRat(10000, 1)},
+		{"gain_rub", first.GainRUB(), big.NewRat(2000, 1)},
+		{"gain_rub_second", realized[1].GainRUB(), big.NewRat(1000, 1)},
+	} {
+		if test.got.Cmp(test.want) != 0 {
+			t.Errorf("%s = %s, want %s", test.name, test.got.FloatString(2), test.want.FloatString(2))
+		}
+	}
+	lots := AccountLots{Open: open, Realized: realized}
+	if got := lots.TaxableGainRUB(); got.Cmp(big.NewRat(3000, 1)) != 0 {
+		t.Errorf("TaxableGainRUB() = %s, want 3000", got.FloatString(2))
+	}
+}
+
+func TestTrackLotsSaleWithoutPurchase(t *testing.T) {
+	operations := []*pb.OperationItem{{
+		Type:     pb.OperationType_OPERATION_TYPE_SELL,
+		AssetUid: "a",
+		Date:     timestamppb.New(time.Date(TaxYear, 3, 1, 12, 0, 0, 0, time.UTC)),
+		Quantity: 1,
+		Payment:  &pb.MoneyValue{Currency: "rub", Units: 100},
+	}}
+	rate := func(string, time.Time) (*big.Rat, error) {
+		return big.NewRat(1, 1), nil
+	}
+	open, realized, err := TrackLots(zap.NewNop(), operations, rate)
+	if err != nil || len(open) != 0 || len(realized) != 0 {
+		t.Errorf("TrackLots() = %v, %v, %v, want no lots", open, realized, err)
+	}
+}
+
+func TestTrackLotsWashSale(t *testing.T) {
+	date := time.Date(TaxYear, 3, 1, 12, 0, 0, 0, time.UTC)
+	operation := func(kind pb.OperationType, days int, units int64) *pb.OperationItem {
+		return &pb.OperationItem{
+			Type:     kind,
+			AssetUid: "a",
+			Date:     timestamppb.New(date.AddDate(0, 0, days)),
+			Quantity: 1,
+			Payment:  &pb.MoneyValue{Currency: "usd", Units: units},
+		}
+	}
+	rate := func(string, time.Time) (*big.Rat, error) {
+		return big.NewRat(1, 1), nil
+	}
+	operations := []*pb.OperationItem{
+		operation(pb.OperationType_OPERATION_TYPE_BUY, 0, -100),
+		operation(pb.OperationType_OPERATION_TYPE_SELL, 10, 80),
+		operation(pb.OperationType_OPERATION_TYPE_BUY, 25, -90),
+		operation(pb.OperationType_OPERATION_TYPE_BUY, 100, -90),
+		operation(pb.OperationType_OPERATION_TYPE_SELL, 110, 95),
+	}
+	_, realized, err := TrackLots(zap.NewNop(), operations, rate)
+	if err != nil || len(realized) != 2 {
+		t.Fatalf("TrackLots() = %v, %v, want two sales", realized, err)
+	}
+	// the loss is repurchased 15 days later, the purchase of the sold lot itself does not count
+	if got := realized[0].Repurchase; got == nil || !got.Equal(date.AddDate(0, 0, 25)) {
+		t.Errorf("Repurchase = %v, want %v", got, date.AddDate(0, 0, 25))
+	}
+	// the second sale is at a gain
+	if got := realized[1].Repurchase; got != nil {
+		t.Errorf("Repurchase = %v, want none", got)
+	}
+}
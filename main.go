@@ -17,26 +17,141 @@
 package main
 
 import (
+	"cmp"
 	"context"
-	"errors"
+	"flag"
+	"fmt"
 	"maps"
 	"math/big"
+	"os"
 	"slices"
+	"strings"
 	"time"
 
+	"github.com/matshch/tbank-invest/storage"
 	"go.uber.org/zap"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 	"opensource.tbank.ru/invest/invest-go/investgo"
 	pb "opensource.tbank.ru/invest/invest-go/proto"
 )
 
 const TaxYear = 2025
 
+var printVersion = flag.Bool("version", false,
+	"print the version, commit and build date and exit")
+var colorMode = flag.String("color", ColorAuto,
+	"highlight headers and totals of the printed tables: auto, always or never")
+var profile = flag.String("profile", "",
+	"use the settings of the profile from Profiles in config.yaml")
+var redact = flag.Bool("redact", false,
+	"replace account IDs and tickers with pseudonyms and scale amounts in all output, to share it in issues")
+var auditOperations = flag.Bool("audit-operations", false,
+	"print statistics of operation types for the tax year and exit")
+var auditStates = flag.String("audit-states", "executed",
+	"comma-separated operation states for -audit-operations: executed, canceled, progress or all")
+var whatIfFile = flag.String("what-if", "",
+	"YAML file with hypothetical operations to add to the account")
+var ledgerFile = flag.String("ledger", "",
+	"export processed operations with USD values to a CSV or JSON file")
+var summaryFile = flag.String("summary", "",
+	"write the summary with rounded and exact values to a JSON file")
+var forwardFile = flag.String("forward", "",
+	"replay operations forward from known portfolio snapshots and compare with the backward reconstruction")
+var composition = flag.Bool("composition", false,
+	"break the portfolio down by country of risk and sector")
+var conservative = flag.Bool("conservative", false,
+	"also search the maximum by the conservative rules: close prices, limited carryforward and liabilities netted")
+var maxima = flag.String("maxima", "",
+	"print the maximum value of each day or week of the tax year")
+var traceAsset = flag.String("asset", "",
+	"investigate a single asset by its ticker, ISIN or UID: download only its candles and print its operations, positions and contribution")
+var explain = flag.String("explain", "",
+	"explain the value at a time, e.g. 2025-03-14T10:00Z: print the positions, their prices and candles, the exchange rates and the sum")
+var attribution = flag.Bool("attribution", false,
+	"print the yearly result of each asset: realized and unrealized, income and fees")
+var dividendTax = flag.Bool("dividend-tax", false,
+	"summarize the foreign dividend withholding by country and rate against the treaty rates")
+var lotsFile = flag.String("lots", "",
+	"write the cost basis of lots in RUB and USD at the official rates of the trade dates to a CSV file")
+var flexFile = flag.String("flex", "",
+	"export the trades and cash transactions of the tax year to a CSV file in the Interactive Brokers Flex schema")
+var portfolioPerformanceFile = flag.String("portfolio-performance", "",
+	"export the operations to a CSV file for the Portfolio Performance import")
+var ghostfolioFile = flag.String("ghostfolio", "",
+	"export the operations to a JSON file for the Ghostfolio import")
+var plainTextFile = flag.String("plaintext", "",
+	"export the operations of the tax year as Beancount entries to a .beancount file or as ledger-cli entries otherwise")
+var ndflFile = flag.String("ndfl", "",
+	"write foreign dividends with CBR rates for the 3-NDFL declaration to a CSV file")
+var fbarFile = flag.String("fbar", "",
+	"write a fill-ready FinCEN Form 114 foreign accounts section to a JSON file")
+var sandbox = flag.Bool("sandbox", false,
+	"use the sandbox endpoint with a sandbox token")
+var incremental = flag.Bool("incremental", false,
+	"fetch only operations and candles newer than the cached ones from the previous incremental run")
+var pointsFile = flag.String("points", "",
+	"stream evaluated points as NDJSON to a file, - for stdout")
+var pointsBreakdown = flag.Bool("points-breakdown", false,
+	"add the value in each currency to the streamed points")
+var threshold = flag.String("threshold", "",
+	"exit with code 10 when the maximum in USD exceeds the value")
+var fast = flag.Bool("fast", false,
+	"skip candles and evaluate only at operation times with trade and current prices for a quick estimate")
+var fixedPoint = flag.Bool("fixed", false,
+	"evaluate with int64 fixed-point arithmetic instead of exact rationals, faster with the same rounded results")
+var parallel = flag.Int("parallel", 1,
+	"value the points of the replay in this many goroutines, 0 for all cores")
+var trace = flag.Bool("trace", false,
+	"log every API call with its request, latency and response size")
+var traceDir = flag.String("trace-dir", "",
+	"also save every API request and response to a file in the directory, implies -trace")
+var cpuProfile = flag.String("cpuprofile", "",
+	"write a CPU profile of the run to a file")
+var memProfile = flag.String("memprofile", "",
+	"write a heap profile at the end of the run to a file")
+var pprofAddress = flag.String("pprof", "",
+	"serve pprof endpoints on the address, e.g. localhost:6060")
+var pdfFile = flag.String("pdf", "",
+	"write a printable PDF report with the maximum values, rates and methodology for tax records")
+var email = flag.Bool("email", false,
+	"email the HTML report with the PDF report and the summary to the SMTP recipients from config.yaml after the run")
+var sheets = flag.Bool("sheets", false,
+	"write the timelines and the summary to the Google Sheet from config.yaml after the run")
+var schedule = flag.String("schedule", "",
+	"re-run the evaluation at the times of the cron expression, e.g. \"0 6 * * *\", printing reports when the maximum changes")
+var scheduledNotifyList = flag.String("scheduled-notify", "",
+	"set by -schedule for its runs: send the listed notifications, email, sheets or webhooks, only when the yearly maximum has changed")
+var diffPrevious = flag.Bool("diff-previous", false,
+	"explain what has changed since the previous run")
+var reconcileDividends = flag.Bool("reconcile-dividends", false,
+	"compare dividend operations with the dividend calendar and the foreign issuer report")
+
+// State is the account state at some moment
+type State struct {
+	// assetUid or currency -> quantity
+	Portfolio map[string]*big.Rat
+	// assetUid -> price
+	Prices map[string]*big.Rat
+	// assetUid -> accrued coupon interest per bond, in the bond currency,
+	// declared dividends are cash receivables in the portfolio instead
+	Accrued map[string]*big.Rat
+	// assetUid -> currency of price and accrued interest
+	Currencies map[string]string
+}
+
+func (s *State) Clone() *State {
+	return &State{
+		Portfolio:  maps.Clone(s.Portfolio),
+		Prices:     maps.Clone(s.Prices),
+		Accrued:    maps.Clone(s.Accrued),
+		Currencies: maps.Clone(s.Currencies),
+	}
+}
+
 // Updates are applied in reverse order, from newest to oldest
-type Update func(portfolio, prices map[string]*big.Rat, currencies map[string]string)
+type Update func(state *State)
 
-var updates = map[time.Time][]Update{}
+// ExchangeRatesDate is the date of the exchange rates below, it changes with every update of the table
+const ExchangeRatesDate = "2025-12-31"
 
 // https://fiscaldata.treasury.gov/datasets/treasury-reporting-rates-exchange/treasury-reporting-rates-of-exchange-source
 var ExchangeRates = map[string]*big.Rat{
@@ -77,6 +192,12 @@ func AddRat(x, y *big.Rat) *big.Rat {
 	return (&big.Rat{}).Add(x, y)
 }
 
+// BondPrice converts bond price quoted in percent of nominal to the nominal currency
+func BondPrice(percent, nominal *big.Rat) *big.Rat {
+	price := (&big.Rat{}).Mul(percent, nominal)
+	return price.Quo(price, big.NewRat(100, 1))
+}
+
 func SubRat(x, y *big.Rat) *big.Rat {
 	if x == nil {
 		x = &big.Rat{}
@@ -88,14 +209,18 @@ func SubRat(x, y *big.Rat) *big.Rat {
 }
 
 func getCurrencyInstruments(in *investgo.InstrumentsServiceClient) (map[string]string, error) {
+	AwaitQuota("Currencies")
+	start := time.Now()
 	currencies, err := in.Currencies(pb.InstrumentStatus_INSTRUMENT_STATUS_ALL)
+	TraceCall("Currencies", pb.InstrumentStatus_INSTRUMENT_STATUS_ALL, start, currencies, err)
 	if err != nil {
 		return nil, err
 	}
 	currencyInstruments := make(map[string]string, len(ExchangeRates))
 	for _, currency := range currencies.Instruments {
-		if _, ok := ExchangeRates[currency.IsoCurrencyName]; ok {
-			currencyInstruments[currency.PositionUid] = currency.IsoCurrencyName
+		iso := NormalizeCurrency(currency.IsoCurrencyName)
+		if _, ok := ExchangeRates[iso]; ok {
+			currencyInstruments[currency.PositionUid] = iso
 		}
 	}
 	return currencyInstruments, nil
@@ -111,6 +236,26 @@ var tickers = make(map[string]string)
 // some assets are traded in different currencies depending on the instrument
 var instrumentCurrencies = make(map[string]string)
 
+// assetUid -> ISIN
+var isins = make(map[string]string)
+
+// assetUid -> instrument name, e.g. "Сбер Банк"
+var names = make(map[string]string)
+
+// assetUid -> instrument kind
+var kinds = make(map[string]pb.InstrumentType)
+
+// Futures are settled daily through variation margin, which is already reflected in the cash balance,
+// so their notional value must not be added to the account value.
+func IsFutures(assetUid string) bool {
+	return kinds[assetUid] == pb.InstrumentType_INSTRUMENT_TYPE_FUTURES
+}
+
+// Bonds are valued together with the accrued coupon interest, which is paid by the buyer
+func IsBond(assetUid string) bool {
+	return kinds[assetUid] == pb.InstrumentType_INSTRUMENT_TYPE_BOND
+}
+
 func getAssetUid(in *investgo.InstrumentsServiceClient, logger *zap.Logger, instrumentUid string) (string, error) {
 	if instrumentUid == "" {
 		return "", nil
@@ -119,30 +264,105 @@ func getAssetUid(in *investgo.InstrumentsServiceClient, logger *zap.Logger, inst
 		return assetUid, nil
 	}
 	logger.Debug("getting instrument info to resolve asset", zap.String("instrument", instrumentUid))
+	AwaitQuota("GetInstrumentBy")
+	start := time.Now()
 	resp, err := in.InstrumentByUid(instrumentUid)
+	TraceCall("GetInstrumentBy", instrumentUid, start, resp, err)
 	if err != nil {
 		return "", err
 	}
 	assetUid := resp.Instrument.AssetUid
 	logger.Debug("getting asset info", zap.String("asset", assetUid), zap.String("ticker", resp.Instrument.Ticker))
+	AwaitQuota("GetAssetBy")
+	start = time.Now()
 	asset, err := in.GetAssetBy(assetUid)
+	TraceCall("GetAssetBy", assetUid, start, asset, err)
 	if err != nil {
 		return "", err
 	}
 	for _, inst := range asset.Asset.Instruments {
 		assets[inst.Uid] = assetUid
 		logger.Debug("getting instrument info to resolve currency", zap.String("instrument", inst.Uid))
+		AwaitQuota("GetInstrumentBy")
+		start = time.Now()
 		instInfo, err := in.InstrumentByUid(inst.Uid)
+		TraceCall("GetInstrumentBy", inst.Uid, start, instInfo, err)
 		if err != nil {
 			return "", err
 		}
-		instrumentCurrencies[inst.Uid] = instInfo.Instrument.Currency
+		instrumentCurrencies[inst.Uid] = NormalizeCurrency(instInfo.Instrument.Currency)
 	}
 	assets[instrumentUid] = assetUid
 	tickers[assetUid] = resp.Instrument.Ticker
+	kinds[assetUid] = resp.Instrument.InstrumentKind
+	isins[assetUid] = resp.Instrument.Isin
+	names[assetUid] = resp.Instrument.Name
+	countries[assetUid] = resp.Instrument.CountryOfRisk
+	instrumentUids[assetUid] = instrumentUid
 	return assetUid, nil
 }
 
+// InstrumentName returns the name and the ISIN of the asset for people who do not know tickers,
+// they are left out when redacting, as they reveal the asset
+func InstrumentName(assetUid string) (string, string) {
+	if redactor != nil {
+		return "", ""
+	}
+	return names[assetUid], isins[assetUid]
+}
+
+// FindAsset resolves an asset UID, a ticker or an ISIN to the asset UID
+func FindAsset(id string) (string, bool) {
+	if _, ok := tickers[id]; ok {
+		return id, true
+	}
+	for _, assetUid := range slices.Sorted(maps.Keys(tickers)) {
+		if tickers[assetUid] == id || isins[assetUid] == id {
+			return assetUid, true
+		}
+	}
+	return "", false
+}
+
+// Keys of the assets in the exports
+const (
+	ExportKeyTicker = "ticker"
+	// ISINs are what foreign tax software and accountants match against
+	ExportKeyISIN = "isin"
+	ExportKeyUid  = "uid"
+)
+
+// ExportKey selects the keys of the assets in the exports
+var ExportKey = ExportKeyTicker
+
+// AssetKey returns the key of the asset in the exports, the asset UID if the ticker or ISIN is unknown,
+// currencies are keyed by their codes
+func AssetKey(assetUid string) string {
+	var key string
+	switch ExportKey {
+	case ExportKeyTicker:
+		key = tickers[assetUid]
+	case ExportKeyISIN:
+		key = isins[assetUid]
+	}
+	if key == "" {
+		return assetUid
+	}
+	return key
+}
+
+// ByTicker orders asset UIDs by their tickers to make the output stable between runs
+func ByTicker(a, b string) int {
+	return cmp.Or(strings.Compare(tickers[a], tickers[b]), strings.Compare(a, b))
+}
+
+// SortedInstruments returns instrument UIDs ordered by their asset tickers
+func SortedInstruments() []string {
+	return slices.SortedFunc(maps.Keys(assets), func(a, b string) int {
+		return cmp.Or(ByTicker(assets[a], assets[b]), strings.Compare(a, b))
+	})
+}
+
 func ToTickers(uids map[string]*big.Rat) map[string]*big.Rat {
 	portfolio := make(map[string]*big.Rat, len(uids))
 	for uid, value := range uids {
@@ -155,60 +375,42 @@ func ToTickers(uids map[string]*big.Rat) map[string]*big.Rat {
 	return portfolio
 }
 
-var UnsupportedOperationError = errors.New("unsupported operation type")
-
-func OperationToUpdate(operation *pb.OperationItem) (Update, error) {
-	switch operation.Type {
-	case pb.OperationType_OPERATION_TYPE_BUY:
-		return func(portfolio, _ map[string]*big.Rat, _ map[string]string) {
-			portfolio[operation.AssetUid] = SubRat(portfolio[operation.AssetUid], big.NewRat(operation.Quantity, 1))
-			if portfolio[operation.AssetUid].Cmp(&big.Rat{}) == 0 {
-				delete(portfolio, operation.AssetUid)
-			}
-			portfolio[operation.Payment.Currency] = SubRat(portfolio[operation.Payment.Currency], ToRat(operation.Payment))
-		}, nil
-	case pb.OperationType_OPERATION_TYPE_SELL:
-		return func(portfolio, _ map[string]*big.Rat, _ map[string]string) {
-			portfolio[operation.AssetUid] = AddRat(portfolio[operation.AssetUid], big.NewRat(operation.Quantity, 1))
-			portfolio[operation.Payment.Currency] = SubRat(portfolio[operation.Payment.Currency], ToRat(operation.Payment))
-			if portfolio[operation.Payment.Currency].Cmp(&big.Rat{}) == 0 {
-				delete(portfolio, operation.Payment.Currency)
-			}
-		}, nil
-	case pb.OperationType_OPERATION_TYPE_BROKER_FEE,
-		pb.OperationType_OPERATION_TYPE_DIVIDEND,
-		pb.OperationType_OPERATION_TYPE_DIVIDEND_TAX,
-		pb.OperationType_OPERATION_TYPE_INPUT,
-		pb.OperationType_OPERATION_TYPE_OUTPUT,
-		pb.OperationType_OPERATION_TYPE_TAX,
-		pb.OperationType_OPERATION_TYPE_TAX_CORRECTION:
-		return func(portfolio, _ map[string]*big.Rat, _ map[string]string) {
-			portfolio[operation.Payment.Currency] = SubRat(portfolio[operation.Payment.Currency], ToRat(operation.Payment))
-			if portfolio[operation.Payment.Currency].Cmp(&big.Rat{}) == 0 {
-				delete(portfolio, operation.Payment.Currency)
-			}
-		}, nil
-	case pb.OperationType_OPERATION_TYPE_INPUT_SECURITIES:
-		return func(portfolio, _ map[string]*big.Rat, _ map[string]string) {
-			portfolio[operation.AssetUid] = SubRat(portfolio[operation.AssetUid], big.NewRat(operation.Quantity, 1))
-			if portfolio[operation.AssetUid].Cmp(&big.Rat{}) == 0 {
-				delete(portfolio, operation.AssetUid)
-			}
-			// there is a payment, but it looks like it is for information purposes only
-		}, nil
-	default:
-		return nil, UnsupportedOperationError
+// SellAll replaces assets in the portfolio with their value in the trading currency
+func SellAll(portfolio map[string]*big.Rat, state *State) {
+	for assetUid, quantity := range portfolio {
+		if IsFutures(assetUid) {
+			delete(portfolio, assetUid)
+			continue
+		}
+		if price, ok := state.Prices[assetUid]; ok {
+			currency := state.Currencies[assetUid]
+			price = AddRat(price, state.Accrued[assetUid])
+			portfolio[currency] = AddRat(portfolio[currency], (&big.Rat{}).Mul(price, quantity))
+			delete(portfolio, assetUid)
+		}
 	}
 }
 
-func SellAll(portfolio, prices map[string]*big.Rat, currencies map[string]string) {
-	for assetUid, quantity := range portfolio {
-		if price, ok := prices[assetUid]; ok {
-			currency := currencies[assetUid]
-			portfolio[currency] = AddRat(portfolio[currency], (&big.Rat{}).Mul(price, quantity))
+// Exclude moves the excluded assets from the portfolio to a separate one
+func Exclude(portfolio map[string]*big.Rat, excluded map[string]bool) map[string]*big.Rat {
+	result := make(map[string]*big.Rat)
+	for assetUid := range excluded {
+		if quantity, ok := portfolio[assetUid]; ok {
+			result[assetUid] = quantity
 			delete(portfolio, assetUid)
 		}
 	}
+	return result
+}
+
+// Cost values the state by currency, separately for the excluded assets, and returns the aggregate value
+// of the rest in USD
+func Cost(state *State, excluded map[string]bool) (cost, excludedCost map[string]*big.Rat, aggregate *big.Rat) {
+	cost = maps.Clone(state.Portfolio)
+	excludedCost = Exclude(cost, excluded)
+	SellAll(cost, state)
+	SellAll(excludedCost, state)
+	return cost, excludedCost, SubRat(Aggregate(cost), uncountedLiabilities(state, excluded))
 }
 
 func Aggregate(cost map[string]*big.Rat) *big.Rat {
@@ -220,18 +422,236 @@ func Aggregate(cost map[string]*big.Rat) *big.Rat {
 }
 
 func main() {
+	os.Exit(run())
+}
+
+func run() (code int) {
+	flag.Parse()
+	if *printVersion {
+		fmt.Println("tbank-invest", GetBuildInfo())
+		return ExitSuccess
+	}
 	logger := zap.Must(zap.NewDevelopment())
+	if *redact {
+		redactor = NewRedactor()
+		logger = RedactLogs(logger)
+	}
 	defer logger.Sync()
+	if *trace || *traceDir != "" {
+		var err error
+		tracer, err = NewTracer(logger, *traceDir)
+		if err != nil {
+			logger.Error("error creating trace directory", zap.String("dir", *traceDir), zap.Error(err))
+			return ExitConfig
+		}
+	}
+	stopProfiling, err := StartProfiling(logger)
+	if err != nil {
+		logger.Error("error starting profiling", zap.Error(err))
+		return ExitFailure
+	}
+	defer stopProfiling()
+
+	err = SetColorMode(*colorMode)
+	if err != nil {
+		logger.Error("unknown color mode", zap.String("color", *colorMode))
+		return ExitConfig
+	}
+
+	command := flag.Arg(0)
+	switch command {
+	case "", "broker-report", "check", "demo":
+	default:
+		logger.Error("unknown command", zap.String("command", command))
+		return ExitConfig
+	}
+
+	data, err := ReadConfig("config.yaml", *profile)
+	if err != nil {
+		logger.Error("error reading config", zap.String("profile", *profile), zap.Error(err))
+		return ExitConfig
+	}
+	config, err := ParseConfig(data)
+	if err != nil {
+		logger.Error("error loading config", zap.Error(err))
+		return ExitConfig
+	}
+	config.Token, err = ResolveToken(config.Token, cmp.Or(*profile, "default"))
+	if err != nil {
+		logger.Error("error reading token from the keyring", zap.Error(err))
+		return ExitConfig
+	}
+	options, err := LoadOptions(data)
+	if err != nil {
+		logger.Error("error loading options", zap.Error(err))
+		return ExitConfig
+	}
+	if options.Language != "" {
+		err = SetLanguage(options.Language)
+		if err != nil {
+			logger.Error("unknown language", zap.String("language", options.Language), zap.Error(err))
+			return ExitConfig
+		}
+	}
+	if options.CacheDir != "" {
+		CacheDir = options.CacheDir
+	}
+	if options.CheckpointDir != "" {
+		CheckpointDir = options.CheckpointDir
+	}
+	if options.Timezone != "" {
+		Location, err = time.LoadLocation(options.Timezone)
+		if err != nil {
+			logger.Error("unknown timezone", zap.String("timezone", options.Timezone), zap.Error(err))
+			return ExitConfig
+		}
+	}
+	if options.Decimals != nil {
+		MoneyDecimals = *options.Decimals
+	}
+	switch options.ExportKey {
+	case "":
+	case ExportKeyTicker, ExportKeyISIN, ExportKeyUid:
+		ExportKey = options.ExportKey
+	default:
+		logger.Error("unknown export key", zap.String("key", options.ExportKey))
+		return ExitConfig
+	}
+	if options.OperationsPageSize < 0 || options.OperationsPageSize > MaxOperationsPageSize {
+		logger.Error("operations page size is out of range",
+			zap.Int("size", options.OperationsPageSize), zap.Int("max", MaxOperationsPageSize))
+		return ExitConfig
+	}
+	OperationsPageSize = int32(options.OperationsPageSize)
+	err = SetRateLimits(options.RateLimits)
+	if err != nil {
+		logger.Error("invalid rate limits", zap.Any("limits", options.RateLimits), zap.Error(err))
+		return ExitConfig
+	}
+	switch options.Liabilities {
+	case "":
+	case LiabilitiesNet, LiabilitiesGross:
+		LiabilitiesPolicy = options.Liabilities
+	default:
+		logger.Error("unknown liabilities policy", zap.String("liabilities", options.Liabilities))
+		return ExitConfig
+	}
+	dividendRates, err := ParseDividendTaxRates(options.DividendTax)
+	if err != nil {
+		logger.Error("invalid dividend tax rates", zap.Error(err))
+		return ExitConfig
+	}
+	if *traceAsset != "" {
+		options.CandleFilter.Only = []string{*traceAsset}
+	}
+	if *explain != "" {
+		ExplainTime, err = ParseExplainTime(*explain)
+		if err != nil {
+			logger.Error("invalid time to explain", zap.Error(err))
+			return ExitConfig
+		}
+	}
+	if options.TradingCalendar.SessionsOnly && options.TradingCalendar.Exchange == "" {
+		logger.Error("set the exchange of the trading calendar to search the maximum within its sessions")
+		return ExitConfig
+	}
+	switch options.CandleSource {
+	case "":
+	case CandleSourceWeekend, CandleSourceExchange:
+		CandleSource = options.CandleSource
+	default:
+		logger.Error("unknown candle source", zap.String("source", options.CandleSource))
+		return ExitConfig
+	}
+	if options.PeakBand != "" {
+		band, ok := (&big.Rat{}).SetString(options.PeakBand)
+		if !ok || band.Sign() <= 0 || band.Cmp(big.NewRat(100, 1)) > 0 {
+			logger.Error("invalid peak band, percent of the maximum expected", zap.String("band", options.PeakBand))
+			return ExitConfig
+		}
+		PeakBand = band.Quo(band, big.NewRat(100, 1))
+	}
+	switch options.Rounding {
+	case "":
+	case RoundHalfUp, RoundHalfEven, RoundUp:
+		Rounding = options.Rounding
+	default:
+		logger.Error("unknown rounding policy", zap.String("rounding", options.Rounding))
+		return ExitConfig
+	}
+	if *maxima != "" {
+		_, err := PeriodMaxima(nil, *maxima)
+		if err != nil {
+			logger.Error("invalid maxima period", zap.Error(err))
+			return ExitConfig
+		}
+	}
+	if *schedule != "" {
+		parsed, err := ParseSchedule(*schedule)
+		if err != nil {
+			logger.Error("invalid schedule", zap.Error(err))
+			return ExitConfig
+		}
+		return RunScheduled(logger, parsed, HistoryFile(options))
+	}
+	if options.OTLPEndpoint == "" {
+		options.OTLPEndpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+	if options.OTLPEndpoint != "" {
+		telemetry = NewTelemetry(options.OTLPEndpoint)
+		span := StartSpan("run", StringAttribute("command", command), StringAttribute("profile", *profile))
+		defer func() {
+			span.SetAttributes(IntAttribute("exit_code", code))
+			if code != ExitSuccess {
+				span.Fail(fmt.Sprintf("exit code %d", code))
+			}
+			span.End()
+			telemetry.Flush(logger)
+		}()
+	}
+
+	if options.Database != "" {
+		store, err = storage.Open(options.Database)
+		if err != nil {
+			logger.Error("error opening database",
+				zap.String("file", options.Database), zap.Error(err))
+			return ExitConfig
+		}
+		defer store.Close()
+	}
+
+	var thresholdValue *big.Rat
+	if *threshold != "" {
+		var ok bool
+		thresholdValue, ok = (&big.Rat{}).SetString(*threshold)
+		if !ok {
+			logger.Error("invalid threshold", zap.String("threshold", *threshold))
+			return ExitConfig
+		}
+	}
 
-	config, err := investgo.LoadConfig("config.yaml")
+	if *sandbox || options.Sandbox || command == "demo" {
+		config.EndPoint = SandboxEndPoint
+	}
+	// without a proxy in the config gRPC dials by itself, using HTTPS_PROXY if it is set
+	dialer, err := NewDialer(options.Proxy)
+	if err != nil {
+		logger.Error(T("proxy must be an HTTP CONNECT or SOCKS5 proxy URL, e.g. socks5://host:1080"),
+			zap.String("proxy", options.Proxy), zap.Error(err))
+		return ExitConfig
+	}
+	keepalive, err := options.Keepalive.Parameters()
 	if err != nil {
-		logger.Fatal("error loading config", zap.Error(err))
+		logger.Error("invalid keepalive", zap.Error(err))
+		return ExitConfig
 	}
+	UseDialOptions(dialer, keepalive)
 
 	logger.Debug("creating client")
 	client, err := investgo.NewClient(context.Background(), config, logger.Sugar())
 	if err != nil {
-		logger.Fatal("error creating client", zap.Error(err))
+		logger.Error("error creating client", zap.Error(err))
+		return ExitConfig
 	}
 	defer func() {
 		logger.Debug("closing client")
@@ -241,18 +661,57 @@ func main() {
 		}
 	}()
 
-	if config.AccountId == "" {
-		logger.Info("cannot proceed without account set in config, getting accounts")
-		resp, err := client.NewUsersServiceClient().GetAccounts(nil)
+	err = CheckToken(client, logger)
+	if err != nil {
+		return ExitCode(err)
+	}
+
+	accountIds := options.AccountIds
+	if len(accountIds) == 0 && config.AccountId != "" {
+		accountIds = []string{config.AccountId}
+	}
+	if command == "demo" {
+		accountId, err := CreateDemoAccount(client, logger)
 		if err != nil {
-			logger.Error("error getting accounts", zap.Error(err))
-			return
+			return ExitCode(err)
 		}
+		accountIds = []string{accountId}
+	}
+
+	logger.Debug("getting accounts")
+	start := time.Now()
+	resp, err := client.NewUsersServiceClient().GetAccounts(nil)
+	TraceCall("GetAccounts", nil, start, resp, err)
+	if err != nil {
+		logger.Error("error getting accounts", zap.Error(err))
+		return ExitCode(err)
+	}
+	accounts := make(map[string]AccountInfo, len(resp.Accounts))
+	for _, account := range resp.Accounts {
+		accounts[account.Id] = NewAccountInfo(account)
+	}
+	if options.AllAccounts && command != "demo" {
+		accountIds = AllAccountIds(logger, resp.Accounts)
+	}
+	accountIds = WithGroupAccounts(accountIds, options.Groups)
+	if len(accountIds) == 0 {
+		logger.Info("cannot proceed without account set in config")
 		for _, account := range resp.Accounts {
 			logger.Info("found account", zap.String("id", account.Id), zap.String("name", account.Name))
 		}
-		logger.Error("set one of these accounts as AccountId in config.yaml")
-		return
+		logger.Error(T("set one of these accounts as AccountId or several as AccountIds in config.yaml"))
+		return ExitConfig
+	}
+
+	err = CheckAccountAccess(client, logger, resp.Accounts, accountIds)
+	if err != nil {
+		return ExitCode(err)
+	}
+	if config.EndPoint != SandboxEndPoint {
+		err = CheckReadOnly(logger, resp.Accounts, accountIds, options.RequireReadOnly)
+		if err != nil {
+			return ExitCode(err)
+		}
 	}
 
 	in := client.NewInstrumentsServiceClient()
@@ -260,179 +719,407 @@ func main() {
 	currencyInstruments, err := getCurrencyInstruments(in)
 	if err != nil {
 		logger.Error("error getting currency instruments", zap.Error(err))
-		return
+		return ExitCode(err)
 	}
 
-	op := client.NewOperationsServiceClient()
-	logger.Debug("getting portfolio")
-	now := time.Now()
-	positions, err := op.GetPortfolio(config.AccountId, pb.PortfolioRequest_RUB)
-	if err != nil {
-		logger.Error("error getting portfolio", zap.Error(err))
-		return
-	}
-
-	portfolio := make(map[string]*big.Rat, len(positions.Positions))
-	prices := make(map[string]*big.Rat, len(positions.Positions))
-	currencies := make(map[string]string, len(positions.Positions))
-	logger.Debug("processing portfolio positions")
-	for _, position := range positions.Positions {
-		var key string
-		if currency, ok := currencyInstruments[position.PositionUid]; ok {
-			key = currency
-		} else {
-			var err error
-			key, err = getAssetUid(in, logger, position.InstrumentUid)
+	if command == "check" {
+		if !CheckOptions(logger, options) {
+			return ExitConfig
+		}
+		op := client.NewOperationsServiceClient()
+		now := time.Now()
+		results := make([]CheckResult, 0, len(accountIds))
+		for _, accountId := range accountIds {
+			account, ok := accounts[accountId]
+			if !ok {
+				account = AccountInfo{Id: accountId}
+			}
+			result, err := CheckAccount(op, logger, currencyInstruments, account, now)
 			if err != nil {
-				logger.Error("error getting instrument for position",
-					zap.String("position", position.Figi),
-					zap.Error(err))
-				return
+				return ExitCode(err)
 			}
-			prices[key] = ToRat(position.CurrentPrice)
-			currencies[key] = position.CurrentPrice.Currency
-		}
-		portfolio[key] = AddRat(portfolio[key], ToRat(position.Quantity))
-	}
-	cost := maps.Clone(portfolio)
-	SellAll(cost, prices, currencies)
-	logger.Info("current portfolio",
-		zap.Any("portfolio", ToTickers(portfolio)),
-		zap.Any("cost", cost),
-		zap.Stringer("aggregate", Aggregate(cost)))
-
-	req := &investgo.GetOperationsByCursorRequest{
-		AccountId: config.AccountId,
-		From:      time.Date(TaxYear, 1, 1, 0, 0, 0, 0, time.UTC),
-		To:        now,
-		State:     pb.OperationState_OPERATION_STATE_EXECUTED,
-	}
-	logger.Debug("getting operations")
-	for {
-		operations, err := op.GetOperationsByCursor(req)
+			results = append(results, result)
+		}
+		err = PrintCheck(os.Stdout, resp.Accounts, results)
+		if err != nil {
+			logger.Error("error printing check", zap.Error(err))
+			return ExitFailure
+		}
+		return ExitSuccess
+	}
+
+	if *auditOperations {
+		states, err := ParseOperationStates(*auditStates)
 		if err != nil {
-			logger.Error("error getting operations",
-				zap.Any("request", req),
-				zap.Error(err))
-			return
-		}
-		for _, operation := range operations.Items {
-			if _, ok := tickers[operation.AssetUid]; !ok {
-				_, err = getAssetUid(in, logger, operation.InstrumentUid)
-				if err != nil {
-					logger.Error("error getting instrument for operation",
-						zap.String("figi", operation.Figi),
-						zap.String("name", operation.Name),
-						zap.String("description", operation.Description),
-						zap.Error(err))
-					return
-				}
+			logger.Error("invalid audit states", zap.String("states", *auditStates), zap.Error(err))
+			return ExitConfig
+		}
+		op := client.NewOperationsServiceClient()
+		for _, accountId := range accountIds {
+			audit, err := AuditOperations(op, logger, accountId,
+				time.Date(TaxYear, 1, 1, 0, 0, 0, 0, Location),
+				time.Date(TaxYear+1, 1, 1, 0, 0, 0, 0, Location), states)
+			if err != nil {
+				logger.Error("error auditing operations", zap.Error(err))
+				return ExitCode(err)
 			}
-			if operation.AssetUid != "" {
-				assets[operation.InstrumentUid] = operation.AssetUid
+			fmt.Printf(T("Account %s\n"), Redact("account", accountId))
+			err = audit.Print(os.Stdout)
+			if err != nil {
+				logger.Error("error printing audit", zap.Error(err))
+				return ExitCode(err)
 			}
-			update, err := OperationToUpdate(operation)
+		}
+		return ExitSuccess
+	}
+
+	if options.TradingCalendar.Exchange != "" {
+		tradingCalendar, err = LoadTradingCalendar(in, logger, options.TradingCalendar)
+		if err != nil {
+			return ExitCode(err)
+		}
+	}
+
+	if *pointsFile != "" {
+		points, err = OpenPointStream(*pointsFile, *pointsBreakdown)
+		if err != nil {
+			logger.Error("error opening points stream", zap.String("file", *pointsFile), zap.Error(err))
+			return ExitCode(err)
+		}
+		defer func() {
+			err := points.Close()
 			if err != nil {
-				logger.Error("cannot process operation",
-					zap.Error(err),
-					zap.Any("operation", operation))
-				return
+				logger.Error("error closing points stream", zap.Error(err))
 			}
-			date := operation.Date.AsTime()
-			updates[date] = append(updates[date], update)
-		}
-		if !operations.HasNext {
-			break
-		}
-		req.Cursor = operations.NextCursor
-		logger.Debug("getting operations", zap.Time("last_processed", operations.Items[len(operations.Items)-1].Date.AsTime()))
-	}
-	logger.Info("instruments", zap.Any("assets", assets), zap.Any("tickers", tickers))
-
-	md := client.NewMarketDataServiceClient()
-	for instrumentUid, assetUid := range assets {
-		logger.Debug("getting candles",
-			zap.String("instrument", instrumentUid),
-			zap.String("asset", assetUid),
-			zap.String("ticker", tickers[assetUid]))
-		candles, err := md.GetHistoricCandles(&investgo.GetHistoricCandlesRequest{
-			Instrument: instrumentUid,
-			Interval:   pb.CandleInterval_CANDLE_INTERVAL_HOUR,
-			From:       time.Date(TaxYear, 1, 1, 0, 0, 0, 0, time.UTC),
-			// There are some issues with future prices reuse as we are going backwards in time,
-			// so it works better to have some extra data on the border to get the best possible approximation.
-			To:     time.Date(TaxYear+1, 2, 1, 0, 0, 0, 0, time.UTC),
-			Source: pb.GetCandlesRequest_CANDLE_SOURCE_INCLUDE_WEEKEND,
-		})
+		}()
+	}
+
+	evaluations, err := evaluateAccounts(client, logger, options, currencyInstruments, accounts, accountIds, command)
+	if err != nil {
+		return ExitCode(err)
+	}
+	for _, person := range options.Persons {
+		personClient, personEvaluations, err := EvaluatePerson(config, logger, options, currencyInstruments, person,
+			command)
 		if err != nil {
-			if status.Code(err) == codes.NotFound {
-				logger.Warn("cannot find candles for instrument",
-					zap.String("instrument", instrumentUid),
-					zap.String("asset", assetUid),
-					zap.String("ticker", tickers[assetUid]))
-				continue
+			return ExitCode(err)
+		}
+		defer func() {
+			err := personClient.Stop()
+			if err != nil {
+				logger.Error("error closing client", zap.String("person", person.Name), zap.Error(err))
 			}
-			logger.Error("error getting candles for instrument",
-				zap.String("instrument", instrumentUid),
-				zap.String("asset", assetUid),
-				zap.String("ticker", tickers[assetUid]),
-				zap.Error(err))
-			return
-		}
-		logger.Debug("processing candles",
-			zap.String("instrument", instrumentUid),
-			zap.String("asset", assetUid),
-			zap.String("ticker", tickers[assetUid]))
-		asset := assetUid
-		inst := instrumentUid
-		for _, candle := range candles {
-			date := candle.Time.AsTime()
-			price := ToRat(candle.High)
-			updates[date] = append(updates[date], func(_, prices map[string]*big.Rat, currencies map[string]string) {
-				prices[asset] = price
-				currencies[asset] = instrumentCurrencies[inst]
-			})
-		}
-	}
-
-	var bestPortfolio, bestCost, bestPrices map[string]*big.Rat
-	var bestTime time.Time
-	bestAggregate := &big.Rat{}
-
-	logger.Info("going back in time", zap.Uint("tax_year", TaxYear))
-	times := slices.SortedFunc(maps.Keys(updates), func(a, b time.Time) int {
-		return b.Compare(a)
-	})
-	for _, date := range times {
-		portfolio = maps.Clone(portfolio)
-		prices = maps.Clone(prices)
-		for _, update := range updates[date] {
-			update(portfolio, prices, currencies)
-		}
-		cost := maps.Clone(portfolio)
-		SellAll(cost, prices, currencies)
-		aggregate := Aggregate(cost)
-		logger.Debug("new portfolio",
-			zap.Time("time", date),
-			zap.Any("portfolio", ToTickers(portfolio)),
-			zap.Any("cost", cost),
-			zap.Stringer("aggregate", aggregate))
-		if date.Year() != TaxYear {
+		}()
+		evaluations = append(evaluations, personEvaluations...)
+	}
+	if len(evaluations) == 0 {
+		logger.Error("no account existed during the tax year", zap.Uint("tax_year", TaxYear))
+		return ExitConfig
+	}
+	options.Groups = PersonGroups(options, evaluations)
+	if *ledgerFile != "" {
+		err := WriteLedger(*ledgerFile, Ledger(evaluations))
+		if err != nil {
+			logger.Error("error writing ledger", zap.String("file", *ledgerFile), zap.Error(err))
+			return ExitCode(err)
+		}
+	}
+	if *flexFile != "" {
+		err := WriteFlex(*flexFile, Flex(evaluations))
+		if err != nil {
+			logger.Error("error writing Flex export", zap.String("file", *flexFile), zap.Error(err))
+			return ExitCode(err)
+		}
+	}
+	if *portfolioPerformanceFile != "" {
+		err := WritePortfolioPerformance(*portfolioPerformanceFile, evaluations)
+		if err != nil {
+			logger.Error("error writing Portfolio Performance export",
+				zap.String("file", *portfolioPerformanceFile), zap.Error(err))
+			return ExitCode(err)
+		}
+	}
+	if *ghostfolioFile != "" {
+		err := WriteGhostfolio(*ghostfolioFile, evaluations)
+		if err != nil {
+			logger.Error("error writing Ghostfolio export", zap.String("file", *ghostfolioFile), zap.Error(err))
+			return ExitCode(err)
+		}
+	}
+	if *plainTextFile != "" {
+		err := WritePlainText(*plainTextFile, evaluations)
+		if err != nil {
+			logger.Error("error writing plain-text accounting export", zap.String("file", *plainTextFile), zap.Error(err))
+			return ExitCode(err)
+		}
+	}
+	if *ndflFile != "" {
+		var entries []NDFLEntry
+		op := client.NewOperationsServiceClient()
+		for _, accountId := range accountIds {
+			accountEntries, err := NDFLDividends(op, logger, accountId)
+			if err != nil {
+				return ExitCode(err)
+			}
+			entries = append(entries, accountEntries...)
+		}
+		err := WriteNDFL(*ndflFile, entries)
+		if err != nil {
+			logger.Error("error writing 3-NDFL dividends", zap.String("file", *ndflFile), zap.Error(err))
+			return ExitCode(err)
+		}
+	}
+	if *lotsFile != "" {
+		var lots []AccountLots
+		now := time.Now()
+		for _, evaluation := range evaluations {
+			op := evaluation.client.NewOperationsServiceClient()
+			accountLots, err := GetLots(op, logger, evaluation.Account, now)
+			if err != nil {
+				return ExitCode(err)
+			}
+			logger.Info("taxable gain of the lots sold during the tax year",
+				zap.String("account", evaluation.AccountId),
+				zap.String("gain_rub", accountLots.TaxableGainRUB().FloatString(2)))
+			for _, sale := range accountLots.WashSales() {
+				logger.Warn("sale at a loss with a repurchase within 30 days",
+					zap.String("account", evaluation.AccountId),
+					zap.String("asset", sale.Asset),
+					zap.Time("sold", sale.SellDate),
+					zap.Timep("repurchased", sale.Repurchase),
+					zap.String("loss_usd", sale.GainUSD().FloatString(2)))
+			}
+			lots = append(lots, accountLots)
+		}
+		err := WriteLots(*lotsFile, lots)
+		if err != nil {
+			logger.Error("error writing lots", zap.String("file", *lotsFile), zap.Error(err))
+			return ExitCode(err)
+		}
+	}
+	span := StartSpan("reports")
+	defer span.End()
+	provenance := NewProvenance(config.EndPoint)
+	for _, evaluation := range evaluations {
+		err := PrintBreakdowns(in, logger, evaluation)
+		if err != nil {
+			return ExitCode(err)
+		}
+	}
+	err = provenance.Print(os.Stdout)
+	if err != nil {
+		logger.Error("error printing provenance", zap.Error(err))
+		return ExitCode(err)
+	}
+	now := time.Now()
+	for _, evaluation := range evaluations {
+		reportReturns(logger, evaluation, now)
+	}
+	if *traceAsset != "" {
+		for _, evaluation := range evaluations {
+			fmt.Printf(T("Account %s asset %s\n"), Redact("account", evaluation.AccountId), *traceAsset)
+			err = PrintAssetTrace(os.Stdout, evaluation)
+			if err != nil {
+				logger.Error("error printing asset trace", zap.Error(err))
+				return ExitCode(err)
+			}
+		}
+	}
+	if *explain != "" {
+		for _, evaluation := range evaluations {
+			fmt.Printf(T("Account %s at %s\n"), Redact("account", evaluation.AccountId),
+				ExplainTime.In(Location).Format(time.DateTime))
+			err = PrintExplanation(os.Stdout, evaluation)
+			if err != nil {
+				logger.Error("error printing explanation", zap.Error(err))
+				return ExitCode(err)
+			}
+		}
+	}
+	if *attribution {
+		for _, evaluation := range evaluations {
+			end := evaluation.Months[12]
+			if end == nil {
+				end = evaluation.CurrentState
+			}
+			fmt.Printf(T("Account %s yearly result by asset\n"), Redact("account", evaluation.AccountId))
+			err = PrintAttribution(os.Stdout, AttributeResults(evaluation.Operations, evaluation.Months[0], end))
+			if err != nil {
+				logger.Error("error printing attribution", zap.Error(err))
+				return ExitCode(err)
+			}
+		}
+	}
+	if *dividendTax {
+		for _, evaluation := range evaluations {
+			fmt.Printf(T("Account %s foreign dividend withholding\n"), Redact("account", evaluation.AccountId))
+			err = PrintWithholding(os.Stdout,
+				SummarizeWithholding(logger, ForeignDividends(evaluation.Operations), dividendRates))
+			if err != nil {
+				logger.Error("error printing dividend withholding", zap.Error(err))
+				return ExitCode(err)
+			}
+		}
+	}
+	historyFile := HistoryFile(options)
+	history, err := LoadHistory(historyFile)
+	if err != nil {
+		logger.Error("error loading run history", zap.String("file", historyFile), zap.Error(err))
+		return ExitCode(err)
+	}
+	record := NewRunRecord(time.Now(), evaluations)
+	if *diffPrevious {
+		if len(history) == 0 {
+			logger.Warn("no previous run to compare with")
+		} else {
+			ExplainChanges(logger, history[len(history)-1], record)
+		}
+	}
+	err = SaveHistory(historyFile, append(history, record))
+	if err != nil {
+		logger.Error("error saving run history", zap.String("file", historyFile), zap.Error(err))
+		return ExitCode(err)
+	}
+	err = ArchiveRun(logger, evaluations)
+	if err != nil {
+		return ExitCode(err)
+	}
+
+	summary := NewSummary(evaluations, provenance)
+	maximum := evaluations[0].BestAggregate
+	if len(evaluations) > 1 && (len(options.Persons) == 0 || options.Household) {
+		combined := Combine(evaluations)
+		best := combined.Best()
+		maximum = best.Aggregate
+		summary.Combined = &CombinedSummary{
+			BestTime:   best.Time,
+			Best:       NewAmount(best.Aggregate, "usd"),
+			Thresholds: reportCombined(logger, options, evaluations, combined),
+		}
+		fmt.Print(T("Combined first threshold crossings\n"))
+		err = PrintThresholds(os.Stdout, summary.Combined.Thresholds)
+		if err != nil {
+			logger.Error("error printing thresholds", zap.Error(err))
+			return ExitCode(err)
+		}
+	}
+	summary.Groups = CombineGroups(logger, options.Groups, evaluations)
+	if len(options.Persons) > 0 && !options.Household {
+		if personsMaximum := PersonsMaximum(options, summary.Groups); personsMaximum != nil {
+			maximum = personsMaximum
+		}
+	}
+	err = PrintGroups(os.Stdout, summary.Groups, evaluations)
+	if err != nil {
+		logger.Error("error printing groups", zap.Error(err))
+		return ExitCode(err)
+	}
+	if *fbarFile != "" {
+		err := WriteJSON(*fbarFile, NewFBARReport(evaluations, provenance))
+		if err != nil {
+			logger.Error("error writing FBAR report", zap.String("file", *fbarFile), zap.Error(err))
+			return ExitCode(err)
+		}
+	}
+	if *summaryFile != "" {
+		err := WriteJSON(*summaryFile, summary.Redacted())
+		if err != nil {
+			logger.Error("error writing summary", zap.String("file", *summaryFile), zap.Error(err))
+			return ExitCode(err)
+		}
+	}
+	report := NewReport(summary, time.Now())
+	if *pdfFile != "" {
+		err := WritePDF(*pdfFile, report)
+		if err != nil {
+			logger.Error("error writing PDF report", zap.String("file", *pdfFile), zap.Error(err))
+			return ExitCode(err)
+		}
+	}
+	if *email || scheduledNotify("email", history, record) {
+		logger.Debug("emailing report", zap.Strings("to", options.SMTP.To))
+		err := EmailReport(options.SMTP, report, summary)
+		if err != nil {
+			logger.Error("error emailing report", zap.String("host", options.SMTP.Host), zap.Error(err))
+			return ExitFailure
+		}
+	}
+	if *sheets || scheduledNotify("sheets", history, record) {
+		logger.Debug("publishing to Google Sheets", zap.String("spreadsheet", options.GoogleSheets.SpreadsheetId))
+		err := PublishSheets(options.GoogleSheets, evaluations, summary)
+		if err != nil {
+			logger.Error("error publishing to Google Sheets", zap.Error(err))
+			return ExitFailure
+		}
+	}
+	err = ClearCheckpoint()
+	if err != nil {
+		logger.Warn("error removing checkpoint", zap.Error(err))
+	}
+	code = ResultCode(evaluations, maximum, thresholdValue)
+	if *scheduledNotifyList == "" {
+		FireWebhooks(logger, options.Webhooks, NewWebhookEvent(WebhookCompleted, record, code))
+	} else if scheduledNotify("webhooks", history, record) {
+		FireWebhooks(logger, options.Webhooks, NewWebhookEvent(WebhookCompleted, record, code))
+		FireWebhooks(logger, options.Webhooks, NewWebhookEvent(WebhookMaximum, record, code))
+	}
+	return code
+}
+
+// evaluateAccounts evaluates the accounts of the token, skipping the ones closed before the tax year
+func evaluateAccounts(client *investgo.Client, logger *zap.Logger, options Options, currencyInstruments map[string]string,
+	accounts map[string]AccountInfo, accountIds []string, command string) ([]*Evaluation, error) {
+	evaluations := make([]*Evaluation, 0, len(accountIds))
+	for _, accountId := range accountIds {
+		account, ok := accounts[accountId]
+		if !ok {
+			logger.Warn("account is not found in the accounts list", zap.String("account", accountId))
+			account = AccountInfo{Id: accountId}
+		}
+		if !account.End().After(time.Date(TaxYear, 1, 1, 0, 0, 0, 0, Location)) {
+			logger.Warn("account was closed before the tax year, skipping it",
+				zap.String("account", accountId), zap.Timep("closed", account.ClosedDate))
 			continue
 		}
-		if bestAggregate.Cmp(aggregate) < 0 {
-			bestPortfolio = portfolio
-			bestPrices = prices
-			bestCost = cost
-			bestTime = date
-			bestAggregate = aggregate
-			logger.Debug("new best shown above")
-		}
-	}
-	logger.Info("best portfolio",
-		zap.Time("time", bestTime),
-		zap.Any("portfolio", ToTickers(bestPortfolio)),
-		zap.Any("prices", ToTickers(bestPrices)),
-		zap.Any("cost", bestCost),
-		zap.Stringer("aggregate", bestAggregate))
+		if account.ClosedInTaxYear() {
+			logger.Info("account was closed during the tax year, it is evaluated until the closure",
+				zap.String("account", accountId), zap.Timep("closed", account.ClosedDate))
+		}
+		if account.IsIIS() {
+			account.IISType = options.IISTypes[accountId]
+			switch account.IISType {
+			case "A", "B", "3":
+			case "":
+				logger.Warn("IIS type is unknown, set it in IISTypes", zap.String("account", accountId))
+			default:
+				logger.Warn("invalid IIS type", zap.String("account", accountId), zap.String("type", account.IISType))
+			}
+		}
+		evaluation, err := evaluate(client, logger, options, currencyInstruments, account, command)
+		if err != nil {
+			return nil, err
+		}
+		evaluations = append(evaluations, evaluation)
+	}
+	return evaluations, nil
+}
+
+func reportCombined(logger *zap.Logger, options Options, evaluations []*Evaluation, combined Timeline) []ThresholdCrossing {
+	best := combined.Best()
+	logger.Info("best combined value",
+		zap.Time("time", best.Time),
+		zap.String("aggregate", FormatUSD(best.Aggregate)))
+	for _, evaluation := range evaluations {
+		logger.Info("account value at best combined time",
+			zap.String("account", evaluation.AccountId),
+			zap.String("aggregate", FormatUSD(evaluation.ValueAt(best.Time))),
+			zap.String("account_best", FormatUSD(evaluation.BestAggregate)),
+			zap.Time("account_best_time", evaluation.BestTime))
+	}
+	thresholds := NewThresholdTracker(logger, options.Thresholds)
+	for _, point := range slices.Backward(combined) {
+		thresholds.Observe(point.Time, point.Aggregate)
+	}
+	thresholds.Report(logger)
+	return thresholds.Crossings()
 }
@@ -0,0 +1,226 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"container/heap"
+	"maps"
+	"math/big"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// PriceSeries is the candles of an asset in ascending time order, they are turned into price updates
+// only while going back in time instead of being indexed all at once
+type PriceSeries struct {
+	Asset string
+	// the instrument the candles are of
+	Instrument string
+	Currency   string
+	// bond prices are quoted in percent of the nominal
+	Nominal *big.Rat
+	// nominals of an amortizing bond, the prices before an amortization are of the larger nominal
+	Nominals NominalHistory
+	// trades in other currencies than the current one, e.g. before a redenomination
+	History CurrencyHistory
+	Candles []*pb.HistoricCandle
+	// close prices instead of the highs
+	Close bool
+	// gaps longer than this are valued at the lower of the prices around them instead of carrying
+	// the last price forward, no limit if zero
+	CarryLimit time.Duration
+}
+
+// NewPriceSeries logs the stale price intervals of the candles and updates the latest price of the asset
+func NewPriceSeries(logger *zap.Logger, latest map[string]LatestPrice, asset, currency string,
+	nominal *big.Rat, nominals NominalHistory, history CurrencyHistory, candles []*pb.HistoricCandle,
+	staleGap time.Duration) *PriceSeries {
+	series := &PriceSeries{Asset: asset, Currency: currency, Nominal: nominal, Nominals: nominals, History: history,
+		Candles: candles}
+	for i := 1; i < len(candles); i++ {
+		previousDate, date := candles[i-1].Time.AsTime(), candles[i].Time.AsTime()
+		if date.Sub(previousDate) >= staleGap {
+			logger.Info("stale price interval",
+				zap.String("ticker", tickers[asset]),
+				zap.Time("from", previousDate.In(Location)),
+				zap.Time("to", date.In(Location)),
+				zap.Stringer("price", series.price(i-1)))
+		}
+	}
+	if len(candles) > 0 {
+		last := len(candles) - 1
+		if date := candles[last].Time.AsTime(); date.After(latest[asset].Time) {
+			latest[asset] = LatestPrice{Time: date, Price: series.price(last), Currency: series.currency(last)}
+		}
+	}
+	return series
+}
+
+func (s *PriceSeries) price(i int) *big.Rat {
+	quotation := s.Candles[i].High
+	// the fallback close prices have the high only
+	if s.Close && s.Candles[i].Close != nil {
+		quotation = s.Candles[i].Close
+	}
+	price := ToRat(quotation)
+	if s.Nominal != nil {
+		price = BondPrice(price, s.Nominals.At(s.Candles[i].Time.AsTime(), s.Nominal))
+	}
+	return price
+}
+
+// currency returns the currency the candle is quoted in
+func (s *PriceSeries) currency(i int) string {
+	if s.History == nil {
+		return s.Currency
+	}
+	return s.History.At(s.Candles[i].Time.AsTime(), s.Currency)
+}
+
+// gapBefore tells whether there is a gap before the candle. Going back in time, the price after a gap
+// would be used during it, so the last known price before the gap is carried forward instead.
+func (s *PriceSeries) gapBefore(i int) bool {
+	return i > 0 && s.Candles[i].Time.AsTime().Sub(s.Candles[i-1].Time.AsTime()) > time.Hour
+}
+
+// beyondCarryLimit tells whether the gap before the candle is too long to carry the last price forward
+func (s *PriceSeries) beyondCarryLimit(i int) bool {
+	return s.CarryLimit > 0 && s.Candles[i].Time.AsTime().Sub(s.Candles[i-1].Time.AsTime()) > s.CarryLimit
+}
+
+// seriesCursor is the position in a price series going back in time
+type seriesCursor struct {
+	series *PriceSeries
+	index  int
+	// the carried forward price is set just before the candle at the index
+	gap bool
+}
+
+func (c *seriesCursor) time() time.Time {
+	date := c.series.Candles[c.index].Time.AsTime()
+	if c.gap {
+		return date.Add(-time.Nanosecond)
+	}
+	return date
+}
+
+func (c *seriesCursor) apply(state *State) {
+	index := c.index
+	if c.gap {
+		index--
+	}
+	price := c.series.price(index)
+	if c.gap && c.series.beyondCarryLimit(c.index) {
+		if next := c.series.price(c.index); next.Cmp(price) < 0 {
+			price = next
+		}
+	}
+	state.Prices[c.series.Asset] = price
+	state.Currencies[c.series.Asset] = c.series.currency(index)
+}
+
+// advance moves the cursor to the previous price, it returns false at the start of the series
+func (c *seriesCursor) advance() bool {
+	if !c.gap && c.series.gapBefore(c.index) {
+		c.gap = true
+		return true
+	}
+	c.gap = false
+	c.index--
+	return c.index >= 0
+}
+
+// cursorHeap keeps the cursor with the latest time on top
+type cursorHeap []seriesCursor
+
+func (h cursorHeap) Len() int           { return len(h) }
+func (h cursorHeap) Less(i, j int) bool { return h[i].time().After(h[j].time()) }
+func (h cursorHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *cursorHeap) Push(x any)        { *h = append(*h, x.(seriesCursor)) }
+func (h *cursorHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// UpdateStream merges the indexed updates with the price series by time going back in time
+type UpdateStream struct {
+	updates map[time.Time][]Update
+	// the times of the indexed updates in descending order
+	times   []time.Time
+	next    int
+	cursors cursorHeap
+}
+
+func NewUpdateStream(updates map[time.Time][]Update, series []*PriceSeries) *UpdateStream {
+	times := slices.SortedFunc(maps.Keys(updates), func(a, b time.Time) int {
+		return b.Compare(a)
+	})
+	stream := &UpdateStream{updates: updates, times: times}
+	for _, s := range series {
+		if len(s.Candles) > 0 {
+			stream.cursors = append(stream.cursors, seriesCursor{series: s, index: len(s.Candles) - 1})
+		}
+	}
+	heap.Init(&stream.cursors)
+	return stream
+}
+
+// Done tells whether all updates have been applied
+func (s *UpdateStream) Done() bool {
+	return s.next == len(s.times) && len(s.cursors) == 0
+}
+
+// Next applies all updates at the next time to the state and returns the time
+func (s *UpdateStream) Next(state *State) (time.Time, bool) {
+	if s.Done() {
+		return time.Time{}, false
+	}
+	var date time.Time
+	if s.next < len(s.times) {
+		date = s.times[s.next]
+	}
+	if len(s.cursors) > 0 && s.cursors[0].time().After(date) {
+		date = s.cursors[0].time()
+	}
+	if s.next < len(s.times) && s.times[s.next].Equal(date) {
+		for _, update := range s.updates[s.times[s.next]] {
+			update(state)
+		}
+		s.next++
+	}
+	for len(s.cursors) > 0 && s.cursors[0].time().Equal(date) {
+		s.cursors[0].apply(state)
+		if s.cursors[0].advance() {
+			heap.Fix(&s.cursors, 0)
+		} else {
+			heap.Pop(&s.cursors)
+		}
+	}
+	return date, true
+}
+
+// Clone copies the position of the stream, the updates and the series are shared
+func (s *UpdateStream) Clone() *UpdateStream {
+	clone := *s
+	clone.cursors = slices.Clone(s.cursors)
+	return &clone
+}
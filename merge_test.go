@@ -0,0 +1,113 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+func TestUpdateStream(t *testing.T) {
+	start := time.Date(TaxYear, 3, 2, 10, 0, 0, 0, time.UTC)
+	candle := func(hours, price int64) *pb.HistoricCandle {
+		return &pb.HistoricCandle{
+			Time: timestamppb.New(start.Add(time.Duration(hours) * time.Hour)),
+			High: &pb.Quotation{Units: price},
+		}
+	}
+	latest := make(map[string]LatestPrice)
+	series := []*PriceSeries{
+		NewPriceSeries(zap.NewNop(), latest, "a", "rub", nil, nil, nil,
+			[]*pb.HistoricCandle{candle(0, 10), candle(1, 11), candle(5, 15)}, defaultStaleGap),
+		NewPriceSeries(zap.NewNop(), latest, "b", "usd", big.NewRat(1000, 1), nil, nil,
+			[]*pb.HistoricCandle{candle(1, 98), candle(2, 99)}, defaultStaleGap),
+	}
+	deposit := start.Add(90 * time.Minute)
+	updates := map[time.Time][]Update{
+		deposit: {func(state *State) {
+			state.Portfolio["usd"] = SubRat(state.Portfolio["usd"], big.NewRat(100, 1))
+		}},
+	}
+	if got := latest["a"].Price; got.Cmp(big.NewRat(15, 1)) != 0 {
+		t.Errorf("latest price of a = %s, want 15", got)
+	}
+	if got := latest["b"].Price; got.Cmp(big.NewRat(990, 1)) != 0 {
+		t.Errorf("latest price of b = %s, want 990", got)
+	}
+
+	type step struct {
+		time time.Time
+		a, b string
+		usd  string
+	}
+	want := []step{
+		{time: start.Add(5 * time.Hour), a: "15", b: "", usd: "200"},
+		// the price of a before the gap is carried forward
+		{time: start.Add(5*time.Hour - time.Nanosecond), a: "11", b: "", usd: "200"},
+		{time: start.Add(2 * time.Hour), a: "11", b: "990", usd: "200"},
+		{time: deposit, a: "11", b: "990", usd: "100"},
+		{time: start.Add(1 * time.Hour), a: "11", b: "980", usd: "100"},
+		{time: start, a: "10", b: "980", usd: "100"},
+	}
+	state := &State{
+		Portfolio:  map[string]*big.Rat{"usd": big.NewRat(200, 1)},
+		Prices:     make(map[string]*big.Rat),
+		Accrued:    make(map[string]*big.Rat),
+		Currencies: make(map[string]string),
+	}
+	stream := NewUpdateStream(updates, series)
+	for i, step := range want {
+		if stream.Done() {
+			t.Fatalf("stream is done after %d steps, want %d", i, len(want))
+		}
+		// a copy of the stream continues from the same position
+		if i == 3 {
+			clone, cloned := stream.Clone(), state.Clone()
+			date, _ := clone.Next(cloned)
+			if !date.Equal(step.time) {
+				t.Errorf("cloned stream step %d time = %s, want %s", i, date, step.time)
+			}
+		}
+		date, ok := stream.Next(state)
+		if !ok || !date.Equal(step.time) {
+			t.Fatalf("step %d time = %s, want %s", i, date, step.time)
+		}
+		for asset, want := range map[string]string{"a": step.a, "b": step.b} {
+			got := ""
+			if price, ok := state.Prices[asset]; ok {
+				got = price.RatString()
+			}
+			if got != want {
+				t.Errorf("step %d price of %s = %q, want %q", i, asset, got, want)
+			}
+		}
+		if got := state.Portfolio["usd"].RatString(); got != step.usd {
+			t.Errorf("step %d usd = %s, want %s", i, got, step.usd)
+		}
+	}
+	if !stream.Done() {
+		t.Error("stream is not done after all steps")
+	}
+	if _, ok := stream.Next(state); ok {
+		t.Error("done stream returned a time")
+	}
+}
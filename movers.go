@@ -0,0 +1,158 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// MoversOptions configure the analysis of sharp changes of the aggregate value
+type MoversOptions struct {
+	// relative change between consecutive points, 0.1 by default
+	Threshold string `yaml:"Threshold"`
+	// number of assets reported, 5 by default
+	Count int `yaml:"Count"`
+}
+
+// Mover is the change of a single asset between consecutive points
+type Mover struct {
+	Asset          string
+	Change         *big.Rat
+	QuantityBefore *big.Rat
+	QuantityAfter  *big.Rat
+	PriceBefore    *big.Rat
+	PriceAfter     *big.Rat
+}
+
+// AssetValues returns the value of each asset in USD
+func AssetValues(state *State, excluded map[string]bool) map[string]*big.Rat {
+	values := make(map[string]*big.Rat, len(state.Portfolio))
+	for key, quantity := range state.Portfolio {
+		if excluded[key] || IsFutures(key) {
+			continue
+		}
+		if rate, ok := ExchangeRates[key]; ok {
+			values[key] = (&big.Rat{}).Quo(quantity, rate)
+			continue
+		}
+		price, ok := state.Prices[key]
+		if !ok {
+			continue
+		}
+		price = AddRat(price, state.Accrued[key])
+		value := (&big.Rat{}).Mul(price, quantity)
+		values[key] = value.Quo(value, ExchangeRates[state.Currencies[key]])
+	}
+	return values
+}
+
+// TopMovers returns assets with the largest absolute value changes
+func TopMovers(before, after *State, excluded map[string]bool, count int) []Mover {
+	valuesBefore := AssetValues(before, excluded)
+	valuesAfter := AssetValues(after, excluded)
+	var movers []Mover
+	for _, key := range diffRats(valuesBefore, valuesAfter) {
+		movers = append(movers, Mover{
+			Asset:          key,
+			Change:         SubRat(valuesAfter[key], valuesBefore[key]),
+			QuantityBefore: before.Portfolio[key],
+			QuantityAfter:  after.Portfolio[key],
+			PriceBefore:    before.Prices[key],
+			PriceAfter:     after.Prices[key],
+		})
+	}
+	slices.SortStableFunc(movers, func(a, b Mover) int {
+		return (&big.Rat{}).Abs(b.Change).Cmp((&big.Rat{}).Abs(a.Change))
+	})
+	if len(movers) > count {
+		movers = movers[:count]
+	}
+	return movers
+}
+
+// MoverTracker reports the assets causing sharp changes of the aggregate value
+type MoverTracker struct {
+	logger    *zap.Logger
+	excluded  map[string]bool
+	threshold *big.Rat
+	count     int
+	// the later point as states are observed in reverse order
+	next      *State
+	nextTime  time.Time
+	nextValue *big.Rat
+}
+
+func NewMoverTracker(logger *zap.Logger, options MoversOptions, excluded map[string]bool) *MoverTracker {
+	tracker := &MoverTracker{
+		logger:    logger,
+		excluded:  excluded,
+		threshold: big.NewRat(1, 10),
+		count:     5,
+	}
+	if options.Threshold != "" {
+		if threshold, ok := (&big.Rat{}).SetString(options.Threshold); ok {
+			tracker.threshold = threshold
+		} else {
+			logger.Warn("invalid movers threshold", zap.String("value", options.Threshold))
+		}
+	}
+	if options.Count > 0 {
+		tracker.count = options.Count
+	}
+	return tracker
+}
+
+// Observe is called in reverse order and compares the state with the later one
+func (t *MoverTracker) Observe(date time.Time, state *State, aggregate *big.Rat) {
+	next, nextTime, nextValue := t.next, t.nextTime, t.nextValue
+	t.next, t.nextTime, t.nextValue = state, date, aggregate
+	if next == nil || !InTaxYear(date) {
+		return
+	}
+	change := SubRat(nextValue, aggregate)
+	base := (&big.Rat{}).Abs(aggregate)
+	if abs := (&big.Rat{}).Abs(nextValue); abs.Cmp(base) > 0 {
+		base = abs
+	}
+	if base.Sign() == 0 || (&big.Rat{}).Quo((&big.Rat{}).Abs(change), base).Cmp(t.threshold) < 0 {
+		return
+	}
+	movers := TopMovers(state, next, t.excluded, t.count)
+	t.logger.Info("sharp change of aggregate value",
+		zap.Time("from", date),
+		zap.Time("to", nextTime),
+		zap.String("before", FormatUSD(aggregate)),
+		zap.String("after", FormatUSD(nextValue)),
+		zap.String("change", FormatUSD(change)))
+	for _, mover := range movers {
+		ticker := tickers[mover.Asset]
+		if ticker == "" {
+			ticker = mover.Asset
+		}
+		t.logger.Info("asset change",
+			zap.String("ticker", ticker),
+			zap.String("change", FormatUSD(mover.Change)),
+			zap.Stringer("quantity_before", AddRat(mover.QuantityBefore, nil)),
+			zap.Stringer("quantity_after", AddRat(mover.QuantityAfter, nil)),
+			zap.Stringer("price_before", AddRat(mover.PriceBefore, nil)),
+			zap.Stringer("price_after", AddRat(mover.PriceAfter, nil)))
+	}
+}
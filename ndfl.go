@@ -0,0 +1,129 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"math/big"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"opensource.tbank.ru/invest/invest-go/investgo"
+)
+
+// NDFLEntry is a foreign dividend payment with the amounts needed for the 3-NDFL declaration
+type NDFLEntry struct {
+	Account     string
+	PaymentDate time.Time
+	Security    string
+	Isin        string
+	Country     string
+	Quantity    int64
+	Currency    string
+	Gross       *big.Rat
+	Tax         *big.Rat
+	Rate        *big.Rat
+	GrossRUB    *big.Rat
+	TaxRUB      *big.Rat
+}
+
+// NDFLDividends lists foreign dividends paid during the tax year with the CBR rates on the payment dates
+func NDFLDividends(op *investgo.OperationsServiceClient, logger *zap.Logger, accountId string) ([]NDFLEntry, error) {
+	report, err := getDividendsForeignIssuerReport(op, logger, accountId,
+		time.Date(TaxYear, 1, 1, 0, 0, 0, 0, Location),
+		time.Date(TaxYear+1, 1, 1, 0, 0, 0, 0, Location))
+	if err != nil {
+		logger.Error("error getting foreign issuer dividends report", zap.Error(err))
+		return nil, err
+	}
+	entries := make([]NDFLEntry, 0, len(report))
+	for _, dividend := range report {
+		paymentDate := dividend.PaymentDate.AsTime().In(Location)
+		rate, err := CBRRate(dividend.Currency, paymentDate)
+		if err != nil {
+			logger.Error("error getting CBR rate",
+				zap.String("currency", dividend.Currency),
+				zap.Time("date", paymentDate),
+				zap.Error(err))
+			return nil, err
+		}
+		gross := ToRat(dividend.DividendGross)
+		tax := ToRat(dividend.Tax)
+		entries = append(entries, NDFLEntry{
+			Account:     accountId,
+			PaymentDate: paymentDate,
+			Security:    dividend.SecurityName,
+			Isin:        dividend.Isin,
+			Country:     dividend.IssuerCountry,
+			Quantity:    dividend.Quantity,
+			Currency:    dividend.Currency,
+			Gross:       gross,
+			Tax:         tax,
+			Rate:        rate,
+			GrossRUB:    (&big.Rat{}).Mul(gross, rate),
+			TaxRUB:      (&big.Rat{}).Mul(tax, rate),
+		})
+	}
+	slices.SortStableFunc(entries, func(a, b NDFLEntry) int {
+		return cmp.Or(a.PaymentDate.Compare(b.PaymentDate), cmp.Compare(a.Isin, b.Isin))
+	})
+	return entries, nil
+}
+
+// WriteNDFL writes the foreign dividends as CSV
+func WriteNDFL(filename string, entries []NDFLEntry) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := csv.NewWriter(file)
+	err = w.Write([]string{"account", "payment_date", "security", "isin", "country", "quantity", "currency",
+		"gross", "tax", "cbr_rate", "gross_rub", "tax_rub"})
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		err = w.Write([]string{
+			entry.Account,
+			entry.PaymentDate.Format(time.DateOnly),
+			entry.Security,
+			entry.Isin,
+			entry.Country,
+			strconv.FormatInt(entry.Quantity, 10),
+			entry.Currency,
+			entry.Gross.FloatString(2),
+			entry.Tax.FloatString(2),
+			entry.Rate.FloatString(4),
+			entry.GrossRUB.FloatString(2),
+			entry.TaxRUB.FloatString(2),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	err = w.Error()
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,76 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"sort"
+	"time"
+
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// NominalChange is the nominal of a bond at the time
+type NominalChange struct {
+	Time    time.Time
+	Nominal *big.Rat
+}
+
+// NominalHistory is the nominals of an amortizing bond in ascending time order
+type NominalHistory []NominalChange
+
+// NewNominalHistory returns the nominals of the accrued interest records, they are nil when the nominal
+// never changed so the current one is used
+func NewNominalHistory(interests []*pb.AccruedInterest) NominalHistory {
+	var history NominalHistory
+	changed := false
+	for _, interest := range interests {
+		if interest.Nominal == nil || interest.Date == nil {
+			continue
+		}
+		nominal := ToRat(interest.Nominal)
+		if nominal.Sign() <= 0 {
+			continue
+		}
+		if len(history) > 0 && history[0].Nominal.Cmp(nominal) != 0 {
+			changed = true
+		}
+		history = append(history, NominalChange{Time: interest.Date.AsTime(), Nominal: nominal})
+	}
+	if !changed {
+		return nil
+	}
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Time.Before(history[j].Time)
+	})
+	return history
+}
+
+// At returns the nominal of the bond at the time: the nominal of the last record before it, the first
+// one before all records or the current one without records
+func (h NominalHistory) At(date time.Time, current *big.Rat) *big.Rat {
+	i := sort.Search(len(h), func(i int) bool {
+		return h[i].Time.After(date)
+	})
+	switch {
+	case len(h) == 0:
+		return current
+	case i == 0:
+		return h[0].Nominal
+	}
+	return h[i-1].Nominal
+}
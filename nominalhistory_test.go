@@ -0,0 +1,74 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+func TestNominalHistory(t *testing.T) {
+	start := time.Date(TaxYear, 3, 2, 0, 0, 0, 0, time.UTC)
+	interest := func(days int, nominal int64) *pb.AccruedInterest {
+		return &pb.AccruedInterest{
+			Date:    timestamppb.New(start.AddDate(0, 0, days)),
+			Nominal: &pb.Quotation{Units: nominal},
+		}
+	}
+	if history := NewNominalHistory([]*pb.AccruedInterest{interest(0, 1000), interest(1, 1000)}); history != nil {
+		t.Errorf("NewNominalHistory() = %v, want nil for a constant nominal", history)
+	}
+	history := NewNominalHistory([]*pb.AccruedInterest{
+		interest(10, 500), interest(0, 1000), interest(5, 1000), {Date: timestamppb.New(start)},
+	})
+	current := big.NewRat(500, 1)
+	for _, test := range []struct {
+		days int
+		want int64
+	}{
+		{-1, 1000},
+		{0, 1000},
+		{9, 1000},
+		{10, 500},
+		{20, 500},
+	} {
+		if got := history.At(start.AddDate(0, 0, test.days), current); got.Cmp(big.NewRat(test.want, 1)) != 0 {
+			t.Errorf("At(%d days) = %s, want %d", test.days, got.FloatString(2), test.want)
+		}
+	}
+	if got := NominalHistory(nil).At(start, current); got != current {
+		t.Errorf("At() without records = %s, want the current nominal", got.FloatString(2))
+	}
+
+	candles := []*pb.HistoricCandle{
+		{Time: timestamppb.New(start.AddDate(0, 0, 9)), High: &pb.Quotation{Units: 100}},
+		{Time: timestamppb.New(start.AddDate(0, 0, 10)), High: &pb.Quotation{Units: 100}},
+	}
+	series := NewPriceSeries(zap.NewNop(), make(map[string]LatestPrice), "bond", "rub", current, history, nil,
+		candles, defaultStaleGap)
+	if got := series.price(0); got.Cmp(big.NewRat(1000, 1)) != 0 {
+		t.Errorf("price before the amortization = %s, want 1000", got.FloatString(2))
+	}
+	if got := series.price(1); got.Cmp(big.NewRat(500, 1)) != 0 {
+		t.Errorf("price after the amortization = %s, want 500", got.FloatString(2))
+	}
+}
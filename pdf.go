@@ -0,0 +1,308 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+)
+
+// A4 page size and margins of the PDF report, in points
+const (
+	pdfWidth  = 595
+	pdfHeight = 842
+	pdfMargin = 50
+)
+
+// Widths of the printable ASCII characters of Helvetica in thousandths of the font size,
+// from its font metrics, to align and wrap text without embedding fonts
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// The standard fonts have no Cyrillic, so account names are transliterated
+var cyrillic = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh", 'з': "z", 'и': "i",
+	'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t",
+	'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "",
+	'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// pdfText converts the text to the WinAnsi encoding of the standard fonts
+func pdfText(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		lower := []rune(strings.ToLower(string(r)))[0]
+		switch latin, ok := cyrillic[lower]; {
+		case ok && lower != r && latin != "":
+			b.WriteString(strings.ToUpper(latin[:1]) + latin[1:])
+		case ok:
+			b.WriteString(latin)
+		case r == '€':
+			b.WriteByte(0x80)
+		case r == '–':
+			b.WriteByte(0x96)
+		case r == '—':
+			b.WriteByte(0x97)
+		case r >= 0x20 && r < 0x7f || r >= 0xa0 && r <= 0xff:
+			b.WriteByte(byte(r))
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// textWidth is the width of the encoded text in points, other characters are counted as wide as "n"
+func textWidth(text string, size float64) float64 {
+	width := 0
+	for i := 0; i < len(text); i++ {
+		if c := text[i]; c >= 0x20 && c < 0x7f {
+			width += helveticaWidths[c-0x20]
+		} else {
+			width += 556
+		}
+	}
+	return float64(width) * size / 1000
+}
+
+func pdfString(text string) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == '(' || c == ')' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c >= 0x80:
+			fmt.Fprintf(&b, "\\%03o", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// pdfDocument lays out lines of text on A4 pages top down
+type pdfDocument struct {
+	pages []*bytes.Buffer
+	// baseline of the next line
+	y float64
+	// printed at the bottom of every page before its number
+	footer string
+}
+
+func (d *pdfDocument) page() *bytes.Buffer {
+	return d.pages[len(d.pages)-1]
+}
+
+// line starts a new line of the given height, on a new page if it does not fit
+func (d *pdfDocument) line(height float64) {
+	if len(d.pages) == 0 || d.y-height < pdfMargin {
+		d.pages = append(d.pages, &bytes.Buffer{})
+		d.y = pdfHeight - pdfMargin
+	}
+	d.y -= height
+}
+
+// text writes the text at the current line starting at x
+func (d *pdfDocument) text(x, size float64, bold bool, text string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(d.page(), "BT /%s %g Tf %.2f %.2f Td %s Tj ET\n", font, size, x, d.y, pdfString(pdfText(text)))
+}
+
+// right writes the text at the current line ending at x
+func (d *pdfDocument) right(x, size float64, text string) {
+	d.text(x-textWidth(pdfText(text), size), size, false, text)
+}
+
+// paragraph writes the text wrapped to the page width
+func (d *pdfDocument) paragraph(size float64, text string) {
+	var line string
+	for _, word := range strings.Fields(text) {
+		candidate := strings.TrimSpace(line + " " + word)
+		if line != "" && textWidth(pdfText(candidate), size) > pdfWidth-2*pdfMargin {
+			d.line(size * 1.4)
+			d.text(pdfMargin, size, false, line)
+			candidate = word
+		}
+		line = candidate
+	}
+	if line != "" {
+		d.line(size * 1.4)
+		d.text(pdfMargin, size, false, line)
+	}
+}
+
+// rule draws a horizontal line under the current line
+func (d *pdfDocument) rule() {
+	fmt.Fprintf(d.page(), "0.5 w %d %.2f m %d %.2f l S\n", pdfMargin, d.y-4, pdfWidth-pdfMargin, d.y-4)
+}
+
+// WriteTo writes the document with page numbers in the footers
+func (d *pdfDocument) WriteTo(w io.Writer, title string, created time.Time) error {
+	out := bufio.NewWriter(w)
+	var offsets []int
+	written := 0
+	write := func(format string, args ...any) {
+		n, _ := fmt.Fprintf(out, format, args...)
+		written += n
+	}
+	object := func(format string, args ...any) {
+		offsets = append(offsets, written)
+		write("%d 0 obj\n", len(offsets))
+		write(format, args...)
+		write("\nendobj\n")
+	}
+
+	write("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 6+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	object("<< /Title %s /Producer (tbank-invest) /CreationDate (D:%s) >>",
+		pdfString(pdfText(title)), created.UTC().Format("20060102150405Z"))
+	for i, page := range d.pages {
+		number := fmt.Sprintf("%d / %d", i+1, len(d.pages))
+		fmt.Fprintf(page, "BT /F1 8 Tf %d %d Td %s Tj ET\n", pdfMargin, pdfMargin/2, pdfString(pdfText(d.footer)))
+		fmt.Fprintf(page, "BT /F1 8 Tf %.2f %d Td %s Tj ET\n",
+			pdfWidth-pdfMargin-textWidth(number, 8), pdfMargin/2, pdfString(number))
+		object("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", pdfWidth, pdfHeight, 7+2*i)
+		object("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.Bytes())
+	}
+	xref := written
+	write("xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		write("%010d 00000 n \n", offset)
+	}
+	write("trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Flush()
+}
+
+func WritePDF(filename string, report Report) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	err = report.WritePDF(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// WritePDF renders the report as a printable PDF for tax records, it is in English,
+// as the standard PDF fonts have no Cyrillic
+func (r Report) WritePDF(w io.Writer) error {
+	var d pdfDocument
+	money := func(value *big.Rat) string {
+		return FormatMoneyCode(value, "usd")
+	}
+	heading := func(text string) {
+		d.line(24)
+		d.text(pdfMargin, 13, true, text)
+		d.rule()
+		d.line(4)
+	}
+	row := func(label, value string) {
+		d.line(14)
+		d.text(pdfMargin, 10, false, label)
+		d.text(230, 10, true, value)
+	}
+
+	d.line(18)
+	d.text(pdfMargin, 16, true, fmt.Sprintf("Maximum account value, tax year %d", r.TaxYear))
+	d.line(14)
+	d.text(pdfMargin, 9, false, "Generated "+formatReportTime(r.Generated))
+	d.footer = "tbank-invest " + r.Provenance.Build.String()
+	if r.Combined != nil {
+		heading("All accounts")
+		row("Combined maximum value", money(r.Combined))
+		row("Date of the maximum", formatReportTime(r.CombinedTime))
+	}
+	for _, group := range r.Groups {
+		heading("Group " + group.Name)
+		row("Combined maximum value", money(group.Best))
+		row("Date of the maximum", formatReportTime(group.BestTime))
+	}
+	for _, account := range r.Accounts {
+		title := "Account " + account.Id
+		if account.Name != "" {
+			title += " " + account.Name
+		}
+		if account.Type != "" {
+			title += ", " + account.Type
+		}
+		if account.Closed != nil {
+			title += ", closed " + account.Closed.Format(time.DateOnly)
+		}
+		heading(title)
+		row("Maximum value", money(account.Best))
+		row("Date of the maximum", formatReportTime(account.BestTime))
+		row("Current value", money(account.Current))
+		row("Excluded assets at the maximum", money(account.Excluded))
+		d.line(20)
+		d.text(pdfMargin, 10, true, "Currency")
+		d.text(330-textWidth("Amount", 10), 10, true, "Amount")
+		d.text(pdfWidth-pdfMargin-textWidth("USD", 10), 10, true, "USD")
+		d.rule()
+		d.line(2)
+		for _, amount := range account.Cost {
+			d.line(14)
+			d.text(pdfMargin, 10, false, strings.ToUpper(amount.Currency))
+			d.right(330, 10, FormatMoneyCode(amount.Amount, amount.Currency))
+			if amount.USD != nil {
+				d.right(pdfWidth-pdfMargin, 10, money(amount.USD))
+			}
+		}
+	}
+
+	heading("Exchange rates")
+	for _, rate := range r.Rates {
+		d.line(12)
+		d.text(pdfMargin, 9, false, strings.ToUpper(rate.Currency))
+		d.right(230, 9, rate.String()+" per USD")
+	}
+	heading("Methodology")
+	d.paragraph(9, Methodology)
+	heading("Provenance")
+	for _, line := range r.Provenance.Lines() {
+		d.paragraph(9, line)
+	}
+	return d.WriteTo(w, fmt.Sprintf("Maximum account value %d", r.TaxYear), r.Generated)
+}
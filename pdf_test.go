@@ -0,0 +1,85 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWritePDF(t *testing.T) {
+	report := Report{
+		TaxYear:   TaxYear,
+		Generated: time.Date(TaxYear+1, 2, 1, 12, 0, 0, 0, time.UTC),
+		Rounding:  RoundHalfUp,
+		Accounts: []ReportAccount{{
+			Id:       "2000000001",
+			Name:     "Брокерский счёт (main)",
+			Type:     "brokerage",
+			Best:     big.NewRat(1234567, 100),
+			Current:  big.NewRat(1000, 1),
+			Excluded: &big.Rat{},
+			Cost: []ReportAmount{
+				{Currency: "rub", Amount: big.NewRat(819960, 1), USD: big.NewRat(10000, 1)},
+				{Currency: "xyz", Amount: big.NewRat(1, 1)},
+			},
+		}},
+		Rates: []ReportRate{{Currency: "rub", Rate: big.NewRat(81996, 1000)}},
+	}
+	// enough accounts for several pages
+	for len(report.Accounts) < 20 {
+		report.Accounts = append(report.Accounts, report.Accounts[0])
+	}
+	var out bytes.Buffer
+	err := report.WritePDF(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdf := out.String()
+	if !strings.HasPrefix(pdf, "%PDF-1.4\n") || !strings.HasSuffix(pdf, "%%EOF\n") {
+		t.Fatalf("not a PDF: %q...%q", pdf[:10], pdf[len(pdf)-10:])
+	}
+
+	// every xref entry points to its object
+	start := strings.LastIndex(pdf, "startxref\n")
+	xref, err := strconv.Atoi(strings.Fields(pdf[start+len("startxref\n"):])[0])
+	if err != nil || !strings.HasPrefix(pdf[xref:], "xref\n") {
+		t.Fatalf("startxref %d does not point to the xref table", xref)
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(pdf[xref:], -1)
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(entry[1])
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !strings.HasPrefix(pdf[offset:], want) {
+			t.Errorf("xref entry %d points to %q", i+1, pdf[offset:offset+10])
+		}
+	}
+	pages := regexp.MustCompile(`/Count (\d+)`).FindStringSubmatch(pdf)
+	if pages == nil || pages[1] == "1" {
+		t.Errorf("pages = %v, want several", pages)
+	}
+	for _, text := range []string{`Brokerskiy schet \(main\), brokerage)`, `(12,345.67 USD)`, `(819,960.00 RUB)`, `(81.996 per USD)`} {
+		if !strings.Contains(pdf, text) {
+			t.Errorf("PDF has no %s", text)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Periods of the maximum values table
+const (
+	PeriodDay  = "day"
+	PeriodWeek = "week"
+)
+
+var InvalidPeriodError = errors.New("invalid period, day or week expected")
+
+// PeriodMaximum is the maximum value within a day or a week
+type PeriodMaximum struct {
+	// the start of the period in the reporting timezone
+	Start time.Time
+	Best  Point
+	// the day is not a trading day by the trading calendar
+	NonTrading bool
+}
+
+// periodStart returns the start of the day or the week, weeks start on Monday
+func periodStart(date time.Time, period string) time.Time {
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	if period == PeriodWeek {
+		start = start.AddDate(0, 0, -(int(start.Weekday())+6)%7)
+	}
+	return start
+}
+
+// PeriodMaxima returns the maximum value of each day or week of the timeline, periods without points are skipped
+func PeriodMaxima(timeline Timeline, period string) ([]PeriodMaximum, error) {
+	if period != PeriodDay && period != PeriodWeek {
+		return nil, fmt.Errorf("%w: %q", InvalidPeriodError, period)
+	}
+	var maxima []PeriodMaximum
+	for _, point := range timeline {
+		start := periodStart(point.Time.In(Location), period)
+		if len(maxima) == 0 || !maxima[len(maxima)-1].Start.Equal(start) {
+			maxima = append(maxima, PeriodMaximum{Start: start, Best: point, NonTrading: nonTradingDay(start, period)})
+			continue
+		}
+		// the earliest point is kept for equal values, as for the yearly maximum
+		if last := &maxima[len(maxima)-1]; last.Best.Aggregate.Cmp(point.Aggregate) < 0 {
+			last.Best = point
+		}
+	}
+	return maxima, nil
+}
+
+// nonTradingDay tells whether the period is a day known to be a non-trading one
+func nonTradingDay(start time.Time, period string) bool {
+	if tradingCalendar == nil || period != PeriodDay {
+		return false
+	}
+	trading, known := tradingCalendar.TradingDay(start)
+	return known && !trading
+}
+
+// PrintPeriodMaxima prints the maximum value of each period, the yearly maximum is marked
+func PrintPeriodMaxima(w io.Writer, maxima []PeriodMaximum, best *big.Rat) error {
+	tw := NewTable(w, tabwriter.AlignRight)
+	fmt.Fprintf(tw, "%s\t%s\t%s\t\t\n", T("PERIOD"), T("MAXIMUM"), T("TIME"))
+	for _, maximum := range maxima {
+		mark := ""
+		if maximum.Best.Aggregate.Cmp(best) == 0 {
+			mark = "*"
+		}
+		if maximum.NonTrading {
+			mark = strings.TrimSpace(mark + " " + T("non-trading"))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\n", maximum.Start.Format(time.DateOnly), FormatUSD(maximum.Best.Aggregate),
+			maximum.Best.Time.Format(time.DateTime), mark)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,55 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestPeriodMaxima(t *testing.T) {
+	defer func(location *time.Location) { Location = location }(Location)
+	Location = time.UTC
+	// Wednesday
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	point := func(hours, value int64) Point {
+		return Point{Time: start.Add(time.Duration(hours) * time.Hour), Aggregate: big.NewRat(value, 1)}
+	}
+	timeline := Timeline{point(0, 5), point(1, 6), point(24, 7), point(48, 7), point(72, 3), point(96, 9), point(120, 1)}
+
+	days, err := PeriodMaxima(timeline, PeriodDay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(days) != 6 || days[0].Best.Aggregate.Cmp(big.NewRat(6, 1)) != 0 {
+		t.Errorf("daily maxima = %v, want 6 days starting with 6", days)
+	}
+	weeks, err := PeriodMaxima(timeline, PeriodWeek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Wednesday to Sunday, then Monday
+	if len(weeks) != 2 || !weeks[0].Start.Equal(time.Date(2024, 12, 30, 0, 0, 0, 0, time.UTC)) ||
+		weeks[0].Best.Aggregate.Cmp(big.NewRat(9, 1)) != 0 || weeks[1].Best.Aggregate.Cmp(big.NewRat(1, 1)) != 0 {
+		t.Errorf("weekly maxima = %v, want 9 from 2024-12-30 and 1", weeks)
+	}
+	if _, err := PeriodMaxima(timeline, "month"); !errors.Is(err, InvalidPeriodError) {
+		t.Errorf("PeriodMaxima(month) error = %v, want InvalidPeriodError", err)
+	}
+}
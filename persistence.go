@@ -0,0 +1,68 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"time"
+)
+
+// PeakBand is the share of the maximum value which counts as near the peak
+var PeakBand = big.NewRat(95, 100)
+
+// PeakPersistence tells whether the maximum was a fleeting spike or a sustained level
+type PeakPersistence struct {
+	// how long the value stayed near the peak without leaving the band
+	Sustained time.Duration
+	// how long the value was near the peak during the year in total
+	Total time.Duration
+}
+
+// Persistence measures how long the value stayed within the band of the maximum. Each point is the value
+// just before its time, so it lasted since the previous point, the first point has no duration.
+func Persistence(timeline Timeline, bestTime time.Time, best *big.Rat) PeakPersistence {
+	var persistence PeakPersistence
+	if len(timeline) == 0 {
+		return persistence
+	}
+	level := (&big.Rat{}).Mul(best, PeakBand)
+	near := func(i int) bool {
+		return timeline[i].Aggregate.Cmp(level) >= 0
+	}
+	duration := func(i int) time.Duration {
+		if i == 0 {
+			return 0
+		}
+		return timeline[i].Time.Sub(timeline[i-1].Time)
+	}
+	peak := 0
+	for i, point := range timeline {
+		if near(i) {
+			persistence.Total += duration(i)
+		}
+		if point.Time.Equal(bestTime) {
+			peak = i
+		}
+	}
+	for i := peak; i >= 0 && near(i); i-- {
+		persistence.Sustained += duration(i)
+	}
+	for i := peak + 1; i < len(timeline) && near(i); i++ {
+		persistence.Sustained += duration(i)
+	}
+	return persistence
+}
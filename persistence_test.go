@@ -0,0 +1,42 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestPersistence(t *testing.T) {
+	start := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	point := func(hours, value int64) Point {
+		return Point{Time: start.Add(time.Duration(hours) * time.Hour), Aggregate: big.NewRat(value, 1)}
+	}
+	timeline := Timeline{point(0, 96), point(1, 50), point(3, 97), point(7, 100), point(8, 95), point(10, 90)}
+	persistence := Persistence(timeline, start.Add(7*time.Hour), big.NewRat(100, 1))
+	// from the 1st hour to the 8th, the dip before the 1st hour ends the stretch
+	if persistence.Sustained != 7*time.Hour || persistence.Total != 7*time.Hour {
+		t.Errorf("Persistence() = %+v, want 7h sustained and in total", persistence)
+	}
+	timeline[1].Aggregate = big.NewRat(99, 1)
+	timeline[2].Aggregate = big.NewRat(10, 1)
+	persistence = Persistence(timeline, start.Add(7*time.Hour), big.NewRat(100, 1))
+	if persistence.Sustained != 5*time.Hour || persistence.Total != 6*time.Hour {
+		t.Errorf("Persistence() = %+v, want 5h sustained and 6h in total", persistence)
+	}
+}
@@ -0,0 +1,142 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"math/big"
+	"time"
+
+	"go.uber.org/zap"
+	"opensource.tbank.ru/invest/invest-go/investgo"
+)
+
+// PersonOptions is another person, e.g. a family member, with the token of their own accounts
+type PersonOptions struct {
+	Name  string `yaml:"Name"`
+	Token string `yaml:"Token"`
+	// all accounts of the token which existed during the tax year if empty
+	AccountIds []string `yaml:"AccountIds"`
+}
+
+// DefaultOwner is the name of the owner of the configured token in the sections of the persons
+const DefaultOwner = "Owner"
+
+// EvaluatePerson evaluates the accounts of the person with their token, the client is kept for the reports
+// and must be stopped by the caller. Instruments, candles and prices are cached by their UIDs, so the persons
+// share them.
+func EvaluatePerson(config investgo.Config, logger *zap.Logger, options Options, currencyInstruments map[string]string,
+	person PersonOptions, command string) (*investgo.Client, []*Evaluation, error) {
+	logger = logger.With(zap.String("person", person.Name))
+	token, err := ResolveToken(person.Token, person.Name)
+	if err != nil {
+		logger.Error("error reading token from the keyring", zap.Error(err))
+		return nil, nil, err
+	}
+	config.Token = token
+	config.AccountId = ""
+	logger.Debug("creating client")
+	client, err := investgo.NewClient(context.Background(), config, logger.Sugar())
+	if err != nil {
+		logger.Error("error creating client", zap.Error(err))
+		return nil, nil, err
+	}
+	evaluations, err := evaluatePerson(client, logger, options, currencyInstruments, person, command,
+		config.EndPoint == SandboxEndPoint)
+	if err != nil {
+		client.Stop()
+		return nil, nil, err
+	}
+	return client, evaluations, nil
+}
+
+func evaluatePerson(client *investgo.Client, logger *zap.Logger, options Options, currencyInstruments map[string]string,
+	person PersonOptions, command string, sandbox bool) ([]*Evaluation, error) {
+	err := CheckToken(client, logger)
+	if err != nil {
+		return nil, err
+	}
+	logger.Debug("getting accounts")
+	start := time.Now()
+	resp, err := client.NewUsersServiceClient().GetAccounts(nil)
+	TraceCall("GetAccounts", nil, start, resp, err)
+	if err != nil {
+		logger.Error("error getting accounts", zap.Error(err))
+		return nil, err
+	}
+	accounts := make(map[string]AccountInfo, len(resp.Accounts))
+	for _, account := range resp.Accounts {
+		accounts[account.Id] = NewAccountInfo(account)
+	}
+	accountIds := person.AccountIds
+	if len(accountIds) == 0 {
+		accountIds = AllAccountIds(logger, resp.Accounts)
+	}
+	err = CheckAccountAccess(client, logger, resp.Accounts, accountIds)
+	if err != nil {
+		return nil, err
+	}
+	if !sandbox {
+		err = CheckReadOnly(logger, resp.Accounts, accountIds, options.RequireReadOnly)
+		if err != nil {
+			return nil, err
+		}
+	}
+	evaluations, err := evaluateAccounts(client, logger, options, currencyInstruments, accounts, accountIds, command)
+	if err != nil {
+		return nil, err
+	}
+	for _, evaluation := range evaluations {
+		evaluation.Person = person.Name
+	}
+	return evaluations, nil
+}
+
+// PersonGroups adds a group of the accounts of each person to the groups, so each person has their section
+// with the combined maximum, the accounts of the configured token belong to the owner
+func PersonGroups(options Options, evaluations []*Evaluation) map[string][]string {
+	if len(options.Persons) == 0 {
+		return options.Groups
+	}
+	groups := maps.Clone(options.Groups)
+	if groups == nil {
+		groups = make(map[string][]string)
+	}
+	for _, evaluation := range evaluations {
+		name := cmp.Or(evaluation.Person, options.Owner, DefaultOwner)
+		groups[name] = append(groups[name], evaluation.AccountId)
+	}
+	return groups
+}
+
+// PersonsMaximum returns the largest combined maximum of the persons, it replaces the combined maximum
+// of all accounts without the household view
+func PersonsMaximum(options Options, groups []GroupSummary) *big.Rat {
+	var maximum *big.Rat
+	for _, group := range groups {
+		isPerson := group.Name == cmp.Or(options.Owner, DefaultOwner)
+		for _, person := range options.Persons {
+			isPerson = isPerson || group.Name == person.Name
+		}
+		if value := exact(group.Best); isPerson && (maximum == nil || value.Cmp(maximum) > 0) {
+			maximum = value
+		}
+	}
+	return maximum
+}
@@ -0,0 +1,53 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"slices"
+	"testing"
+)
+
+func TestPersonGroups(t *testing.T) {
+	evaluations := []*Evaluation{
+		{AccountId: "1"},
+		{AccountId: "2", Person: "Spouse"},
+		{AccountId: "3", Person: "Spouse"},
+	}
+	options := Options{
+		Persons: []PersonOptions{{Name: "Spouse"}},
+		Groups:  map[string][]string{"main": {"1"}},
+	}
+	groups := PersonGroups(options, evaluations)
+	for name, want := range map[string][]string{"main": {"1"}, DefaultOwner: {"1"}, "Spouse": {"2", "3"}} {
+		if !slices.Equal(groups[name], want) {
+			t.Errorf("PersonGroups()[%q] = %v, want %v", name, groups[name], want)
+		}
+	}
+	if len(options.Groups) != 1 {
+		t.Errorf("PersonGroups() changed the configured groups: %v", options.Groups)
+	}
+
+	maximum := PersonsMaximum(options, []GroupSummary{
+		{Name: "main", Best: NewAmount(big.NewRat(900, 1), "usd")},
+		{Name: DefaultOwner, Best: NewAmount(big.NewRat(100, 1), "usd")},
+		{Name: "Spouse", Best: NewAmount(big.NewRat(300, 1), "usd")},
+	})
+	if maximum == nil || maximum.Cmp(big.NewRat(300, 1)) != 0 {
+		t.Errorf("PersonsMaximum() = %v, want 300", maximum)
+	}
+}
@@ -0,0 +1,171 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// Accounts of the operations without a security posting, the cash side is the account of the currency
+var plainTextAccounts = map[pb.OperationType]string{
+	pb.OperationType_OPERATION_TYPE_DIVIDEND:     "Income:TBank:Dividends",
+	pb.OperationType_OPERATION_TYPE_COUPON:       "Income:TBank:Coupons",
+	pb.OperationType_OPERATION_TYPE_DIVIDEND_TAX: "Expenses:TBank:Taxes",
+	pb.OperationType_OPERATION_TYPE_BOND_TAX:     "Expenses:TBank:Taxes",
+	pb.OperationType_OPERATION_TYPE_INPUT:        "Equity:TBank:Transfers",
+	pb.OperationType_OPERATION_TYPE_OUTPUT:       "Equity:TBank:Transfers",
+}
+
+// Commodities start with a letter and have letters, digits and some punctuation
+var invalidCommodity = regexp.MustCompile(`[^A-Z0-9'._-]`)
+
+// PlainTextCommodity returns the commodity of the asset, its ticker or ISIN where there is one
+func PlainTextCommodity(assetUid string) string {
+	name := strings.ToUpper(cmp.Or(tickers[assetUid], isins[assetUid], assetUid))
+	name = invalidCommodity.ReplaceAllString(name, "-")
+	if name == "" || name[0] < 'A' || name[0] > 'Z' {
+		name = "X" + name
+	}
+	return strings.TrimRight(name, "'._-")
+}
+
+// PlainTextPosting is a line of a transaction, postings without an amount are balanced by the tool
+type PlainTextPosting struct {
+	Account string
+	Amount  string
+}
+
+// PlainTextTransaction is an operation as a double-entry transaction
+type PlainTextTransaction struct {
+	Date      time.Time
+	Narration string
+	Postings  []PlainTextPosting
+}
+
+// NewPlainTextTransaction maps the operation of the account to a transaction, Beancount books the sales
+// against the lots and puts the result to the gains account, ledger-cli keeps the sales at their prices.
+// It returns false for operations without a counterpart.
+func NewPlainTextTransaction(accountId string, operation *pb.OperationItem, beancount bool) (PlainTextTransaction, bool) {
+	if operation.Payment == nil {
+		return PlainTextTransaction{}, false
+	}
+	root := "Assets:TBank:" + accountId
+	currency := strings.ToUpper(operation.Payment.Currency)
+	payment := ToRat(operation.Payment)
+	amount := func(value *big.Rat, commodity string) string {
+		return value.FloatString(2) + " " + commodity
+	}
+	transaction := PlainTextTransaction{
+		Date:      operation.Date.AsTime().In(Location),
+		Narration: cmp.Or(operation.Description, operation.Type.String()),
+	}
+	cash := PlainTextPosting{root + ":Cash", amount(payment, currency)}
+	switch operation.Type {
+	case pb.OperationType_OPERATION_TYPE_BUY, pb.OperationType_OPERATION_TYPE_SELL:
+		commodity := PlainTextCommodity(operation.AssetUid)
+		quantity := operation.Quantity
+		if operation.Type == pb.OperationType_OPERATION_TYPE_SELL {
+			quantity = -quantity
+		}
+		total := amount((&big.Rat{}).Abs(payment), currency)
+		security := fmt.Sprintf("%d %s @@ %s", quantity, commodity, total)
+		if beancount && quantity > 0 {
+			security = fmt.Sprintf("%d %s {{%s}}", quantity, commodity, total)
+		} else if beancount {
+			security = fmt.Sprintf("%d %s {} @@ %s", quantity, commodity, total)
+		}
+		transaction.Postings = []PlainTextPosting{{root + ":" + commodity, security}, cash}
+		if beancount && quantity < 0 {
+			transaction.Postings = append(transaction.Postings, PlainTextPosting{Account: "Income:TBank:Gains"})
+		}
+	default:
+		account, ok := plainTextAccounts[operation.Type]
+		if slices.Contains(FeeTypes, operation.Type) {
+			account, ok = "Expenses:TBank:Fees", true
+		}
+		if !ok {
+			return PlainTextTransaction{}, false
+		}
+		transaction.Postings = []PlainTextPosting{cash, {account, amount((&big.Rat{}).Neg(payment), currency)}}
+	}
+	return transaction, true
+}
+
+// WritePlainText writes the operations of the tax year as Beancount entries if the file extension is .beancount
+// or as ledger-cli entries otherwise
+func WritePlainText(filename string, evaluations []*Evaluation) error {
+	beancount := filepath.Ext(filename) == ".beancount"
+	var transactions []PlainTextTransaction
+	for _, evaluation := range evaluations {
+		for _, operation := range evaluation.Operations {
+			if !InTaxYear(operation.Date.AsTime()) {
+				continue
+			}
+			if transaction, ok := NewPlainTextTransaction(evaluation.AccountId, operation, beancount); ok {
+				transactions = append(transactions, transaction)
+			}
+		}
+	}
+	slices.SortStableFunc(transactions, func(a, b PlainTextTransaction) int {
+		return a.Date.Compare(b.Date)
+	})
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := bufio.NewWriter(file)
+	dateFormat := "2006/01/02"
+	if beancount {
+		// Beancount needs the accounts opened before they are used
+		dateFormat = time.DateOnly
+		opened := make(map[string]bool)
+		start := time.Date(TaxYear, 1, 1, 0, 0, 0, 0, Location).Format(time.DateOnly)
+		for _, transaction := range transactions {
+			for _, posting := range transaction.Postings {
+				if !opened[posting.Account] {
+					opened[posting.Account] = true
+					fmt.Fprintf(w, "%s open %s\n", start, posting.Account)
+				}
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	for _, transaction := range transactions {
+		fmt.Fprintf(w, "%s * %q\n", transaction.Date.Format(dateFormat), transaction.Narration)
+		for _, posting := range transaction.Postings {
+			fmt.Fprintln(w, strings.TrimRight("  "+posting.Account+"  "+posting.Amount, " "))
+		}
+		fmt.Fprintln(w)
+	}
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
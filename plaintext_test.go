@@ -0,0 +1,55 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+func TestNewPlainTextTransaction(t *testing.T) {
+	sell := &pb.OperationItem{
+		Type:     pb.OperationType_OPERATION_TYPE_SELL,
+		AssetUid: "1f-uid",
+		Date:     timestamppb.New(time.Date(TaxYear, 3, 1, 12, 0, 0, 0, time.UTC)),
+		Quantity: 10,
+		Payment:  &pb.MoneyValue{Currency: "usd", Units: 150},
+	}
+	for _, test := range []struct {
+		beancount bool
+		want      []PlainTextPosting
+	}{
+		{false, []PlainTextPosting{
+			{"Assets:TBank:1:X1F-UID", "-10 X1F-UID @@ 150.00 USD"},
+			{"Assets:TBank:1:Cash", "150.00 USD"},
+		}},
+		{true, []PlainTextPosting{
+			{"Assets:TBank:1:X1F-UID", "-10 X1F-UID {} @@ 150.00 USD"},
+			{"Assets:TBank:1:Cash", "150.00 USD"},
+			{Account: "Income:TBank:Gains"},
+		}},
+	} {
+		got, ok := NewPlainTextTransaction("1", sell, test.beancount)
+		if !ok || !slices.Equal(got.Postings, test.want) {
+			t.Errorf("NewPlainTextTransaction(beancount = %v) = %v, want %v", test.beancount, got.Postings, test.want)
+		}
+	}
+}
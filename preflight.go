@@ -0,0 +1,118 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"opensource.tbank.ru/invest/invest-go/investgo"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+var NoAccountAccessError = errors.New("token has no access to the account")
+var TradingTokenError = errors.New("token can trade on the account")
+
+// logAccessError explains common token problems before returning the error
+func logAccessError(logger *zap.Logger, err error, scope string, fields ...zap.Field) error {
+	switch status.Code(err) {
+	case codes.Unauthenticated:
+		logger.Error(T("token is invalid or expired"), append(fields, zap.Error(err))...)
+	case codes.PermissionDenied:
+		logger.Error(fmt.Sprintf(T("token lacks %s scope"), scope), append(fields, zap.Error(err))...)
+	default:
+		logger.Error(fmt.Sprintf(T("error checking %s access"), scope), append(fields, zap.Error(err))...)
+	}
+	return err
+}
+
+// CheckToken calls a cheap endpoint to verify the token before anything else
+func CheckToken(client *investgo.Client, logger *zap.Logger) error {
+	logger.Debug("checking token")
+	start := time.Now()
+	info, err := client.NewUsersServiceClient().GetInfo()
+	TraceCall("GetInfo", nil, start, info, err)
+	if err != nil {
+		return logAccessError(logger, err, "users")
+	}
+	return nil
+}
+
+// CheckAccountAccess verifies that the token can read operations of the accounts,
+// so that the run does not fail after minutes of downloading candles
+func CheckAccountAccess(client *investgo.Client, logger *zap.Logger, accounts []*pb.Account, accountIds []string) error {
+	op := client.NewOperationsServiceClient()
+	for _, accountId := range accountIds {
+		for _, account := range accounts {
+			if account.Id == accountId && account.AccessLevel == pb.AccessLevel_ACCOUNT_ACCESS_LEVEL_NO_ACCESS {
+				logger.Error(T("token has no access to the account"), zap.String("account", accountId))
+				return NoAccountAccessError
+			}
+		}
+		logger.Debug("checking operations access", zap.String("account", accountId))
+		req := &investgo.GetOperationsByCursorRequest{
+			AccountId: accountId,
+			From:      time.Date(TaxYear, 1, 1, 0, 0, 0, 0, Location),
+			To:        time.Now(),
+			Limit:     1,
+		}
+		AwaitQuota("GetOperationsByCursor")
+		start := time.Now()
+		operations, err := op.GetOperationsByCursor(req)
+		TraceCall("GetOperationsByCursor", req, start, operations, err)
+		if cursorUnsupported(err) {
+			// operations of such accounts are read with the legacy method
+			logger.Debug("cursor-based operations are unavailable, checking legacy operations access",
+				zap.String("account", accountId), zap.Error(err))
+			legacy := &investgo.GetOperationsRequest{
+				AccountId: accountId,
+				From:      time.Now().Add(-24 * time.Hour),
+				To:        time.Now(),
+			}
+			AwaitQuota("GetOperations")
+			start = time.Now()
+			legacyOperations, legacyErr := op.GetOperations(legacy)
+			TraceCall("GetOperations", legacy, start, legacyOperations, legacyErr)
+			err = legacyErr
+		}
+		if err != nil {
+			return logAccessError(logger, err, "operations", zap.String("account", accountId))
+		}
+	}
+	return nil
+}
+
+// CheckReadOnly warns about the accounts the token can trade on, or refuses them when a read-only token
+// is required, as the evaluation only reads
+func CheckReadOnly(logger *zap.Logger, accounts []*pb.Account, accountIds []string, require bool) error {
+	for _, account := range accounts {
+		if account.AccessLevel != pb.AccessLevel_ACCOUNT_ACCESS_LEVEL_FULL_ACCESS || !slices.Contains(accountIds, account.Id) {
+			continue
+		}
+		if require {
+			logger.Error(T("token can trade on the account, a read-only token is enough"), zap.String("account", account.Id))
+			return TradingTokenError
+		}
+		logger.Warn(T("token can trade on the account, a read-only token is enough"), zap.String("account", account.Id))
+	}
+	return nil
+}
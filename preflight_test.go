@@ -0,0 +1,46 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+func TestCheckReadOnly(t *testing.T) {
+	accounts := []*pb.Account{
+		{Id: "1", AccessLevel: pb.AccessLevel_ACCOUNT_ACCESS_LEVEL_READ_ONLY},
+		{Id: "2", AccessLevel: pb.AccessLevel_ACCOUNT_ACCESS_LEVEL_FULL_ACCESS},
+	}
+	for _, test := range []struct {
+		accountIds []string
+		require    bool
+		want       error
+	}{
+		{[]string{"1"}, true, nil},
+		{[]string{"1", "2"}, false, nil},
+		{[]string{"1", "2"}, true, TradingTokenError},
+	} {
+		err := CheckReadOnly(zap.NewNop(), accounts, test.accountIds, test.require)
+		if !errors.Is(err, test.want) {
+			t.Errorf("CheckReadOnly(%v, %v) = %v, want %v", test.accountIds, test.require, err, test.want)
+		}
+	}
+}
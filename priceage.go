@@ -0,0 +1,103 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"math/big"
+	"slices"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// Prices older than these are flagged as stale at the peak
+const (
+	staleHours = time.Hour
+	staleDays  = 24 * time.Hour
+)
+
+// PriceAge is how old the price of an asset was at some moment
+type PriceAge struct {
+	Asset string
+	// time of the last candle at or before the moment, zero if the moment is before the first candle
+	// and the price of the next one is used
+	Candle time.Time
+	Age    time.Duration
+}
+
+// PriceAges returns the age of the candle prices of the assets in the portfolio at the time, by ticker.
+// Assets valued without candles, e.g. by the current portfolio prices, are not listed.
+func PriceAges(series []*PriceSeries, portfolio map[string]*big.Rat, date time.Time) []PriceAge {
+	bySeries := make(map[string]*PriceSeries, len(series))
+	for _, s := range series {
+		if len(s.Candles) > 0 {
+			bySeries[s.Asset] = s
+		}
+	}
+	var ages []PriceAge
+	for _, asset := range slices.SortedFunc(maps.Keys(portfolio), ByTicker) {
+		s, ok := bySeries[asset]
+		if !ok || portfolio[asset].Sign() == 0 {
+			continue
+		}
+		// the first candle after the time
+		i := sort.Search(len(s.Candles), func(i int) bool {
+			return s.Candles[i].Time.AsTime().After(date)
+		})
+		age := PriceAge{Asset: asset}
+		if i > 0 {
+			age.Candle = s.Candles[i-1].Time.AsTime()
+			age.Age = date.Sub(age.Candle)
+		}
+		ages = append(ages, age)
+	}
+	return ages
+}
+
+// staleness flags prices older than an hour or a day
+func staleness(age PriceAge) string {
+	switch {
+	case age.Candle.IsZero():
+		return T("next candle")
+	case age.Age >= staleDays:
+		return T("days stale")
+	case age.Age > staleHours:
+		return T("hours stale")
+	}
+	return ""
+}
+
+// PrintPriceAges prints the time of the last candle and the age of the price of each asset
+func PrintPriceAges(w io.Writer, ages []PriceAge) error {
+	tw := NewTable(w, tabwriter.AlignRight)
+	fmt.Fprintf(tw, "%s\t%s\t%s\t\t\n", T("TICKER"), T("CANDLE"), T("AGE"))
+	for _, age := range ages {
+		name := age.Asset
+		if ticker, ok := tickers[age.Asset]; ok {
+			name = ticker
+		}
+		candle := "-"
+		if !age.Candle.IsZero() {
+			candle = age.Candle.In(Location).Format(time.DateTime)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\n", name, candle, age.Age, staleness(age))
+	}
+	return tw.Flush()
+}
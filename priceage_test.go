@@ -0,0 +1,56 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+func TestPriceAges(t *testing.T) {
+	start := time.Date(TaxYear, 3, 7, 10, 0, 0, 0, time.UTC)
+	candle := func(hours int64) *pb.HistoricCandle {
+		return &pb.HistoricCandle{Time: timestamppb.New(start.Add(time.Duration(hours) * time.Hour)), High: &pb.Quotation{Units: 1}}
+	}
+	series := []*PriceSeries{
+		{Asset: "fresh", Candles: []*pb.HistoricCandle{candle(0), candle(1), candle(2)}},
+		{Asset: "stale", Candles: []*pb.HistoricCandle{candle(-72), candle(10)}},
+		{Asset: "later", Candles: []*pb.HistoricCandle{candle(5)}},
+		{Asset: "sold", Candles: []*pb.HistoricCandle{candle(0)}},
+	}
+	portfolio := map[string]*big.Rat{
+		"fresh": big.NewRat(1, 1), "stale": big.NewRat(1, 1), "later": big.NewRat(1, 1),
+		"sold": {}, "rub": big.NewRat(100, 1),
+	}
+	ages := PriceAges(series, portfolio, start.Add(90*time.Minute))
+	want := map[string]string{"fresh": "", "stale": "days stale", "later": "next candle"}
+	if len(ages) != len(want) {
+		t.Fatalf("PriceAges() = %v, want %d assets", ages, len(want))
+	}
+	for _, age := range ages {
+		if got := staleness(age); got != want[age.Asset] {
+			t.Errorf("%s staleness = %q, want %q", age.Asset, got, want[age.Asset])
+		}
+	}
+	if ages[1].Asset != "later" || ages[1].Age != 0 {
+		t.Errorf("PriceAges() = %v, want later second without age", ages)
+	}
+}
@@ -0,0 +1,80 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"net/http"
+	_ "net/http/pprof"
+	"os"
+	"runtime"
+	"runtime/pprof"
+
+	"go.uber.org/zap"
+)
+
+// StartProfiling starts the profiles requested by the flags, the returned function writes them
+func StartProfiling(logger *zap.Logger) (func(), error) {
+	if *pprofAddress != "" {
+		go func() {
+			logger.Info("serving pprof", zap.String("address", *pprofAddress))
+			err := http.ListenAndServe(*pprofAddress, nil)
+			logger.Warn("pprof server stopped", zap.Error(err))
+		}()
+	}
+	var cpu *os.File
+	if *cpuProfile != "" {
+		var err error
+		cpu, err = os.Create(*cpuProfile)
+		if err != nil {
+			return nil, err
+		}
+		err = pprof.StartCPUProfile(cpu)
+		if err != nil {
+			cpu.Close()
+			return nil, err
+		}
+	}
+	return func() {
+		if cpu != nil {
+			pprof.StopCPUProfile()
+			err := cpu.Close()
+			if err != nil {
+				logger.Warn("error writing CPU profile", zap.Error(err))
+			}
+		}
+		if *memProfile != "" {
+			err := writeHeapProfile(*memProfile)
+			if err != nil {
+				logger.Warn("error writing heap profile", zap.Error(err))
+			}
+		}
+	}, nil
+}
+
+func writeHeapProfile(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	runtime.GC()
+	err = pprof.WriteHeapProfile(file)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,87 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// Provenance tells how the reported values were computed, so a figure can be explained if questioned
+type Provenance struct {
+	Tool           string    `json:"tool"`
+	Build          BuildInfo `json:"build"`
+	Endpoint       string    `json:"endpoint"`
+	DataSource     string    `json:"data_source"`
+	CandleInterval string    `json:"candle_interval"`
+	CandleSource   string    `json:"candle_source"`
+	Fallbacks      string    `json:"fallbacks"`
+	PriceField     string    `json:"price_field"`
+	RateSource     string    `json:"rate_source"`
+	// date of the exchange rates table and a hash of its rates
+	RatesVersion string `json:"rates_version"`
+	Rounding     string `json:"rounding"`
+}
+
+// NewProvenance describes the current build and run settings
+func NewProvenance(endpoint string) Provenance {
+	provenance := Provenance{
+		Tool:           "github.com/matshch/tbank-invest",
+		Build:          GetBuildInfo(),
+		Endpoint:       endpoint,
+		DataSource:     PriceSource,
+		CandleInterval: "1 hour",
+		CandleSource:   candleSourceNames[CandleSource],
+		Fallbacks:      "daily candles, then the last official close price",
+		PriceField:     "high",
+		RateSource:     RateSource,
+		RatesVersion:   ExchangeRatesDate + " sha256:" + hashRats(ExchangeRates)[:12],
+		Rounding:       fmt.Sprintf("%s to %d decimal places", Rounding, MoneyDecimals),
+	}
+	if *fast {
+		provenance.CandleInterval = "none, fast mode"
+		provenance.CandleSource = "none, fast mode"
+		provenance.Fallbacks = "trade prices, then the current portfolio prices"
+		provenance.PriceField = "trade price"
+	}
+	return provenance
+}
+
+// Lines lists the provenance for text reports
+func (p Provenance) Lines() []string {
+	return []string{
+		"Tool: " + p.Tool + " " + p.Build.String(),
+		"Data: " + p.DataSource + " at " + p.Endpoint,
+		"Candle interval: " + p.CandleInterval + ", fallbacks: " + p.Fallbacks,
+		"Candle source: " + p.CandleSource,
+		"Price field: " + p.PriceField,
+		"Exchange rates: " + p.RatesVersion + ", " + p.RateSource,
+		"Rounding: " + p.Rounding,
+	}
+}
+
+// Print prints the provenance after the text reports
+func (p Provenance) Print(w io.Writer) error {
+	_, err := fmt.Fprintln(w, T("Provenance"))
+	for _, line := range p.Lines() {
+		if err != nil {
+			break
+		}
+		_, err = fmt.Fprintln(w, "  "+line)
+	}
+	return err
+}
@@ -0,0 +1,205 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+	_ "unsafe" // for go:linkname
+
+	"golang.org/x/net/proxy"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/resolver"
+)
+
+var InvalidProxyError = errors.New("invalid proxy, an http, https, socks5 or socks5h URL expected")
+
+// ContextDialer dials the address like grpc.WithContextDialer
+type ContextDialer func(ctx context.Context, address string) (net.Conn, error)
+
+// NewDialer returns the dialer of the API connections through the HTTP CONNECT or SOCKS5 proxy,
+// nil if the URL is empty, so gRPC dials by itself
+func NewDialer(proxyURL string) (ContextDialer, error) {
+	if proxyURL == "" {
+		return nil, nil
+	}
+	dialer := &net.Dialer{}
+	parsed, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", InvalidProxyError, err)
+	}
+	switch parsed.Scheme {
+	case "http", "https":
+		return httpConnectDialer(parsed, dialer), nil
+	case "socks5", "socks5h":
+		socks, err := proxy.FromURL(parsed, dialer)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", InvalidProxyError, err)
+		}
+		contextDialer := socks.(proxy.ContextDialer)
+		return func(ctx context.Context, address string) (net.Conn, error) {
+			return contextDialer.DialContext(ctx, "tcp", address)
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %q", InvalidProxyError, parsed.Scheme)
+}
+
+// httpConnectDialer opens tunnels with the CONNECT method, the connection to an https proxy is encrypted too
+func httpConnectDialer(proxyURL *url.URL, dialer *net.Dialer) ContextDialer {
+	return func(ctx context.Context, address string) (net.Conn, error) {
+		host := proxyURL.Host
+		if proxyURL.Port() == "" {
+			port := "80"
+			if proxyURL.Scheme == "https" {
+				port = "443"
+			}
+			host = net.JoinHostPort(proxyURL.Hostname(), port)
+		}
+		conn, err := dialer.DialContext(ctx, "tcp", host)
+		if err != nil {
+			return nil, err
+		}
+		if proxyURL.Scheme == "https" {
+			tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+			err = tlsConn.HandshakeContext(ctx)
+			if err != nil {
+				conn.Close()
+				return nil, err
+			}
+			conn = tlsConn
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
+			defer conn.SetDeadline(time.Time{})
+		}
+		req := &http.Request{
+			Method: http.MethodConnect,
+			URL:    &url.URL{Opaque: address},
+			Host:   address,
+			Header: make(http.Header),
+		}
+		if user := proxyURL.User; user != nil {
+			password, _ := user.Password()
+			credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+			req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+		}
+		err = req.Write(conn)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		reader := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(reader, req)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			conn.Close()
+			return nil, fmt.Errorf("proxy refused to connect to %s: %s", address, resp.Status)
+		}
+		if reader.Buffered() > 0 {
+			return &bufferedConn{Conn: conn, reader: reader}, nil
+		}
+		return conn, nil
+	}
+}
+
+// bufferedConn reads the data the proxy sent right after its response first
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+// KeepaliveOptions are the gRPC keepalive parameters of the API connections
+type KeepaliveOptions struct {
+	// ping period of an idle connection, at least 10s, e.g. 30s, no pings if empty
+	Time string `yaml:"Time"`
+	// how long to wait for the ping acknowledgement before closing the connection, 20s if empty
+	Timeout string `yaml:"Timeout"`
+	// ping without active calls too, e.g. between the pages of a long run
+	PermitWithoutStream bool `yaml:"PermitWithoutStream"`
+}
+
+var InvalidKeepaliveError = errors.New("invalid keepalive, positive durations expected")
+
+// Parameters returns the keepalive parameters, nil if keepalive is not configured
+func (o KeepaliveOptions) Parameters() (*keepalive.ClientParameters, error) {
+	if o.Time == "" {
+		return nil, nil
+	}
+	params := &keepalive.ClientParameters{Timeout: 20 * time.Second, PermitWithoutStream: o.PermitWithoutStream}
+	var err error
+	params.Time, err = time.ParseDuration(o.Time)
+	if err != nil || params.Time <= 0 {
+		return nil, fmt.Errorf("%w: %q", InvalidKeepaliveError, o.Time)
+	}
+	if o.Timeout != "" {
+		params.Timeout, err = time.ParseDuration(o.Timeout)
+		if err != nil || params.Timeout <= 0 {
+			return nil, fmt.Errorf("%w: %q", InvalidKeepaliveError, o.Timeout)
+		}
+	}
+	return params, nil
+}
+
+// NewDialOptions returns the gRPC dial options of the API connections: the dialer of the proxy, if any,
+// and the keepalive parameters, if any
+func NewDialOptions(dial ContextDialer, params *keepalive.ClientParameters) []grpc.DialOption {
+	var opts []grpc.DialOption
+	if dial != nil {
+		opts = append(opts, grpc.WithContextDialer(dial))
+	}
+	if params != nil {
+		opts = append(opts, grpc.WithKeepaliveParams(*params))
+	}
+	return opts
+}
+
+// addGlobalDialOptions is set by gRPC to add dial options to all its connections. The SDK creates
+// its connection without taking dial options, so the proxy and keepalive options reach it this way,
+// the other connections of the process are not gRPC ones.
+//
+//go:linkname addGlobalDialOptions google.golang.org/grpc/internal.AddGlobalDialOptions
+var addGlobalDialOptions any
+
+// UseDialOptions adds the dial options to the API connections created after it. The target is not
+// resolved locally through a proxy, so its dialer gets the host name like gRPC's own proxy support.
+func UseDialOptions(dial ContextDialer, params *keepalive.ClientParameters) {
+	opts := NewDialOptions(dial, params)
+	if len(opts) == 0 {
+		return
+	}
+	if dial != nil {
+		resolver.SetDefaultScheme("passthrough")
+	}
+	addGlobalDialOptions.(func(opt ...grpc.DialOption))(opts...)
+}
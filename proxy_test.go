@@ -0,0 +1,215 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// serve accepts a single connection, handles the proxy handshake and echoes the rest
+func serve(t *testing.T, handshake func(conn net.Conn, reader *bufio.Reader) error) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		if handshake != nil && handshake(conn, reader) != nil {
+			return
+		}
+		io.Copy(conn, reader)
+	}()
+	return listener.Addr().String()
+}
+
+// echoes dials the address and checks that the data comes back
+func echoes(t *testing.T, dial ContextDialer, address string) {
+	t.Helper()
+	conn, err := dial(context.Background(), address)
+	if err != nil {
+		t.Fatalf("dial error = %v", err)
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte("ping"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply := make([]byte, 4)
+	_, err = io.ReadFull(conn, reply)
+	if err != nil || string(reply) != "ping" {
+		t.Errorf("reply = %q, %v, want ping", reply, err)
+	}
+}
+
+func TestHTTPConnectDialer(t *testing.T) {
+	address := serve(t, func(conn net.Conn, reader *bufio.Reader) error {
+		req, err := http.ReadRequest(reader)
+		if err != nil {
+			return err
+		}
+		want := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:secret"))
+		if req.Method != http.MethodConnect || req.Host != "api.example:443" ||
+			req.Header.Get("Proxy-Authorization") != want {
+			t.Errorf("request = %s %s %v", req.Method, req.Host, req.Header)
+			_, err = conn.Write([]byte("HTTP/1.1 403 Forbidden\r\n\r\n"))
+			return errors.New("unexpected request")
+		}
+		_, err = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		return err
+	})
+	dial, err := NewDialer("http://user:secret@" + address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	echoes(t, dial, "api.example:443")
+}
+
+func TestSOCKS5Dialer(t *testing.T) {
+	address := serve(t, func(conn net.Conn, reader *bufio.Reader) error {
+		// the greeting with the methods, no authentication is chosen
+		greeting := make([]byte, 2)
+		if _, err := io.ReadFull(reader, greeting); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(reader, make([]byte, greeting[1])); err != nil {
+			return err
+		}
+		if _, err := conn.Write([]byte{5, 0}); err != nil {
+			return err
+		}
+		// the connect request with the domain name
+		request := make([]byte, 5)
+		if _, err := io.ReadFull(reader, request); err != nil {
+			return err
+		}
+		target := make([]byte, int(request[4])+2)
+		if _, err := io.ReadFull(reader, target); err != nil {
+			return err
+		}
+		if request[1] != 1 || request[3] != 3 || string(target[:request[4]]) != "api.example" {
+			t.Errorf("request = %v %q", request, target)
+			return errors.New("unexpected request")
+		}
+		_, err := conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+		return err
+	})
+	dial, err := NewDialer("socks5h://" + address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	echoes(t, dial, "api.example:443")
+}
+
+func TestNewDialerInvalid(t *testing.T) {
+	if _, err := NewDialer("ftp://proxy.example"); !errors.Is(err, InvalidProxyError) {
+		t.Errorf("NewDialer() error = %v, want %v", err, InvalidProxyError)
+	}
+}
+
+func TestDialOptions(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(listener)
+	defer server.Stop()
+	// the proxy gets the API host name and relays the connection to the server
+	address := serve(t, func(conn net.Conn, reader *bufio.Reader) error {
+		req, err := http.ReadRequest(reader)
+		if err != nil {
+			return err
+		}
+		if req.Host != "api.example:443" {
+			t.Errorf("CONNECT %s, want api.example:443", req.Host)
+			return errors.New("unexpected request")
+		}
+		upstream, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			return err
+		}
+		defer upstream.Close()
+		_, err = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		if err != nil {
+			return err
+		}
+		go io.Copy(upstream, reader)
+		io.Copy(conn, upstream)
+		return errors.New("relayed")
+	})
+	dial, err := NewDialer("http://" + address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := KeepaliveOptions{Time: "30s", PermitWithoutStream: true}.Parameters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := append(NewDialOptions(dial, params), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient("passthrough:///api.example:443", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil || resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("health check through the proxy = %v, %v", resp, err)
+	}
+}
+
+func TestGlobalDialOptions(t *testing.T) {
+	// UseDialOptions relies on the gRPC internals, it must fail here and not on the first run with a proxy
+	if _, ok := addGlobalDialOptions.(func(opt ...grpc.DialOption)); !ok {
+		t.Errorf("addGlobalDialOptions = %T, want a function of dial options", addGlobalDialOptions)
+	}
+}
+
+func TestKeepaliveOptions(t *testing.T) {
+	params, err := KeepaliveOptions{}.Parameters()
+	if params != nil || err != nil {
+		t.Errorf("empty Parameters() = %v, %v, want none", params, err)
+	}
+	params, err = KeepaliveOptions{Time: "1m"}.Parameters()
+	if err != nil || params.Time != time.Minute || params.Timeout != 20*time.Second || params.PermitWithoutStream {
+		t.Errorf("Parameters() = %+v, %v", params, err)
+	}
+	for _, options := range []KeepaliveOptions{{Time: "soon"}, {Time: "-1s"}, {Time: "30s", Timeout: "0s"}} {
+		if _, err := options.Parameters(); !errors.Is(err, InvalidKeepaliveError) {
+			t.Errorf("Parameters(%+v) error = %v, want %v", options, err, InvalidKeepaliveError)
+		}
+	}
+}
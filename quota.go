@@ -0,0 +1,48 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"go.uber.org/zap"
+)
+
+// EstimateQuota logs the estimated requests of the phase and warns if they exceed the per-minute limit
+// of the service, as they are delayed by the rate limiter then; with throttling the requests of the service
+// are spaced evenly instead of going in bursts
+func EstimateQuota(logger *zap.Logger, phase string, requests int, service string, throttle bool) {
+	if requests == 0 {
+		return
+	}
+	limiter := limiters[service]
+	fields := []zap.Field{
+		zap.String("phase", phase),
+		zap.Int("requests", requests),
+		zap.String("service", service),
+		zap.Int("per_minute", limiter.PerMinute()),
+		zap.Duration("duration_at_limit", minutes(requests, limiter.PerMinute())),
+	}
+	if requests <= limiter.PerMinute() {
+		logger.Info("estimated API requests", fields...)
+		return
+	}
+	if throttle {
+		logger.Warn("estimated API requests exceed the per-minute limit, throttling", fields...)
+		limiter.Smooth()
+		return
+	}
+	logger.Warn("estimated API requests exceed the per-minute limit, set Throttle to space them evenly", fields...)
+}
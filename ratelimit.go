@@ -0,0 +1,147 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Services of the API with separate request limits
+const (
+	ServiceOperations  = "operations"
+	ServiceInstruments = "instruments"
+	ServiceMarketData  = "marketdata"
+)
+
+var InvalidRateLimitError = errors.New("rate limit must not be negative")
+
+// RateLimitsOptions are the requests per minute by service, the default API limits are used for zeros
+type RateLimitsOptions struct {
+	Operations  int `yaml:"Operations"`
+	Instruments int `yaml:"Instruments"`
+	MarketData  int `yaml:"MarketData"`
+}
+
+// methodServices map the methods to their services, methods of other services are not limited
+var methodServices = map[string]string{
+	"GenerateBrokerReport":            ServiceOperations,
+	"GetBrokerReport":                 ServiceOperations,
+	"GetDividendsForeignIssuer":       ServiceOperations,
+	"GetDividendsForeignIssuerReport": ServiceOperations,
+	"GetOperations":                   ServiceOperations,
+	"GetOperationsByCursor":           ServiceOperations,
+	"GetPortfolio":                    ServiceOperations,
+	"BondBy":                          ServiceInstruments,
+	"Currencies":                      ServiceInstruments,
+	"EtfBy":                           ServiceInstruments,
+	"FutureBy":                        ServiceInstruments,
+	"GetAccruedInterests":             ServiceInstruments,
+	"GetAssetBy":                      ServiceInstruments,
+	"GetDividends":                    ServiceInstruments,
+	"GetInstrumentBy":                 ServiceInstruments,
+	"ShareBy":                         ServiceInstruments,
+	"TradingSchedules":                ServiceInstruments,
+	"GetCandles":                      ServiceMarketData,
+	"GetClosePrices":                  ServiceMarketData,
+	"GetLastPrices":                   ServiceMarketData,
+}
+
+// RateLimiter is a token bucket refilled at the per-minute limit of a service. It lets bursts
+// up to the limit through and then spaces the requests, so the API does not reject them.
+type RateLimiter struct {
+	mu        sync.Mutex
+	perMinute int
+	burst     float64
+	tokens    float64
+	last      time.Time
+}
+
+func NewRateLimiter(perMinute int) *RateLimiter {
+	return &RateLimiter{
+		perMinute: perMinute,
+		burst:     float64(perMinute),
+		tokens:    float64(perMinute),
+		last:      time.Now(),
+	}
+}
+
+// PerMinute returns the limit of the service
+func (l *RateLimiter) PerMinute() int {
+	return l.perMinute
+}
+
+// Smooth spaces all following requests evenly instead of letting bursts through
+func (l *RateLimiter) Smooth() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.burst = 1
+	l.tokens = min(l.tokens, l.burst)
+}
+
+// Wait blocks until the requests may be sent, requests of concurrent callers are queued
+func (l *RateLimiter) Wait(requests int) {
+	l.mu.Lock()
+	now := time.Now()
+	// tokens per nanosecond
+	rate := float64(l.perMinute) / float64(time.Minute)
+	l.tokens = min(l.burst, l.tokens+float64(now.Sub(l.last))*rate)
+	l.last = now
+	l.tokens -= float64(requests)
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / rate)
+	}
+	l.mu.Unlock()
+	time.Sleep(wait)
+}
+
+// limiters of the services, set from the options at the start
+var limiters = map[string]*RateLimiter{
+	ServiceOperations:  NewRateLimiter(OperationsRequestsPerMinute),
+	ServiceInstruments: NewRateLimiter(InstrumentsRequestsPerMinute),
+	ServiceMarketData:  NewRateLimiter(MarketDataRequestsPerMinute),
+}
+
+// SetRateLimits replaces the limiters with the configured limits, none is replaced if any limit is invalid
+func SetRateLimits(options RateLimitsOptions) error {
+	limits := map[string]struct{ value, fallback int }{
+		ServiceOperations:  {options.Operations, OperationsRequestsPerMinute},
+		ServiceInstruments: {options.Instruments, InstrumentsRequestsPerMinute},
+		ServiceMarketData:  {options.MarketData, MarketDataRequestsPerMinute},
+	}
+	for _, limit := range limits {
+		if limit.value < 0 {
+			return InvalidRateLimitError
+		}
+	}
+	for service, limit := range limits {
+		if limit.value == 0 {
+			limit.value = limit.fallback
+		}
+		limiters[service] = NewRateLimiter(limit.value)
+	}
+	return nil
+}
+
+// AwaitQuota blocks until a request of the method may be sent under the limit of its service
+func AwaitQuota(method string) {
+	if limiter, ok := limiters[methodServices[method]]; ok {
+		limiter.Wait(1)
+	}
+}
@@ -0,0 +1,60 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	// a token every 5ms
+	limiter := NewRateLimiter(12000)
+	start := time.Now()
+	limiter.Wait(100)
+	if elapsed := time.Since(start); elapsed > 10*time.Millisecond {
+		t.Errorf("limiter waited %v for a burst under the limit", elapsed)
+	}
+	limiter.Smooth()
+	start = time.Now()
+	limiter.Wait(1)
+	limiter.Wait(2)
+	limiter.Wait(1)
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Errorf("limiter waited %v for 4 smoothed requests, want at least 15ms", elapsed)
+	}
+}
+
+func TestSetRateLimits(t *testing.T) {
+	defer SetRateLimits(RateLimitsOptions{})
+	if err := SetRateLimits(RateLimitsOptions{MarketData: 60}); err != nil {
+		t.Fatal(err)
+	}
+	if limit := limiters[ServiceMarketData].PerMinute(); limit != 60 {
+		t.Errorf("market data limit = %d, want 60", limit)
+	}
+	if limit := limiters[ServiceOperations].PerMinute(); limit != OperationsRequestsPerMinute {
+		t.Errorf("operations limit = %d, want the default %d", limit, OperationsRequestsPerMinute)
+	}
+	if err := SetRateLimits(RateLimitsOptions{MarketData: 30, Instruments: -1}); err != InvalidRateLimitError {
+		t.Errorf("SetRateLimits() error = %v, want %v", err, InvalidRateLimitError)
+	}
+	// an invalid limit keeps all the previous ones
+	if limit := limiters[ServiceMarketData].PerMinute(); limit != 60 {
+		t.Errorf("market data limit after an invalid one = %d, want 60", limit)
+	}
+}
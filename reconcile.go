@@ -0,0 +1,173 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"opensource.tbank.ru/invest/invest-go/investgo"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// Broker reports are generated asynchronously, they are polled with this interval
+const reportPollInterval = 5 * time.Second
+const reportTimeout = 5 * time.Minute
+
+// Payment dates in the reports may differ from the operations by a few days
+const paymentDateTolerance = 7 * 24 * time.Hour
+
+// pollReport calls get until the report is generated
+func pollReport[T any](logger *zap.Logger, get func() (T, error)) (T, error) {
+	deadline := time.Now().Add(reportTimeout)
+	for {
+		resp, err := get()
+		if err == nil || time.Now().After(deadline) {
+			return resp, err
+		}
+		switch status.Code(err) {
+		case codes.NotFound, codes.Unavailable, codes.FailedPrecondition:
+			logger.Debug("report is not ready yet", zap.Error(err))
+			time.Sleep(reportPollInterval)
+		default:
+			return resp, err
+		}
+	}
+}
+
+func getDividendsForeignIssuerReport(op *investgo.OperationsServiceClient, logger *zap.Logger,
+	accountId string, from, to time.Time) ([]*pb.DividendsForeignIssuerReport, error) {
+	logger.Debug("requesting foreign issuer dividends report")
+	AwaitQuota("GetDividendsForeignIssuer")
+	start := time.Now()
+	task, err := op.GetDividendsForeignIssuer(accountId, from, to)
+	TraceCall("GetDividendsForeignIssuer", reportRequest{accountId, from, to}, start, task, err)
+	if err != nil {
+		return nil, err
+	}
+	var result []*pb.DividendsForeignIssuerReport
+	for page := int32(0); ; page++ {
+		resp, err := pollReport(logger, func() (*investgo.GetDividendsForeignIssuerReportResponse, error) {
+			AwaitQuota("GetDividendsForeignIssuerReport")
+			start := time.Now()
+			resp, err := op.GetDividendsForeignIssuerReport(task.TaskId, page)
+			TraceCall("GetDividendsForeignIssuerReport", reportPageRequest{task.TaskId, page}, start, resp, err)
+			return resp, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, resp.DividendsForeignIssuerReport...)
+		if page+1 >= resp.PagesCount {
+			return result, nil
+		}
+	}
+}
+
+// IsForeign checks whether the security is issued outside of Russia
+func IsForeign(isin string) bool {
+	return isin != "" && !strings.HasPrefix(isin, "RU")
+}
+
+func withinTolerance(a, b time.Time) bool {
+	diff := a.Sub(b)
+	return diff <= paymentDateTolerance && diff >= -paymentDateTolerance
+}
+
+// ReconcileDividends compares dividend operations with the dividend calendar and the foreign issuer report,
+// it returns the number of discrepancies found
+func ReconcileDividends(in *investgo.InstrumentsServiceClient, op *investgo.OperationsServiceClient, logger *zap.Logger,
+	accountId string, operations []*pb.OperationItem, from, to time.Time) (int, error) {
+	mismatches := 0
+
+	type payment struct {
+		asset  string
+		record time.Time
+	}
+	seen := make(map[payment]*pb.OperationItem)
+	for _, operation := range operations {
+		calendar, err := getDividends(in, logger, operation.InstrumentUid)
+		if err != nil {
+			return mismatches, err
+		}
+		record, ok := FindRecordDate(calendar, operation.Date.AsTime())
+		if !ok {
+			mismatches++
+			logger.Warn("dividend operation does not match any declared dividend",
+				zap.String("ticker", tickers[operation.AssetUid]),
+				zap.Time("date", operation.Date.AsTime()),
+				zap.Stringer("payment", ToRat(operation.Payment)),
+				zap.String("currency", operation.Payment.GetCurrency()))
+			continue
+		}
+		key := payment{operation.AssetUid, record}
+		if previous, ok := seen[key]; ok {
+			mismatches++
+			logger.Warn("dividend may be counted twice",
+				zap.String("ticker", tickers[operation.AssetUid]),
+				zap.Time("record_date", record),
+				zap.String("operation", operation.Id),
+				zap.String("previous_operation", previous.Id))
+			continue
+		}
+		seen[key] = operation
+	}
+
+	report, err := getDividendsForeignIssuerReport(op, logger, accountId, from, to)
+	if err != nil {
+		return mismatches, err
+	}
+	matched := make(map[*pb.OperationItem]bool)
+	for _, row := range report {
+		if row.PaymentDate == nil {
+			continue
+		}
+		found := false
+		for _, operation := range operations {
+			if !matched[operation] && isins[operation.AssetUid] == row.Isin &&
+				withinTolerance(operation.Date.AsTime(), row.PaymentDate.AsTime()) {
+				matched[operation] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			mismatches++
+			logger.Warn("dividend from broker report is missing in operations",
+				zap.String("name", row.SecurityName),
+				zap.String("isin", row.Isin),
+				zap.Time("payment_date", row.PaymentDate.AsTime()),
+				zap.Stringer("amount", ToRat(row.DividendAmount)),
+				zap.String("currency", row.Currency))
+		}
+	}
+	for _, operation := range operations {
+		// the report only covers foreign issuers, so only their operations are expected there
+		if !matched[operation] && IsForeign(isins[operation.AssetUid]) {
+			mismatches++
+			logger.Warn("dividend operation is missing in broker report",
+				zap.String("ticker", tickers[operation.AssetUid]),
+				zap.Time("date", operation.Date.AsTime()),
+				zap.Stringer("payment", ToRat(operation.Payment)),
+				zap.String("currency", operation.Payment.GetCurrency()))
+		}
+	}
+	return mismatches, nil
+}
@@ -0,0 +1,231 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand/v2"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Redactor replaces identifiers with consistent pseudonyms and scales amounts by a random factor,
+// so the output can be shared without exposing the finances while the ratios still make sense
+type Redactor struct {
+	mu         sync.Mutex
+	scale      *big.Rat
+	pseudonyms map[string]string
+	counts     map[string]int
+}
+
+// redactor is used for all output, nil unless redacting
+var redactor *Redactor
+
+func NewRedactor() *Redactor {
+	return &Redactor{
+		// between 0.5 and 2, it is not shown anywhere
+		scale:      big.NewRat(int64(50+rand.IntN(151)), 100),
+		pseudonyms: make(map[string]string),
+		counts:     make(map[string]int),
+	}
+}
+
+// Pseudonym returns the same name like "account-1" for the same value of the kind
+func (r *Redactor) Pseudonym(kind, value string) string {
+	if value == "" {
+		return value
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := kind + "\x00" + value
+	pseudonym, ok := r.pseudonyms[key]
+	if !ok {
+		r.counts[kind]++
+		pseudonym = fmt.Sprintf("%s-%d", kind, r.counts[kind])
+		r.pseudonyms[key] = pseudonym
+	}
+	return pseudonym
+}
+
+// Redact returns the pseudonym of the identifier when redacting, the identifier itself otherwise
+func Redact(kind, value string) string {
+	if redactor == nil {
+		return value
+	}
+	return redactor.Pseudonym(kind, value)
+}
+
+// ScaleAmount scales the absolute amount when redacting, prices and rates are public and left as is
+func ScaleAmount(value *big.Rat) *big.Rat {
+	if redactor == nil || value == nil {
+		return value
+	}
+	return (&big.Rat{}).Mul(value, redactor.scale)
+}
+
+// pseudonymized log fields and the kinds of their pseudonyms
+var redactedKeys = map[string]string{
+	"account":    "account",
+	"id":         "account",
+	"name":       "name",
+	"ticker":     "asset",
+	"asset":      "asset",
+	"instrument": "instrument",
+	"figi":       "instrument",
+	"isin":       "isin",
+	"accounts":   "account",
+	"to":         "email",
+}
+
+// redactAmount scales the amount when redacting
+func redactAmount(amount Amount) Amount {
+	if redactor == nil {
+		return amount
+	}
+	return NewAmount(ScaleAmount(exact(amount)), amount.Currency)
+}
+
+// redactAmounts scales the amounts by currency when redacting
+func redactAmounts(amounts map[string]Amount) map[string]Amount {
+	if redactor == nil || amounts == nil {
+		return amounts
+	}
+	result := make(map[string]Amount, len(amounts))
+	for currency, amount := range amounts {
+		result[currency] = redactAmount(amount)
+	}
+	return result
+}
+
+// stringCollector collects the elements of a string array field, zap.Strings appends strings only
+type stringCollector struct {
+	zapcore.ArrayEncoder
+	values []string
+}
+
+func (c *stringCollector) AppendString(value string) {
+	c.values = append(c.values, value)
+}
+
+// log fields with public prices, they are not scaled
+var priceKeys = map[string]bool{
+	"price":           true,
+	"price_before":    true,
+	"price_after":     true,
+	"last_price":      true,
+	"portfolio_price": true,
+	"prices":          true,
+}
+
+// redactKey pseudonymizes the portfolio key unless it is a currency
+func redactKey(key string) string {
+	if _, ok := ExchangeRates[key]; ok {
+		return key
+	}
+	return Redact("asset", key)
+}
+
+func redactField(field zapcore.Field) zapcore.Field {
+	switch field.Type {
+	case zapcore.StringType:
+		if kind, ok := redactedKeys[field.Key]; ok {
+			field.String = Redact(kind, field.String)
+		}
+	case zapcore.StringerType:
+		if value, ok := field.Interface.(*big.Rat); ok && !priceKeys[field.Key] {
+			field.Interface = ScaleAmount(value)
+		}
+	case zapcore.ArrayMarshalerType:
+		kind, ok := redactedKeys[field.Key]
+		marshaler, isArray := field.Interface.(zapcore.ArrayMarshaler)
+		if !ok || !isArray {
+			break
+		}
+		collector := &stringCollector{}
+		if marshaler.MarshalLogArray(collector) != nil {
+			return zap.String(field.Key, "redacted")
+		}
+		for i, value := range collector.values {
+			collector.values[i] = Redact(kind, value)
+		}
+		return zap.Strings(field.Key, collector.values)
+	case zapcore.ReflectType:
+		switch value := field.Interface.(type) {
+		case map[string]*big.Rat:
+			result := make(map[string]*big.Rat, len(value))
+			for key, amount := range value {
+				if !priceKeys[field.Key] {
+					amount = ScaleAmount(amount)
+				}
+				result[redactKey(key)] = amount
+			}
+			field.Interface = result
+		case map[string]string:
+			// instrument and asset UIDs and tickers, or amounts already scaled by FormatMoney
+			result := make(map[string]string, len(value))
+			for key, text := range value {
+				if field.Key == "assets" || field.Key == "tickers" {
+					text = Redact("asset", text)
+				}
+				result[redactKey(key)] = text
+			}
+			field.Interface = result
+		default:
+			// operations and requests carry everything at once
+			return zap.String(field.Key, "redacted")
+		}
+	}
+	return field
+}
+
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	result := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		result[i] = redactField(field)
+	}
+	return result
+}
+
+// redactingCore redacts the fields of all log entries
+type redactingCore struct {
+	zapcore.Core
+}
+
+func (c redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return redactingCore{c.Core.With(redactFields(fields))}
+}
+
+func (c redactingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(entry, redactFields(fields))
+}
+
+// RedactLogs makes the logger redact all fields
+func RedactLogs(logger *zap.Logger) *zap.Logger {
+	return logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return redactingCore{core}
+	}))
+}
@@ -0,0 +1,115 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+func TestRedactedOutputs(t *testing.T) {
+	defer func(r *Redactor) { redactor = r }(redactor)
+	redactor = NewRedactor()
+	// a scale of one would leave the amounts as they are
+	redactor.scale = big.NewRat(3, 2)
+	tickers["redacted-asset"], names["redacted-asset"], isins["redacted-asset"] = "SECRETTICKER", "Secret Name", "RU000SECRET1"
+	defer func() {
+		delete(tickers, "redacted-asset")
+		delete(names, "redacted-asset")
+		delete(isins, "redacted-asset")
+	}()
+	date := time.Date(TaxYear, 3, 2, 10, 0, 0, 0, time.UTC)
+	best := big.NewRat(1234567, 100)
+	state := &State{
+		Portfolio:  map[string]*big.Rat{"redacted-asset": big.NewRat(7, 1), "usd": big.NewRat(1234567, 100)},
+		Prices:     map[string]*big.Rat{"redacted-asset": big.NewRat(100, 1)},
+		Accrued:    make(map[string]*big.Rat),
+		Currencies: map[string]string{"redacted-asset": "usd"},
+	}
+	evaluation := &Evaluation{
+		AccountId: "2000123456",
+		Account:   AccountInfo{Id: "2000123456", Name: "Secret Account"},
+		Operations: []*pb.OperationItem{{
+			Id:            "98765432101",
+			Type:          pb.OperationType_OPERATION_TYPE_BUY,
+			InstrumentUid: "redacted-instrument",
+			AssetUid:      "redacted-asset",
+			Date:          timestamppb.New(date),
+			Quantity:      7,
+			Payment:       &pb.MoneyValue{Currency: "usd", Units: -12345, Nano: 670000000},
+		}},
+		Timeline:      Timeline{{Time: date, Aggregate: best}},
+		Current:       best,
+		BestState:     state,
+		BestCost:      map[string]*big.Rat{"usd": best},
+		BestTime:      date,
+		BestAggregate: best,
+	}
+	evaluations := []*Evaluation{evaluation}
+	var logs bytes.Buffer
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(&logs), zap.DebugLevel)
+	logger := RedactLogs(zap.New(core))
+	summary := NewSummary(evaluations, Provenance{})
+	summary.Groups = CombineGroups(logger, map[string][]string{"family": {"2000123456"}}, evaluations)
+
+	dir := t.TempDir()
+	outputs := map[string]func(string) error{
+		"summary.json": func(filename string) error { return WriteJSON(filename, summary.Redacted()) },
+		"ledger.csv":   func(filename string) error { return WriteLedger(filename, Ledger(evaluations)) },
+		"ledger.json":  func(filename string) error { return WriteLedger(filename, Ledger(evaluations)) },
+		"fbar.json":    func(filename string) error { return WriteJSON(filename, NewFBARReport(evaluations, Provenance{})) },
+		"lots.csv": func(filename string) error {
+			return WriteLots(filename, []AccountLots{{Account: "2000123456", Open: []Lot{{
+				Asset: "redacted-asset", Date: date, Quantity: 7, Currency: "usd",
+				Cost: big.NewRat(1234567, 100), CostRUB: big.NewRat(1234567, 100), CostUSD: big.NewRat(1234567, 100),
+			}}}})
+		},
+	}
+	contents := map[string]string{"group logs": logs.String()}
+	for name, write := range outputs {
+		filename := filepath.Join(dir, name)
+		if err := write(filename); err != nil {
+			t.Fatalf("writing %s: %v", name, err)
+		}
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents[name] = string(data)
+	}
+	for name, content := range contents {
+		for _, raw := range []string{"2000123456", "Secret Account", "SECRETTICKER", "Secret Name", "RU000SECRET1",
+			"98765432101", "12345.67", "12346"} {
+			if strings.Contains(content, raw) {
+				t.Errorf("%s contains %q:\n%s", name, raw, content)
+			}
+		}
+		if !strings.Contains(content, "account-1") {
+			t.Errorf("%s does not contain the account pseudonym:\n%s", name, content)
+		}
+	}
+}
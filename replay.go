@@ -0,0 +1,174 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"runtime"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// replayPartition is the number of points valued by one goroutine at a time
+const replayPartition = 1024
+
+// ValuedPoint is a replayed state with its value
+type ValuedPoint struct {
+	Time         time.Time
+	State        *State
+	Cost         map[string]*big.Rat
+	ExcludedCost map[string]*big.Rat
+	Aggregate    *big.Rat
+}
+
+// valuePoints takes up to count next points from the stream starting from the state and values them,
+// the costs are left empty by the fixed-point engine unless the values do not fit in it
+func valuePoints(state *State, stream *UpdateStream, count int, excluded map[string]bool) []ValuedPoint {
+	var engine *FixedEngine
+	if *fixedPoint {
+		engine = NewFixedEngine()
+	}
+	valued := make([]ValuedPoint, 0, count)
+	for range count {
+		state = state.Clone()
+		date, ok := stream.Next(state)
+		if !ok {
+			break
+		}
+		point := ValuedPoint{Time: date, State: state}
+		var fixed bool
+		if engine != nil {
+			var aggregate Fixed
+			aggregate, fixed = engine.Aggregate(state, excluded)
+			point.Aggregate = aggregate.Rat()
+		}
+		// the values too large for the fixed point are valued exactly
+		if !fixed {
+			point.Cost, point.ExcludedCost, point.Aggregate = Cost(state, excluded)
+		}
+		valued = append(valued, point)
+	}
+	return valued
+}
+
+// Replay goes back in time from the current state applying the updates and the prices from the series and
+// evaluates the portfolio at every point of the tax year since the account opening. The states at the partition
+// boundaries are found first, then the partitions are valued in parallel and observed in order.
+func Replay(logger *zap.Logger, evaluation *Evaluation, state *State, updates map[time.Time][]Update,
+	series []*PriceSeries, excluded map[string]bool, months *MonthEnds, movers *MoverTracker,
+	thresholds ThresholdTracker) error {
+	workers := *parallel
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	// updates replace values in the maps, so cloning the maps at the boundaries is enough
+	type partitionStart struct {
+		state  *State
+		stream *UpdateStream
+	}
+	var starts []partitionStart
+	stream := NewUpdateStream(updates, series)
+	boundary := state.Clone()
+	for i := 0; !stream.Done(); i++ {
+		if i%replayPartition == 0 {
+			starts = append(starts, partitionStart{boundary.Clone(), stream.Clone()})
+		}
+		stream.Next(boundary)
+	}
+	results := make([]chan []ValuedPoint, len(starts))
+	for i := range results {
+		results[i] = make(chan []ValuedPoint, 1)
+	}
+	// at most as many partitions as workers are valued or waiting to be observed
+	slots := make(chan struct{}, workers)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for i, start := range starts {
+			select {
+			case slots <- struct{}{}:
+			case <-done:
+				return
+			}
+			go func() {
+				results[i] <- valuePoints(start.state, start.stream, replayPartition, excluded)
+			}()
+		}
+	}()
+
+	for _, result := range results {
+		for _, point := range <-result {
+			err := observePoint(logger, evaluation, point, excluded, months, movers, thresholds)
+			if err != nil {
+				return err
+			}
+		}
+		<-slots
+	}
+	return nil
+}
+
+// observePoint records the valued point in the evaluation and the trackers
+func observePoint(logger *zap.Logger, evaluation *Evaluation, point ValuedPoint, excluded map[string]bool,
+	months *MonthEnds, movers *MoverTracker, thresholds ThresholdTracker) error {
+	date, state := point.Time, point.State
+	cost, excludedCost, aggregate := point.Cost, point.ExcludedCost, point.Aggregate
+	// the updates are keyed by the original times, reports use the reporting timezone
+	local := date.In(Location)
+	logger.Debug("new portfolio",
+		zap.Time("time", local),
+		zap.Any("portfolio", ToTickers(state.Portfolio)),
+		zap.Any("cost", cost),
+		zap.Any("excluded_cost", excludedCost),
+		zap.Stringer("aggregate", aggregate))
+	months.Observe(date, state)
+	movers.Observe(local, state, aggregate)
+	// there was no account before its opening and after its closure
+	if !InTaxYear(date) || date.Before(evaluation.Account.Start()) || date.After(evaluation.Account.End()) {
+		return nil
+	}
+	// the fixed-point value is replaced by the exact one where the costs are needed
+	if cost == nil && (points != nil || evaluation.YearEndCost == nil || evaluation.BestAggregate.Cmp(aggregate) < 0) {
+		cost, excludedCost, aggregate = Cost(state, excluded)
+	}
+	if evaluation.YearEndCost == nil {
+		evaluation.YearEndTime = local
+		evaluation.YearEndCost = cost
+	}
+	evaluation.Timeline = append(evaluation.Timeline, Point{Time: local, Aggregate: aggregate})
+	err := points.Emit(evaluation.AccountId, local, aggregate, cost)
+	if err != nil {
+		logger.Error("error streaming point", zap.Error(err))
+		return err
+	}
+	thresholds.Observe(local, aggregate)
+	evaluation.Asset.Observe(local, state, aggregate)
+	evaluation.Explanation.Observe(local, state)
+	securities, cash := SplitValue(state, aggregate)
+	observeMaximum(&evaluation.BestSecurities, local, securities)
+	observeMaximum(&evaluation.BestCash, local, cash)
+	if evaluation.BestAggregate.Cmp(aggregate) < 0 && countsForMaximum(local) {
+		evaluation.BestState = state
+		evaluation.BestCost = cost
+		evaluation.BestExcludedCost = excludedCost
+		evaluation.BestTime = local
+		evaluation.BestAggregate = aggregate
+		logger.Debug("new best shown above")
+	}
+	return nil
+}
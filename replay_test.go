@@ -0,0 +1,133 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+const (
+	benchmarkAssets = 30
+	benchmarkHours  = 24 * 365
+)
+
+// benchmarkCandles returns hourly candles of the tax year with a night gap every day
+func benchmarkCandles(hours int) []*pb.HistoricCandle {
+	start := time.Date(TaxYear, 1, 1, 0, 0, 0, 0, time.UTC)
+	candles := make([]*pb.HistoricCandle, 0, hours)
+	for hour := range hours {
+		if hour%24 < 7 {
+			continue
+		}
+		candles = append(candles, &pb.HistoricCandle{
+			Time: timestamppb.New(start.Add(time.Duration(hour) * time.Hour)),
+			High: &pb.Quotation{Units: int64(100 + hour%50), Nano: int32(hour%1000) * 1_000_000},
+		})
+	}
+	return candles
+}
+
+// benchmarkReplay prepares the state and the price series of a portfolio of shares with a year of hourly candles
+func benchmarkReplay() (*State, []*PriceSeries) {
+	logger := zap.NewNop()
+	state := &State{
+		Portfolio:  map[string]*big.Rat{"usd": big.NewRat(1000, 1), "rub": big.NewRat(-500, 3)},
+		Prices:     make(map[string]*big.Rat),
+		Accrued:    make(map[string]*big.Rat),
+		Currencies: make(map[string]string),
+	}
+	var series []*PriceSeries
+	latest := make(map[string]LatestPrice)
+	candles := benchmarkCandles(benchmarkHours)
+	for i := range benchmarkAssets {
+		asset := fmt.Sprintf("asset%d", i)
+		state.Portfolio[asset] = big.NewRat(int64(10+i), 1)
+		series = append(series, NewPriceSeries(logger, latest, asset, "rub", nil, nil, nil, candles, defaultStaleGap))
+	}
+	for asset, price := range latest {
+		state.Prices[asset] = price.Price
+		state.Currencies[asset] = price.Currency
+	}
+	return state, series
+}
+
+func BenchmarkUpdateStream(b *testing.B) {
+	state, series := benchmarkReplay()
+	b.ReportAllocs()
+	for b.Loop() {
+		stream := NewUpdateStream(make(map[time.Time][]Update), series)
+		replayed := state.Clone()
+		for !stream.Done() {
+			stream.Next(replayed)
+		}
+	}
+}
+
+func benchmarkReplayEngine(b *testing.B, fixed bool, workers int) {
+	defer func(previous bool, workers int) { *fixedPoint, *parallel = previous, workers }(*fixedPoint, *parallel)
+	*fixedPoint, *parallel = fixed, workers
+	logger := zap.NewNop()
+	state, series := benchmarkReplay()
+	b.ReportAllocs()
+	for b.Loop() {
+		evaluation := &Evaluation{BestAggregate: &big.Rat{}}
+		var months MonthEnds
+		movers := NewMoverTracker(logger, MoversOptions{}, nil)
+		thresholds := NewThresholdTracker(logger, nil)
+		err := Replay(logger, evaluation, state, make(map[time.Time][]Update), series, nil, &months, movers, thresholds)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReplay(b *testing.B) {
+	benchmarkReplayEngine(b, false, 1)
+}
+
+func BenchmarkReplayFixed(b *testing.B) {
+	benchmarkReplayEngine(b, true, 1)
+}
+
+func BenchmarkReplayParallel(b *testing.B) {
+	benchmarkReplayEngine(b, false, 0)
+}
+
+func TestOperationWithoutPayment(t *testing.T) {
+	state := &State{Portfolio: map[string]*big.Rat{"asset": big.NewRat(10, 1), "rub": big.NewRat(500, 1)}}
+	for _, operation := range []*pb.OperationItem{
+		{Type: pb.OperationType_OPERATION_TYPE_BUY, AssetUid: "asset", Quantity: 4},
+		{Type: pb.OperationType_OPERATION_TYPE_BROKER_FEE},
+	} {
+		update, err := OperationToUpdate(operation)
+		if err != nil {
+			t.Fatal(err)
+		}
+		update(state)
+	}
+	if len(state.Portfolio) != 2 || state.Portfolio["asset"].Cmp(big.NewRat(6, 1)) != 0 ||
+		state.Portfolio["rub"].Cmp(big.NewRat(500, 1)) != 0 {
+		t.Errorf("portfolio = %v, want asset 6 and rub 500", state.Portfolio)
+	}
+}
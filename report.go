@@ -0,0 +1,222 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"html/template"
+	"io"
+	"maps"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Sources of the values in the reports
+const (
+	RateSource = "U.S. Treasury Reporting Rates of Exchange for the last day of the tax year, " +
+		"https://fiscaldata.treasury.gov/datasets/treasury-reporting-rates-exchange/"
+	PriceSource = "T-Bank Invest API: the current portfolio, the operations log and hourly candles"
+	Methodology = "The current portfolio is taken from the broker, and the operations of the account are " +
+		"applied to it in reverse order to reconstruct its holdings at every moment of the tax year. The " +
+		"holdings are valued at the highest price of every hourly candle, or by daily candles and the last " +
+		"close price when there are none, and converted to USD at the fixed exchange rates of the report. The maximum is the " +
+		"largest value found, each amount is rounded by the stated policy."
+)
+
+// Report is the human readable summary of the run shared by the HTML and PDF formats
+type Report struct {
+	TaxYear   int
+	Generated time.Time
+	Rounding  string
+	Accounts  []ReportAccount
+	// combined maximum of several accounts, nil for a single account
+	Combined     *big.Rat
+	CombinedTime time.Time
+	Groups       []ReportGroup
+	Maximum      *big.Rat
+	Rates        []ReportRate
+	Provenance   Provenance
+}
+
+type ReportAccount struct {
+	Id   string
+	Name string
+	Type string
+	// closure during the tax year, the account is evaluated until then
+	Closed   *time.Time
+	BestTime time.Time
+	Best     *big.Rat
+	Current  *big.Rat
+	Excluded *big.Rat
+	// value at the peak by currency
+	Cost []ReportAmount
+}
+
+// ReportGroup is the combined maximum of a group of accounts
+type ReportGroup struct {
+	Name     string
+	Best     *big.Rat
+	BestTime time.Time
+}
+
+type ReportAmount struct {
+	Currency string
+	Amount   *big.Rat
+	// nil without an exchange rate
+	USD *big.Rat
+}
+
+type ReportRate struct {
+	Currency string
+	// units of the currency per USD
+	Rate *big.Rat
+}
+
+func (r ReportRate) String() string {
+	return formatDecimal(r.Rate)
+}
+
+// exact returns the exact value of the amount
+func exact(amount Amount) *big.Rat {
+	value, ok := (&big.Rat{}).SetString(amount.Exact)
+	if !ok {
+		return &big.Rat{}
+	}
+	return value
+}
+
+func NewReport(summary *Summary, now time.Time) Report {
+	report := Report{
+		TaxYear:    summary.TaxYear,
+		Generated:  now.In(Location),
+		Rounding:   summary.Rounding,
+		Provenance: summary.Provenance,
+	}
+	for _, account := range summary.Accounts {
+		reportAccount := ReportAccount{
+			Id:       Redact("account", account.AccountId),
+			Name:     Redact("name", account.Account.Name),
+			Type:     account.Account.Type,
+			BestTime: account.BestTime.In(Location),
+			Best:     exact(account.Best),
+			Current:  exact(account.Current),
+			Excluded: exact(account.Excluded),
+		}
+		if account.Account.IISType != "" {
+			reportAccount.Type += " " + account.Account.IISType
+		}
+		if account.Account.ClosedInTaxYear() {
+			reportAccount.Closed = account.Account.ClosedDate
+		}
+		for _, currency := range slices.Sorted(maps.Keys(account.BestCost)) {
+			amount := ReportAmount{Currency: currency, Amount: exact(account.BestCost[currency])}
+			if rate, ok := ExchangeRates[currency]; ok {
+				amount.USD = (&big.Rat{}).Quo(amount.Amount, rate)
+			}
+			reportAccount.Cost = append(reportAccount.Cost, amount)
+		}
+		report.Accounts = append(report.Accounts, reportAccount)
+	}
+	if len(report.Accounts) > 0 {
+		report.Maximum = report.Accounts[0].Best
+	}
+	if summary.Combined != nil {
+		report.Combined = exact(summary.Combined.Best)
+		report.CombinedTime = summary.Combined.BestTime.In(Location)
+		report.Maximum = report.Combined
+	}
+	for _, group := range summary.Groups {
+		report.Groups = append(report.Groups, ReportGroup{
+			Name:     group.Name,
+			Best:     exact(group.Best),
+			BestTime: group.BestTime.In(Location),
+		})
+	}
+	for _, currency := range slices.Sorted(maps.Keys(ExchangeRates)) {
+		if currency != "usd" {
+			report.Rates = append(report.Rates, ReportRate{Currency: currency, Rate: ExchangeRates[currency]})
+		}
+	}
+	return report
+}
+
+// Subject is the title of the report, e.g. for emails
+func (r Report) Subject() string {
+	return T("Maximum account value") + " " + FormatUSD(r.Maximum)
+}
+
+func formatReportTime(t time.Time) string {
+	return t.Format("2006-01-02 15:04 MST")
+}
+
+var htmlReport = template.Must(template.New("report").Funcs(template.FuncMap{
+	"T":     T,
+	"time":  formatReportTime,
+	"usd":   FormatUSD,
+	"money": FormatMoney,
+	"upper": strings.ToUpper,
+	"methodology": func() string {
+		return Methodology
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Subject}}</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 0.25em 0.5em; text-align: left; }
+td.amount { text-align: right; }
+</style>
+</head>
+<body>
+<h1>{{T "Maximum account value"}} {{.TaxYear}}</h1>
+{{if .Combined}}<p>{{T "Combined maximum"}}: <b>{{usd .Combined}}</b>, {{time .CombinedTime}}</p>{{end}}
+{{range .Groups}}<p>{{T "Group"}} {{.Name}}: <b>{{usd .Best}}</b>, {{time .BestTime}}</p>
+{{end}}{{range .Accounts}}
+<h2>{{T "Account"}} {{.Id}}{{if .Name}} {{.Name}}{{end}}{{if .Type}}, {{.Type}}{{end}}{{if .Closed}}, {{T "closed"}} {{.Closed.Format "2006-01-02"}}{{end}}</h2>
+<table>
+<tr><th>{{T "Maximum value"}}</th><td class="amount"><b>{{usd .Best}}</b></td></tr>
+<tr><th>{{T "Maximum time"}}</th><td>{{time .BestTime}}</td></tr>
+<tr><th>{{T "Current value"}}</th><td class="amount">{{usd .Current}}</td></tr>
+<tr><th>{{T "Excluded assets at maximum"}}</th><td class="amount">{{usd .Excluded}}</td></tr>
+</table>
+<table>
+<tr><th>{{T "CURRENCY"}}</th><th>{{T "AMOUNT"}}</th><th>USD</th></tr>
+{{range .Cost}}<tr><td>{{.Currency}}</td><td class="amount">{{money .Amount .Currency}}</td><td class="amount">{{if .USD}}{{usd .USD}}{{end}}</td></tr>
+{{end}}</table>
+{{end}}
+<table>
+<tr><th>{{T "CURRENCY"}}</th><th>{{T "Rate per USD"}}</th></tr>
+{{range .Rates}}<tr><td>{{upper .Currency}}</td><td class="amount">{{.}}</td></tr>
+{{end}}</table>
+<p>{{methodology}}</p>
+<h2>{{T "Provenance"}}</h2>
+<ul>
+{{range .Provenance.Lines}}<li>{{.}}</li>
+{{end}}</ul>
+<p>{{T "Generated"}} {{time .Generated}}, tbank-invest {{.Provenance.Build}}</p>
+</body>
+</html>
+`))
+
+// WriteHTML renders the report as a standalone HTML page
+func (r Report) WriteHTML(w io.Writer) error {
+	return htmlReport.Execute(w, r)
+}
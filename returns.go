@@ -0,0 +1,168 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var NoTimelineError = errors.New("no values during the tax year")
+var NoXIRRError = errors.New("money-weighted return cannot be found")
+
+// CashFlow is an external money flow of the account in USD, deposits are positive
+type CashFlow struct {
+	Time   time.Time
+	Amount *big.Rat
+}
+
+// CashFlows returns deposits and withdrawals of the tax year
+func (e *Evaluation) CashFlows() []CashFlow {
+	var flows []CashFlow
+	for _, operation := range e.Operations {
+		if operation.Payment == nil ||
+			!slices.Contains(DepositTypes, operation.Type) && !slices.Contains(WithdrawalTypes, operation.Type) {
+			continue
+		}
+		date := operation.Date.AsTime()
+		if !InTaxYear(date) {
+			continue
+		}
+		amount := (&big.Rat{}).Quo(ToRat(operation.Payment), ExchangeRates[operation.Payment.Currency])
+		flows = append(flows, CashFlow{Time: date, Amount: amount})
+	}
+	return flows
+}
+
+// periodFlows returns the cash flows of the period: the value at a point is the one just before the events
+// at its time, so the flows at the start are in the period and the ones at the end are not
+func (e *Evaluation) periodFlows(start, end time.Time) []CashFlow {
+	var flows []CashFlow
+	for _, flow := range e.CashFlows() {
+		if !flow.Time.Before(start) && flow.Time.Before(end) {
+			flows = append(flows, flow)
+		}
+	}
+	return flows
+}
+
+// period returns the start and the end of the evaluated part of the tax year with the values at them
+func (e *Evaluation) period(now time.Time) (start, end time.Time, startValue, endValue *big.Rat, err error) {
+	if len(e.Timeline) == 0 {
+		return start, end, nil, nil, NoTimelineError
+	}
+	first, last := e.Timeline[0], e.Timeline[len(e.Timeline)-1]
+	if InTaxYear(now) && !e.Account.ClosedInTaxYear() {
+		return first.Time, now, first.Aggregate, e.Current, nil
+	}
+	return first.Time, last.Time, first.Aggregate, last.Aggregate, nil
+}
+
+// TWR returns the time-weighted return of the tax year, the value at each flow is the one just before it
+func (e *Evaluation) TWR(now time.Time) (*big.Rat, error) {
+	start, end, startValue, endValue, err := e.period(now)
+	if err != nil {
+		return nil, err
+	}
+	growth := big.NewRat(1, 1)
+	previous := startValue
+	for _, flow := range e.periodFlows(start, end) {
+		before := e.ValueAt(flow.Time)
+		if previous.Sign() != 0 {
+			growth.Mul(growth, (&big.Rat{}).Quo(before, previous))
+		}
+		previous = AddRat(before, flow.Amount)
+	}
+	if previous.Sign() != 0 {
+		growth.Mul(growth, (&big.Rat{}).Quo(endValue, previous))
+	}
+	return growth.Sub(growth, big.NewRat(1, 1)), nil
+}
+
+// XIRR returns the annualized money-weighted return of the tax year
+func (e *Evaluation) XIRR(now time.Time) (float64, error) {
+	start, end, startValue, endValue, err := e.period(now)
+	if err != nil {
+		return 0, err
+	}
+	// flows from the investor's point of view: the starting value is invested,
+	// deposits are paid and withdrawals and the final value are received
+	type flow struct {
+		years  float64
+		amount float64
+	}
+	years := func(date time.Time) float64 {
+		return date.Sub(start).Hours() / 24 / 365
+	}
+	startAmount, _ := startValue.Float64()
+	endAmount, _ := endValue.Float64()
+	flows := []flow{{0, -startAmount}}
+	for _, cashFlow := range e.periodFlows(start, end) {
+		amount, _ := cashFlow.Amount.Float64()
+		flows = append(flows, flow{years(cashFlow.Time), -amount})
+	}
+	flows = append(flows, flow{years(end), endAmount})
+
+	npv := func(rate float64) float64 {
+		sum := 0.0
+		for _, f := range flows {
+			sum += f.amount / math.Pow(1+rate, f.years)
+		}
+		return sum
+	}
+	// bisection, the present value decreases with the rate for a usual investment
+	low, high := -0.9999, 1000.0
+	lowValue, highValue := npv(low), npv(high)
+	if math.IsNaN(lowValue) || math.IsNaN(highValue) || (lowValue > 0) == (highValue > 0) {
+		return 0, NoXIRRError
+	}
+	for range 200 {
+		middle := (low + high) / 2
+		middleValue := npv(middle)
+		if (middleValue > 0) == (lowValue > 0) {
+			low, lowValue = middle, middleValue
+		} else {
+			high = middle
+		}
+	}
+	return (low + high) / 2, nil
+}
+
+func reportReturns(logger *zap.Logger, evaluation *Evaluation, now time.Time) {
+	one := big.NewRat(1, 1)
+	twr, err := evaluation.TWR(now)
+	if err != nil {
+		logger.Warn("cannot calculate time-weighted return", zap.String("account", evaluation.AccountId), zap.Error(err))
+		return
+	}
+	fields := []zap.Field{
+		zap.String("account", evaluation.AccountId),
+		zap.String("twr", percent(twr, one)),
+	}
+	xirr, err := evaluation.XIRR(now)
+	if err != nil {
+		logger.Warn("cannot calculate money-weighted return", zap.String("account", evaluation.AccountId), zap.Error(err))
+	} else {
+		fields = append(fields, zap.String("xirr", percent((&big.Rat{}).SetFloat64(xirr), one)))
+	}
+	logger.Info("returns for the tax year", fields...)
+}
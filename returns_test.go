@@ -0,0 +1,108 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+func TestReturns(t *testing.T) {
+	day := func(n int) time.Time {
+		return time.Date(TaxYear, 1, 1+n, 0, 0, 0, 0, Location)
+	}
+	point := func(n int, value int64) Point {
+		return Point{Time: day(n), Aggregate: big.NewRat(value, 1)}
+	}
+	flow := func(n int, amount int64) *pb.OperationItem {
+		operationType := pb.OperationType_OPERATION_TYPE_INPUT
+		if amount < 0 {
+			operationType = pb.OperationType_OPERATION_TYPE_OUTPUT
+		}
+		return &pb.OperationItem{
+			Type:    operationType,
+			Date:    timestamppb.New(day(n)),
+			Payment: &pb.MoneyValue{Currency: "usd", Units: amount},
+		}
+	}
+	opened, closed := day(59), day(273)
+	// the values are evaluated after the tax year, so it ends at the last point
+	now := time.Date(TaxYear+1, 2, 1, 0, 0, 0, 0, Location)
+	for _, test := range []struct {
+		name       string
+		account    AccountInfo
+		timeline   Timeline
+		operations []*pb.OperationItem
+		twr        *big.Rat
+		xirr       float64
+	}{
+		{
+			name:     "no flows",
+			timeline: Timeline{point(0, 100), point(364, 110)},
+			twr:      big.NewRat(1, 10),
+			xirr:     0.100288,
+		},
+		{
+			// the point at the deposit is the value just before it
+			name:       "mid-year deposit",
+			timeline:   Timeline{point(0, 100), point(182, 110), point(364, 231)},
+			operations: []*pb.OperationItem{flow(182, 100)},
+			twr:        big.NewRat(21, 100),
+			xirr:       0.210634,
+		},
+		{
+			name:       "withdrawal",
+			timeline:   Timeline{point(0, 200), point(90, 220), point(364, 110)},
+			operations: []*pb.OperationItem{flow(90, -120)},
+			twr:        big.NewRat(21, 100),
+			xirr:       0.267815,
+		},
+		{
+			// the opening deposit is at the first point, the period starts at it
+			name:       "mid-year start",
+			account:    AccountInfo{OpenedDate: &opened},
+			timeline:   Timeline{point(59, 0), point(364, 1100)},
+			operations: []*pb.OperationItem{flow(59, 1000)},
+			twr:        big.NewRat(1, 10),
+			xirr:       0.120819,
+		},
+		{
+			// the last point is the value just before the closing withdrawal, it is not in the period
+			name:       "withdrawal at the last point",
+			account:    AccountInfo{ClosedDate: &closed},
+			timeline:   Timeline{point(0, 400), point(273, 500)},
+			operations: []*pb.OperationItem{flow(273, -500)},
+			twr:        big.NewRat(1, 4),
+			xirr:       0.347623,
+		},
+	} {
+		evaluation := &Evaluation{Account: test.account, Timeline: test.timeline, Operations: test.operations}
+		twr, err := evaluation.TWR(now)
+		if err != nil || twr.Cmp(test.twr) != 0 {
+			t.Errorf("%s: TWR() = %v, %v, want %v", test.name, twr, err, test.twr)
+		}
+		xirr, err := evaluation.XIRR(now)
+		if err != nil || math.Abs(xirr-test.xirr) > 1e-6 {
+			t.Errorf("%s: XIRR() = %v, %v, want %v", test.name, xirr, err, test.xirr)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"opensource.tbank.ru/invest/invest-go/investgo"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// SandboxEndPoint serves the same API for sandbox accounts with real market data
+const SandboxEndPoint = "sandbox-invest-public-api.tbank.ru:443"
+
+// Sample positions of the demo account
+var demoPositions = []struct {
+	Ticker    string
+	ClassCode string
+	Quantity  int64
+}{
+	{"SBER", "TQBR", 10},
+	{"GAZP", "TQBR", 10},
+	{"LKOH", "TQBR", 1},
+	{"TMOS", "TQTF", 100},
+}
+
+// CreateDemoAccount opens a sandbox account with some cash and sample positions
+func CreateDemoAccount(client *investgo.Client, logger *zap.Logger) (string, error) {
+	sandbox := client.NewSandboxServiceClient()
+	in := client.NewInstrumentsServiceClient()
+	logger.Info("opening sandbox account")
+	start := time.Now()
+	account, err := sandbox.OpenSandboxAccount()
+	TraceCall("OpenSandboxAccount", nil, start, account, err)
+	if err != nil {
+		logger.Error("error opening sandbox account", zap.Error(err))
+		return "", err
+	}
+	for _, payIn := range []*investgo.SandboxPayInRequest{
+		{AccountId: account.AccountId, Currency: "RUB", Unit: 100_000},
+		{AccountId: account.AccountId, Currency: "USD", Unit: 1_000},
+	} {
+		start = time.Now()
+		resp, err := sandbox.SandboxPayIn(payIn)
+		TraceCall("SandboxPayIn", payIn, start, resp, err)
+		if err != nil {
+			logger.Error("error paying in to sandbox account", zap.String("currency", payIn.Currency), zap.Error(err))
+			return "", err
+		}
+	}
+	for _, position := range demoPositions {
+		AwaitQuota("ShareBy")
+		start = time.Now()
+		share, err := in.ShareByTicker(position.Ticker, position.ClassCode)
+		TraceCall("ShareBy", position, start, share, err)
+		if err != nil {
+			logger.Error("error getting demo instrument", zap.String("ticker", position.Ticker), zap.Error(err))
+			return "", err
+		}
+		order := &investgo.PostOrderRequest{
+			InstrumentId: share.Instrument.Uid,
+			Quantity:     position.Quantity,
+			Direction:    pb.OrderDirection_ORDER_DIRECTION_BUY,
+			AccountId:    account.AccountId,
+			OrderType:    pb.OrderType_ORDER_TYPE_MARKET,
+			OrderId:      fmt.Sprintf("demo-%s-%d", position.Ticker, time.Now().UnixNano()),
+		}
+		start = time.Now()
+		resp, err := sandbox.PostSandboxOrder(order)
+		TraceCall("PostSandboxOrder", order, start, resp, err)
+		if err != nil {
+			logger.Error("error buying demo position", zap.String("ticker", position.Ticker), zap.Error(err))
+			return "", err
+		}
+	}
+	logger.Info("sandbox account is ready, set it as AccountId to evaluate it again",
+		zap.String("account", account.AccountId))
+	return account.AccountId, nil
+}
@@ -0,0 +1,299 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var InvalidScheduleError = errors.New("invalid cron expression")
+
+// A schedule without a matching time in this period never fires, e.g. on February 30
+const maxScheduleSearch = 5 * 366 * 24 * time.Hour
+
+// Schedule is a parsed cron expression: minute, hour, day of month, month and day of week
+type Schedule struct {
+	minutes, hours, days, months, weekdays uint64
+	// when both days and weekdays are restricted, either of them matches, as in cron
+	anyDay, anyWeekday bool
+}
+
+var scheduleMacros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+// ParseSchedule parses a standard five-field cron expression with lists, ranges and steps
+func ParseSchedule(expression string) (*Schedule, error) {
+	if macro, ok := scheduleMacros[expression]; ok {
+		expression = macro
+	}
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: %q has %d fields instead of 5", InvalidScheduleError, expression, len(fields))
+	}
+	var schedule Schedule
+	var err error
+	bounds := []struct {
+		set      *uint64
+		min, max int
+	}{
+		{&schedule.minutes, 0, 59},
+		{&schedule.hours, 0, 23},
+		{&schedule.days, 1, 31},
+		{&schedule.months, 1, 12},
+		{&schedule.weekdays, 0, 7},
+	}
+	for i, field := range fields {
+		*bounds[i].set, err = parseScheduleField(field, bounds[i].min, bounds[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %w", InvalidScheduleError, field, err)
+		}
+	}
+	// 7 is Sunday too
+	if schedule.weekdays&(1<<7) != 0 {
+		schedule.weekdays |= 1
+	}
+	// as in cron, a field starting with an asterisk, e.g. */2, does not restrict the other day field
+	schedule.anyDay = strings.HasPrefix(fields[2], "*")
+	schedule.anyWeekday = strings.HasPrefix(fields[4], "*")
+	return &schedule, nil
+}
+
+func parseScheduleField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if before, after, ok := strings.Cut(part, "/"); ok {
+			var err error
+			step, err = strconv.Atoi(after)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", after)
+			}
+			part = before
+		}
+		from, to := min, max
+		if part != "*" {
+			before, after, isRange := strings.Cut(part, "-")
+			var err error
+			from, err = strconv.Atoi(before)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", before)
+			}
+			to = from
+			if isRange {
+				to, err = strconv.Atoi(after)
+				if err != nil {
+					return 0, fmt.Errorf("invalid value %q", after)
+				}
+			} else if step > 1 {
+				to = max
+			}
+		}
+		if from < min || to > max || from > to {
+			return 0, fmt.Errorf("%d-%d is out of range %d-%d", from, to, min, max)
+		}
+		for value := from; value <= to; value += step {
+			set |= 1 << value
+		}
+	}
+	return set, nil
+}
+
+func (s *Schedule) matchesDay(date time.Time) bool {
+	day := s.days&(1<<date.Day()) != 0
+	weekday := s.weekdays&(1<<int(date.Weekday())) != 0
+	if s.anyDay || s.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
+
+// Next returns the first scheduled time after the given one in its location,
+// the zero time if there is none
+func (s *Schedule) Next(after time.Time) time.Time {
+	date := after.Truncate(time.Minute).Add(time.Minute)
+	limit := after.Add(maxScheduleSearch)
+	for date.Before(limit) {
+		year, month, day := date.Date()
+		location := date.Location()
+		switch {
+		case s.months&(1<<int(month)) == 0:
+			date = time.Date(year, month+1, 1, 0, 0, 0, 0, location)
+		case !s.matchesDay(date):
+			date = time.Date(year, month, day+1, 0, 0, 0, 0, location)
+		case s.hours&(1<<date.Hour()) == 0:
+			date = time.Date(year, month, day, date.Hour()+1, 0, 0, 0, location)
+		case s.minutes&(1<<date.Minute()) == 0:
+			date = date.Add(time.Minute)
+		default:
+			return date
+		}
+	}
+	return time.Time{}
+}
+
+// Notification flags are removed from the arguments of the scheduled runs and passed in -scheduled-notify
+// together with the webhooks, so the runs send them only when the yearly maximum has changed
+var scheduledNotifications = []string{"email", "sheets"}
+
+// withoutFlags removes the flags from the arguments of the scheduled runs and returns the removed ones,
+// flags end at the first non-flag argument as in the flag package
+func withoutFlags(args []string, names ...string) (result, removed []string) {
+	result = make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || arg == "-" || !strings.HasPrefix(arg, "-") {
+			return append(result, args[i:]...), removed
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		takesValue := false
+		if f := flag.Lookup(name); f != nil && !hasValue {
+			boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool })
+			takesValue = !ok || !boolFlag.IsBoolFlag()
+		}
+		if !slices.Contains(names, name) {
+			result = append(result, arg)
+			if takesValue && i+1 < len(args) {
+				result = append(result, args[i+1])
+			}
+		} else if !slices.Contains(removed, name) {
+			removed = append(removed, name)
+		}
+		if takesValue {
+			i++
+		}
+	}
+	return result, removed
+}
+
+// scheduledNotify tells whether the run started by -schedule sends the notification, it is sent when
+// the yearly maximum has changed since the previous run
+func scheduledNotify(notification string, previous []RunRecord, current RunRecord) bool {
+	if *scheduledNotifyList == "" || !slices.Contains(strings.Split(*scheduledNotifyList, ","), notification) {
+		return false
+	}
+	return len(previous) == 0 || MaximumChanged(previous[len(previous)-1], current)
+}
+
+// MaximumChanged tells whether the best value or time of any account differs between the runs
+func MaximumChanged(previous, current RunRecord) bool {
+	if len(previous.Accounts) != len(current.Accounts) {
+		return true
+	}
+	best := make(map[string]AccountRecord, len(previous.Accounts))
+	for _, account := range previous.Accounts {
+		best[account.AccountId] = account
+	}
+	for _, account := range current.Accounts {
+		before, ok := best[account.AccountId]
+		if !ok || before.Best != account.Best || !before.BestTime.Equal(account.BestTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// RunScheduled re-runs the evaluation in a child process at every scheduled time, so each run starts with
+// empty caches, and prints its reports and sends the notifications only when the yearly maximum has changed
+func RunScheduled(logger *zap.Logger, schedule *Schedule, historyFile string) int {
+	executable, err := os.Executable()
+	if err != nil {
+		logger.Error("error finding the executable for scheduled runs", zap.Error(err))
+		return ExitFailure
+	}
+	args, notifications := withoutFlags(os.Args[1:], append([]string{"schedule", "scheduled-notify"},
+		scheduledNotifications...)...)
+	notifications = slices.DeleteFunc(notifications, func(name string) bool {
+		return !slices.Contains(scheduledNotifications, name)
+	})
+	args = append([]string{"-scheduled-notify=" + strings.Join(append(notifications, "webhooks"), ",")}, args...)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	for {
+		next := schedule.Next(time.Now().In(Location))
+		if next.IsZero() {
+			logger.Error("schedule never fires")
+			return ExitConfig
+		}
+		logger.Info("waiting for the next scheduled run", zap.Time("time", next))
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			logger.Info("scheduled runs are stopped")
+			return ExitSuccess
+		case <-timer.C:
+		}
+
+		history, err := LoadHistory(historyFile)
+		if err != nil {
+			logger.Error("error loading run history", zap.String("file", historyFile), zap.Error(err))
+			return ExitCode(err)
+		}
+		var previous *RunRecord
+		if len(history) > 0 {
+			previous = &history[len(history)-1]
+		}
+
+		var output bytes.Buffer
+		cmd := exec.CommandContext(ctx, executable, args...)
+		cmd.Stdout = &output
+		cmd.Stderr = os.Stderr
+		err = cmd.Run()
+		var exitErr *exec.ExitError
+		if err != nil && !errors.As(err, &exitErr) {
+			logger.Error("error starting scheduled run", zap.Error(err))
+			return ExitFailure
+		}
+		code := cmd.ProcessState.ExitCode()
+		logger.Info("scheduled run finished", zap.Int("exit_code", code))
+
+		history, err = LoadHistory(historyFile)
+		if err != nil {
+			logger.Error("error loading run history", zap.String("file", historyFile), zap.Error(err))
+			return ExitCode(err)
+		}
+		if len(history) == 0 || (previous != nil && history[len(history)-1].Time.Equal(previous.Time)) {
+			logger.Warn("scheduled run has not recorded its results", zap.Int("exit_code", code))
+			os.Stdout.Write(output.Bytes())
+			continue
+		}
+		if previous != nil && !MaximumChanged(*previous, history[len(history)-1]) {
+			logger.Info("yearly maximum is unchanged")
+			continue
+		}
+		logger.Info("yearly maximum has changed")
+		os.Stdout.Write(output.Bytes())
+	}
+}
@@ -0,0 +1,97 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// Wednesday
+	after := time.Date(2025, 1, 15, 6, 30, 20, 0, time.UTC)
+	for _, test := range []struct {
+		expression string
+		want       time.Time
+	}{
+		{"0 6 * * *", time.Date(2025, 1, 16, 6, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, 1, 15, 6, 45, 0, 0, time.UTC)},
+		{"31 6 * * *", time.Date(2025, 1, 15, 6, 31, 0, 0, time.UTC)},
+		{"0 9-17/4 * * 1-5", time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)},
+		{"0 0 1 */3 *", time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2025, 1, 19, 0, 0, 0, 0, time.UTC)},
+		// either the day of month or the day of week matches
+		{"0 0 20 * 5", time.Date(2025, 1, 17, 0, 0, 0, 0, time.UTC)},
+		// both match when either field starts with an asterisk: an odd day on Monday
+		{"0 0 */2 * 1", time.Date(2025, 1, 27, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+		{"@monthly", time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+	} {
+		schedule, err := ParseSchedule(test.expression)
+		if err != nil {
+			t.Errorf("%q: %v", test.expression, err)
+			continue
+		}
+		if got := schedule.Next(after); !got.Equal(test.want) {
+			t.Errorf("%q next = %s, want %s", test.expression, got, test.want)
+		}
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, expression := range []string{"0 6 * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := ParseSchedule(expression)
+		if !errors.Is(err, InvalidScheduleError) {
+			t.Errorf("%q error = %v, want %v", expression, err, InvalidScheduleError)
+		}
+	}
+}
+
+func TestWithoutFlags(t *testing.T) {
+	args := []string{"-schedule", "0 6 * * *", "-fast", "--schedule=@daily", "-email", "-points", "-schedule",
+		"broker-report", "-schedule"}
+	want := []string{"-fast", "-points", "-schedule", "broker-report", "-schedule"}
+	got, removed := withoutFlags(args, "schedule", "email", "sheets")
+	if !slices.Equal(got, want) {
+		t.Errorf("withoutFlags = %q, want %q", got, want)
+	}
+	if !slices.Equal(removed, []string{"schedule", "email"}) {
+		t.Errorf("removed = %q, want schedule and email", removed)
+	}
+}
+
+func TestScheduledNotify(t *testing.T) {
+	defer func(list string) { *scheduledNotifyList = list }(*scheduledNotifyList)
+	date := time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC)
+	previous := []RunRecord{{Accounts: []AccountRecord{{AccountId: "a", Best: "100", BestTime: date}}}}
+	same := RunRecord{Accounts: []AccountRecord{{AccountId: "a", Best: "100", BestTime: date}}}
+	changed := RunRecord{Accounts: []AccountRecord{{AccountId: "a", Best: "120", BestTime: date}}}
+	*scheduledNotifyList = ""
+	if scheduledNotify("email", previous, changed) {
+		t.Error("notified without a schedule")
+	}
+	*scheduledNotifyList = "email,webhooks"
+	if !scheduledNotify("email", previous, changed) || !scheduledNotify("webhooks", nil, same) {
+		t.Error("not notified of a changed maximum")
+	}
+	if scheduledNotify("email", previous, same) || scheduledNotify("sheets", previous, changed) {
+		t.Error("notified of an unchanged maximum or without the flag")
+	}
+}
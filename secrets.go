@@ -0,0 +1,115 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+var UnsupportedKeyringError = errors.New("OS keyring is not supported on this system")
+
+// KeyringToken in place of a token reads it from the OS keyring
+const KeyringToken = "keyring"
+
+// Service of the tokens in the OS keyring, the account is the profile or the person
+const keyringService = "tbank-invest"
+
+// keyringCommand returns the command printing the secret of the keyring entry on the system,
+// Windows Credential Manager has no such command, so the keyring is not supported there
+func keyringCommand(goos, service, account string) ([]string, error) {
+	switch goos {
+	case "darwin":
+		return []string{"security", "find-generic-password", "-s", service, "-a", account, "-w"}, nil
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return []string{"secret-tool", "lookup", "service", service, "account", account}, nil
+	}
+	return nil, UnsupportedKeyringError
+}
+
+// runSecretCommand runs the command and returns its output without the trailing newline,
+// the command errors are passed to stderr
+func runSecretCommand(args []string) ([]byte, error) {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", args[0], err)
+	}
+	return output, nil
+}
+
+// ResolveToken returns the token, reading it from the OS keyring entry of the account if it is KeyringToken
+func ResolveToken(token, account string) (string, error) {
+	if token != KeyringToken {
+		return token, nil
+	}
+	args, err := keyringCommand(runtime.GOOS, keyringService, account)
+	if err != nil {
+		return "", err
+	}
+	output, err := runSecretCommand(args)
+	if err != nil {
+		return "", err
+	}
+	secret := strings.TrimSpace(string(output))
+	if secret == "" {
+		return "", fmt.Errorf("no token in the keyring for %s/%s", keyringService, account)
+	}
+	return secret, nil
+}
+
+// decryptCommands decrypt the config by the extension of its encrypted file,
+// age uses the identity from AGE_IDENTITY or the age key of sops
+var decryptCommands = map[string]func(filename string) []string{
+	".age": func(filename string) []string {
+		identity := os.Getenv("AGE_IDENTITY")
+		if identity == "" {
+			config, _ := os.UserConfigDir()
+			identity = filepath.Join(config, "sops", "age", "keys.txt")
+		}
+		return []string{"age", "--decrypt", "--identity", identity, filename}
+	},
+	".gpg": func(filename string) []string {
+		return []string{"gpg", "--quiet", "--batch", "--decrypt", filename}
+	},
+}
+
+// readConfigFile reads the config, or decrypts its .age or .gpg file if there is no plaintext one
+func readConfigFile(filename string) ([]byte, error) {
+	data, err := os.ReadFile(filename)
+	if !errors.Is(err, os.ErrNotExist) {
+		return data, err
+	}
+	for _, extension := range []string{".age", ".gpg"} {
+		encrypted := filename + extension
+		if _, statErr := os.Stat(encrypted); statErr != nil {
+			continue
+		}
+		output, err := runSecretCommand(decryptCommands[extension](encrypted))
+		if err != nil {
+			return nil, err
+		}
+		return output, nil
+	}
+	return nil, err
+}
@@ -0,0 +1,63 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestKeyringCommand(t *testing.T) {
+	args, err := keyringCommand("linux", keyringService, "default")
+	want := []string{"secret-tool", "lookup", "service", "tbank-invest", "account", "default"}
+	if err != nil || !slices.Equal(args, want) {
+		t.Errorf("keyringCommand(linux) = %v, %v, want %v", args, err, want)
+	}
+	args, err = keyringCommand("darwin", keyringService, "spouse")
+	want = []string{"security", "find-generic-password", "-s", "tbank-invest", "-a", "spouse", "-w"}
+	if err != nil || !slices.Equal(args, want) {
+		t.Errorf("keyringCommand(darwin) = %v, %v, want %v", args, err, want)
+	}
+	_, err = keyringCommand("plan9", keyringService, "default")
+	if !errors.Is(err, UnsupportedKeyringError) {
+		t.Errorf("keyringCommand(plan9) error = %v, want %v", err, UnsupportedKeyringError)
+	}
+	token, err := ResolveToken("t.secret", "default")
+	if err != nil || token != "t.secret" {
+		t.Errorf("ResolveToken() = %q, %v, want the token itself", token, err)
+	}
+}
+
+func TestReadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "config.yaml")
+	_, err := readConfigFile(filename)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("readConfigFile() error = %v, want %v without any config", err, os.ErrNotExist)
+	}
+	err = os.WriteFile(filename, []byte("APIToken: keyring\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := readConfigFile(filename)
+	if err != nil || string(data) != "APIToken: keyring\n" {
+		t.Errorf("readConfigFile() = %q, %v", data, err)
+	}
+}
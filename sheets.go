@@ -0,0 +1,235 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+var NoSpreadsheetError = errors.New("no spreadsheet in GoogleSheets.SpreadsheetId")
+var InvalidPrivateKeyError = errors.New("service account private key is not an RSA key")
+
+const sheetsScope = "https://www.googleapis.com/auth/spreadsheets"
+const sheetsAPI = "https://sheets.googleapis.com/v4/spreadsheets/"
+
+// SheetsOptions are the Google Sheet settings for -sheets, the sheets are overwritten on each run
+type SheetsOptions struct {
+	SpreadsheetId string `yaml:"SpreadsheetId"`
+	// service account key in JSON, GOOGLE_APPLICATION_CREDENTIALS is used if empty
+	CredentialsFile string `yaml:"CredentialsFile"`
+	// Timeline and Summary by default
+	TimelineSheet string `yaml:"TimelineSheet"`
+	SummarySheet  string `yaml:"SummarySheet"`
+}
+
+// serviceAccount is the part of the service account key needed for a token
+type serviceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// Sheets writes values to a spreadsheet on behalf of a service account
+type Sheets struct {
+	client        *http.Client
+	spreadsheetId string
+	token         string
+}
+
+// NewSheets gets an access token of the service account for the spreadsheet
+func NewSheets(options SheetsOptions) (*Sheets, error) {
+	if options.SpreadsheetId == "" {
+		return nil, NoSpreadsheetError
+	}
+	filename := options.CredentialsFile
+	if filename == "" {
+		filename = os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var account serviceAccount
+	err = json.Unmarshal(data, &account)
+	if err != nil {
+		return nil, err
+	}
+	assertion, err := account.assertion(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.PostForm(account.TokenURI, url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return nil, err
+	}
+	return &Sheets{client: client, spreadsheetId: options.SpreadsheetId, token: token.AccessToken}, nil
+}
+
+// assertion signs the JWT exchanged for an access token
+func (a serviceAccount) assertion(now time.Time) (string, error) {
+	block, _ := pem.Decode([]byte(a.PrivateKey))
+	if block == nil {
+		return "", InvalidPrivateKeyError
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return "", err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", InvalidPrivateKeyError
+	}
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iss":   a.ClientEmail,
+		"scope": sheetsScope,
+		"aud":   a.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(nil, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func (s *Sheets) call(method, path string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(method, sheetsAPI+s.spreadsheetId+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// Write replaces the values of the sheet, the sheet must exist
+func (s *Sheets) Write(sheet string, rows [][]any) error {
+	name := url.PathEscape("'" + strings.ReplaceAll(sheet, "'", "''") + "'")
+	err := s.call(http.MethodPost, "/values/"+name+":clear", struct{}{})
+	if err != nil {
+		return err
+	}
+	return s.call(http.MethodPut, "/values/"+name+"?valueInputOption=USER_ENTERED", map[string]any{
+		"values": rows,
+	})
+}
+
+// sheetsTime formats the time so that the spreadsheet recognizes it
+func sheetsTime(t time.Time) string {
+	return t.In(Location).Format(time.DateTime)
+}
+
+// SheetsTimeline returns the rows of the timeline of each account and of their sum
+func SheetsTimeline(evaluations []*Evaluation) [][]any {
+	rows := [][]any{{"time", "account", "value_usd"}}
+	for _, evaluation := range evaluations {
+		for _, point := range evaluation.Timeline {
+			rows = append(rows, []any{sheetsTime(point.Time), Redact("account", evaluation.AccountId),
+				Round(ScaleAmount(point.Aggregate), MoneyDecimals, Rounding).FloatString(MoneyDecimals)})
+		}
+	}
+	if len(evaluations) > 1 {
+		for _, point := range Combine(evaluations) {
+			rows = append(rows, []any{sheetsTime(point.Time), "combined",
+				Round(ScaleAmount(point.Aggregate), MoneyDecimals, Rounding).FloatString(MoneyDecimals)})
+		}
+	}
+	return rows
+}
+
+// SheetsSummary returns the rows of the maximum and the current value of each account and of their sum
+func SheetsSummary(summary *Summary) [][]any {
+	rows := [][]any{{"account", "name", "best_time", "best_usd", "current_usd"}}
+	summary = summary.Redacted()
+	for _, account := range summary.Accounts {
+		rows = append(rows, []any{account.AccountId, account.Account.Name,
+			sheetsTime(account.BestTime),
+			account.Best.Value, account.Current.Value})
+	}
+	if summary.Combined != nil {
+		rows = append(rows, []any{"combined", "", sheetsTime(summary.Combined.BestTime), summary.Combined.Best.Value, ""})
+	}
+	return rows
+}
+
+// PublishSheets writes the timelines and the summary to the spreadsheet
+func PublishSheets(options SheetsOptions, evaluations []*Evaluation, summary *Summary) error {
+	sheets, err := NewSheets(options)
+	if err != nil {
+		return err
+	}
+	err = sheets.Write(cmp.Or(options.TimelineSheet, "Timeline"), SheetsTimeline(evaluations))
+	if err != nil {
+		return err
+	}
+	return sheets.Write(cmp.Or(options.SummarySheet, "Summary"), SheetsSummary(summary))
+}
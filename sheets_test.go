@@ -0,0 +1,98 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServiceAccountAssertion(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	account := serviceAccount{
+		ClientEmail: "robot@example.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		TokenURI:    "https://oauth2.googleapis.com/token",
+	}
+	now := time.Unix(1700000000, 0)
+	assertion, err := account.assertion(now)
+	if err != nil {
+		t.Fatalf("assertion() error = %v", err)
+	}
+	parts := strings.Split(assertion, ".")
+	if len(parts) != 3 {
+		t.Fatalf("assertion() = %q, want a signed JWT", assertion)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	err = rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature)
+	if err != nil {
+		t.Errorf("signature is invalid: %v", err)
+	}
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var claims struct {
+		Iss   string `json:"iss"`
+		Scope string `json:"scope"`
+		Aud   string `json:"aud"`
+		Exp   int64  `json:"exp"`
+	}
+	err = json.Unmarshal(data, &claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Iss != account.ClientEmail || claims.Scope != sheetsScope || claims.Aud != account.TokenURI ||
+		claims.Exp != now.Add(time.Hour).Unix() {
+		t.Errorf("claims = %+v", claims)
+	}
+}
+
+func TestSheetsRedacted(t *testing.T) {
+	defer func(r *Redactor) { redactor = r }(redactor)
+	redactor = NewRedactor()
+	summary := &Summary{Accounts: []AccountSummary{{AccountId: "2000123456", Account: AccountInfo{Name: "Broker"}}}}
+	row := SheetsSummary(summary)[1]
+	if row[0] != "account-1" || row[1] != "name-1" {
+		t.Errorf("SheetsSummary() = %v, want the pseudonyms", row)
+	}
+	evaluations := []*Evaluation{{AccountId: "2000123456", Timeline: Timeline{{Aggregate: big.NewRat(1, 1)}}}}
+	if row := SheetsTimeline(evaluations)[1]; row[1] != "account-1" {
+		t.Errorf("SheetsTimeline() = %v, want the pseudonym", row)
+	}
+}
@@ -0,0 +1,354 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package storage keeps the fetched operations, candles and instruments and the computed timelines in SQLite,
+// it serves both as a cache of completed data and as an archive for later audits.
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// Driver is the database/sql driver name, it is registered by the SQLite driver package
+const Driver = "sqlite"
+
+// migrations are applied in order, the number of applied ones is kept in the user_version pragma
+var migrations = []string{
+	`CREATE TABLE operations (
+		account_id TEXT NOT NULL,
+		id TEXT NOT NULL,
+		date INTEGER NOT NULL,
+		data TEXT NOT NULL,
+		PRIMARY KEY (account_id, id)
+	);
+	CREATE INDEX operations_date ON operations (account_id, date);
+	CREATE TABLE candles (
+		instrument_uid TEXT NOT NULL,
+		time INTEGER NOT NULL,
+		data TEXT NOT NULL,
+		PRIMARY KEY (instrument_uid, time)
+	);
+	CREATE TABLE candle_ranges (
+		instrument_uid TEXT PRIMARY KEY,
+		fetched_from INTEGER NOT NULL,
+		fetched_to INTEGER NOT NULL
+	);
+	CREATE TABLE instruments (
+		uid TEXT PRIMARY KEY,
+		asset_uid TEXT NOT NULL,
+		ticker TEXT NOT NULL,
+		isin TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		currency TEXT NOT NULL
+	);
+	CREATE TABLE timelines (
+		account_id TEXT NOT NULL,
+		year INTEGER NOT NULL,
+		time INTEGER NOT NULL,
+		aggregate TEXT NOT NULL,
+		PRIMARY KEY (account_id, year, time)
+	);`,
+	`ALTER TABLE instruments ADD COLUMN name TEXT NOT NULL DEFAULT '';`,
+}
+
+var NewerSchemaError = errors.New("database schema is newer than supported")
+
+// Store is an SQLite database with the fetched data
+type Store struct {
+	db *sql.DB
+}
+
+// Open opens the database and migrates its schema
+func Open(filename string) (*Store, error) {
+	db, err := sql.Open(Driver, filename)
+	if err != nil {
+		return nil, err
+	}
+	store := &Store{db: db}
+	err = store.migrate()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func (s *Store) migrate() error {
+	var version int
+	err := s.db.QueryRow("PRAGMA user_version").Scan(&version)
+	if err != nil {
+		return err
+	}
+	if version > len(migrations) {
+		return NewerSchemaError
+	}
+	for ; version < len(migrations); version++ {
+		err = s.transaction(func(tx *sql.Tx) error {
+			_, err := tx.Exec(migrations[version])
+			if err != nil {
+				return fmt.Errorf("migration %d: %w", version+1, err)
+			}
+			// pragmas do not take parameters
+			_, err = tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version+1))
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) transaction(f func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	err = f(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// SaveOperations adds the operations of the account, the known ones are replaced
+func (s *Store) SaveOperations(accountId string, operations []*pb.OperationItem) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare("INSERT OR REPLACE INTO operations (account_id, id, date, data) VALUES (?, ?, ?, ?)")
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for _, operation := range operations {
+			data, err := json.Marshal(operation)
+			if err != nil {
+				return err
+			}
+			_, err = stmt.Exec(accountId, operation.Id, operation.Date.AsTime().UnixNano(), string(data))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Operations returns the operations of the account in the range in ascending time order
+func (s *Store) Operations(accountId string, from, to time.Time) ([]*pb.OperationItem, error) {
+	rows, err := s.db.Query("SELECT data FROM operations WHERE account_id = ? AND date >= ? AND date < ? ORDER BY date, id",
+		accountId, from.UnixNano(), to.UnixNano())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var operations []*pb.OperationItem
+	for rows.Next() {
+		var data []byte
+		err = rows.Scan(&data)
+		if err != nil {
+			return nil, err
+		}
+		operation := &pb.OperationItem{}
+		err = json.Unmarshal(data, operation)
+		if err != nil {
+			return nil, err
+		}
+		operations = append(operations, operation)
+	}
+	return operations, rows.Err()
+}
+
+// SaveCandles replaces the candles of the instrument in the fetched range
+func (s *Store) SaveCandles(instrumentUid string, from, to time.Time, candles []*pb.HistoricCandle) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		_, err := tx.Exec("DELETE FROM candles WHERE instrument_uid = ? AND time >= ? AND time < ?",
+			instrumentUid, from.UnixNano(), to.UnixNano())
+		if err != nil {
+			return err
+		}
+		stmt, err := tx.Prepare("INSERT OR REPLACE INTO candles (instrument_uid, time, data) VALUES (?, ?, ?)")
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for _, candle := range candles {
+			data, err := json.Marshal(candle)
+			if err != nil {
+				return err
+			}
+			_, err = stmt.Exec(instrumentUid, candle.Time.AsTime().UnixNano(), string(data))
+			if err != nil {
+				return err
+			}
+		}
+		_, err = tx.Exec(`INSERT INTO candle_ranges (instrument_uid, fetched_from, fetched_to) VALUES (?, ?, ?)
+			ON CONFLICT (instrument_uid) DO UPDATE SET
+				fetched_from = min(fetched_from, excluded.fetched_from),
+				fetched_to = max(fetched_to, excluded.fetched_to)`,
+			instrumentUid, from.UnixNano(), to.UnixNano())
+		return err
+	})
+}
+
+// Candles returns the candles of the instrument in the range in ascending time order,
+// it returns false if the range has not been fetched completely
+func (s *Store) Candles(instrumentUid string, from, to time.Time) ([]*pb.HistoricCandle, bool, error) {
+	var fetchedFrom, fetchedTo int64
+	err := s.db.QueryRow("SELECT fetched_from, fetched_to FROM candle_ranges WHERE instrument_uid = ?",
+		instrumentUid).Scan(&fetchedFrom, &fetchedTo)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if fetchedFrom > from.UnixNano() || fetchedTo < to.UnixNano() {
+		return nil, false, nil
+	}
+	rows, err := s.db.Query("SELECT data FROM candles WHERE instrument_uid = ? AND time >= ? AND time < ? ORDER BY time",
+		instrumentUid, from.UnixNano(), to.UnixNano())
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+	var candles []*pb.HistoricCandle
+	for rows.Next() {
+		var data []byte
+		err = rows.Scan(&data)
+		if err != nil {
+			return nil, false, err
+		}
+		candle := &pb.HistoricCandle{}
+		err = json.Unmarshal(data, candle)
+		if err != nil {
+			return nil, false, err
+		}
+		candles = append(candles, candle)
+	}
+	return candles, true, rows.Err()
+}
+
+// Instrument is the reference data of an instrument used by the evaluation
+type Instrument struct {
+	Uid      string
+	AssetUid string
+	Ticker   string
+	Name     string
+	Isin     string
+	Kind     string
+	Currency string
+}
+
+// SaveInstruments adds the instruments, the known ones are replaced
+func (s *Store) SaveInstruments(instruments []Instrument) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(`INSERT OR REPLACE INTO instruments (uid, asset_uid, ticker, name, isin, kind, currency)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for _, instrument := range instruments {
+			_, err = stmt.Exec(instrument.Uid, instrument.AssetUid, instrument.Ticker, instrument.Name,
+				instrument.Isin, instrument.Kind, instrument.Currency)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Instruments returns all saved instruments
+func (s *Store) Instruments() ([]Instrument, error) {
+	rows, err := s.db.Query("SELECT uid, asset_uid, ticker, name, isin, kind, currency FROM instruments ORDER BY uid")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var instruments []Instrument
+	for rows.Next() {
+		var instrument Instrument
+		err = rows.Scan(&instrument.Uid, &instrument.AssetUid, &instrument.Ticker, &instrument.Name,
+			&instrument.Isin, &instrument.Kind, &instrument.Currency)
+		if err != nil {
+			return nil, err
+		}
+		instruments = append(instruments, instrument)
+	}
+	return instruments, rows.Err()
+}
+
+// Point is a value of the timeline, the aggregate is an exact rational in USD
+type Point struct {
+	Time      time.Time
+	Aggregate string
+}
+
+// SaveTimeline replaces the timeline of the account for the year
+func (s *Store) SaveTimeline(accountId string, year int, timeline []Point) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		_, err := tx.Exec("DELETE FROM timelines WHERE account_id = ? AND year = ?", accountId, year)
+		if err != nil {
+			return err
+		}
+		stmt, err := tx.Prepare("INSERT INTO timelines (account_id, year, time, aggregate) VALUES (?, ?, ?, ?)")
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for _, point := range timeline {
+			_, err = stmt.Exec(accountId, year, point.Time.UnixNano(), point.Aggregate)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Timeline returns the timeline of the account for the year in ascending time order
+func (s *Store) Timeline(accountId string, year int) ([]Point, error) {
+	rows, err := s.db.Query("SELECT time, aggregate FROM timelines WHERE account_id = ? AND year = ? ORDER BY time",
+		accountId, year)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var timeline []Point
+	for rows.Next() {
+		var nanos int64
+		var point Point
+		err = rows.Scan(&nanos, &point.Aggregate)
+		if err != nil {
+			return nil, err
+		}
+		point.Time = time.Unix(0, nanos).UTC()
+		timeline = append(timeline, point)
+	}
+	return timeline, rows.Err()
+}
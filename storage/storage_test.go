@@ -0,0 +1,56 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestStoreTimeline(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "archive.db")
+	store, err := Open(filename)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	timeline := []Point{
+		{Time: time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC), Aggregate: "100"},
+		{Time: time.Date(2025, 3, 14, 11, 0, 0, 0, time.UTC), Aggregate: "201/2"},
+	}
+	err = store.SaveTimeline("account", 2025, timeline)
+	if err != nil {
+		t.Fatalf("SaveTimeline() error = %v", err)
+	}
+	store.Close()
+	// the migrated schema is kept
+	store, err = Open(filename)
+	if err != nil {
+		t.Fatalf("Open() of the existing database error = %v", err)
+	}
+	defer store.Close()
+	got, err := store.Timeline("account", 2025)
+	if err != nil {
+		t.Fatalf("Timeline() error = %v", err)
+	}
+	if !slices.EqualFunc(got, timeline, func(a, b Point) bool {
+		return a.Time.Equal(b.Time) && a.Aggregate == b.Aggregate
+	}) {
+		t.Errorf("Timeline() = %v, want %v", got, timeline)
+	}
+}
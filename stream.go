@@ -0,0 +1,83 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"math/big"
+	"os"
+	"time"
+)
+
+// PointRecord is an evaluated point streamed as a JSON line
+type PointRecord struct {
+	Account   string            `json:"account"`
+	Time      time.Time         `json:"time"`
+	Aggregate Amount            `json:"aggregate"`
+	Cost      map[string]Amount `json:"cost,omitempty"`
+}
+
+// PointStream writes evaluated points as NDJSON while going back in time,
+// so the points of each account come in descending time order
+type PointStream struct {
+	w         io.WriteCloser
+	encoder   *json.Encoder
+	breakdown bool
+}
+
+// points is the stream of the run, nil if disabled
+var points *PointStream
+
+// OpenPointStream creates the stream to the file, "-" is stdout
+func OpenPointStream(filename string, breakdown bool) (*PointStream, error) {
+	var w io.WriteCloser = os.Stdout
+	if filename != "-" {
+		file, err := os.Create(filename)
+		if err != nil {
+			return nil, err
+		}
+		w = file
+	}
+	return &PointStream{w: w, encoder: json.NewEncoder(w), breakdown: breakdown}, nil
+}
+
+// Emit writes the point, it does nothing for a nil stream
+func (s *PointStream) Emit(accountId string, date time.Time, aggregate *big.Rat, cost map[string]*big.Rat) error {
+	if s == nil {
+		return nil
+	}
+	record := PointRecord{
+		Account:   Redact("account", accountId),
+		Time:      date,
+		Aggregate: NewAmount(ScaleAmount(aggregate), "usd"),
+	}
+	if s.breakdown {
+		record.Cost = make(map[string]Amount, len(cost))
+		for currency, value := range cost {
+			record.Cost[currency] = NewAmount(ScaleAmount(value), currency)
+		}
+	}
+	return s.encoder.Encode(record)
+}
+
+func (s *PointStream) Close() error {
+	if s == nil || s.w == os.Stdout {
+		return nil
+	}
+	return s.w.Close()
+}
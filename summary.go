@@ -0,0 +1,260 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"slices"
+	"time"
+)
+
+// Amount is a rounded value together with the exact rational one
+type Amount struct {
+	Value    string `json:"value"`
+	Exact    string `json:"exact"`
+	Currency string `json:"currency"`
+}
+
+func NewAmount(value *big.Rat, currency string) Amount {
+	if value == nil {
+		value = &big.Rat{}
+	}
+	return Amount{
+		Value:    Round(value, MoneyDecimals, Rounding).FloatString(MoneyDecimals),
+		Exact:    value.RatString(),
+		Currency: currency,
+	}
+}
+
+func NewAmounts(cost map[string]*big.Rat) map[string]Amount {
+	result := make(map[string]Amount, len(cost))
+	for currency, value := range cost {
+		result[currency] = NewAmount(value, currency)
+	}
+	return result
+}
+
+type AccountSummary struct {
+	AccountId string            `json:"account_id"`
+	Account   AccountInfo       `json:"account"`
+	Person    string            `json:"person,omitempty"`
+	Current   Amount            `json:"current"`
+	BestTime  time.Time         `json:"best_time"`
+	Best      Amount            `json:"best"`
+	BestCost  map[string]Amount `json:"best_cost"`
+	Excluded  Amount            `json:"excluded"`
+	// maximum values of the securities and the cash alone
+	BestSecuritiesTime time.Time `json:"best_securities_time"`
+	BestSecurities     Amount    `json:"best_securities"`
+	BestCashTime       time.Time `json:"best_cash_time"`
+	BestCash           Amount    `json:"best_cash"`
+	// negative positions at the peak, e.g. margin loans, whether they reduce the best value depends on the policy
+	Liabilities Amount `json:"liabilities"`
+	// the first time each reporting threshold was exceeded
+	Thresholds []ThresholdCrossing `json:"thresholds"`
+	// how long the value stayed above the peak band of the maximum, in seconds
+	PeakBand      string  `json:"peak_band"`
+	PeakSustained float64 `json:"peak_sustained_seconds"`
+	PeakTotal     float64 `json:"peak_total_seconds"`
+	// the maximum by the conservative rules, with -conservative
+	Conservative *ConservativeSummary `json:"conservative,omitempty"`
+	// the maximum is outside the trading sessions of the trading calendar
+	PeakOffSession bool `json:"peak_off_session,omitempty"`
+	// deposits during the tax year for IIS accounts
+	Contributions map[string]Amount `json:"contributions,omitempty"`
+	// positions at the peak by ExportKey, cash by currency
+	Holdings map[string]HoldingSummary `json:"holdings"`
+}
+
+// HoldingSummary is a position at the peak
+type HoldingSummary struct {
+	Ticker   string `json:"ticker,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Isin     string `json:"isin,omitempty"`
+	Quantity string `json:"quantity"`
+	Currency string `json:"currency,omitempty"`
+	// value in USD, missing for assets without a price, e.g. futures
+	Value *Amount `json:"value,omitempty"`
+}
+
+// NewHoldingSummaries keys the positions by ExportKey, the asset UID is used for duplicate keys
+func NewHoldingSummaries(state *State, portfolio map[string]*big.Rat) map[string]HoldingSummary {
+	holdings, _ := holdings(state, portfolio)
+	result := make(map[string]HoldingSummary, len(holdings))
+	for _, row := range holdings {
+		summary := HoldingSummary{
+			Name:     row.title,
+			Isin:     row.isin,
+			Quantity: row.quantity.RatString(),
+			Currency: row.currency,
+		}
+		key := row.key
+		if _, ok := tickers[row.key]; ok {
+			summary.Ticker = row.name
+			key = AssetKey(row.key)
+			if _, ok := result[key]; ok {
+				key = row.key
+			}
+		}
+		if row.usd != nil {
+			value := NewAmount(row.usd, "usd")
+			summary.Value = &value
+		}
+		result[key] = summary
+	}
+	return result
+}
+
+// ConservativeSummary is the maximum by close prices, limited carryforward and liabilities netted
+type ConservativeSummary struct {
+	BestTime   time.Time `json:"best_time"`
+	Best       Amount    `json:"best"`
+	CarryLimit string    `json:"carry_limit"`
+}
+
+type CombinedSummary struct {
+	BestTime   time.Time           `json:"best_time"`
+	Best       Amount              `json:"best"`
+	Thresholds []ThresholdCrossing `json:"thresholds"`
+}
+
+// Summary is the machine readable result of the run
+type Summary struct {
+	TaxYear  int    `json:"tax_year"`
+	Rounding string `json:"rounding"`
+	// net or gross
+	Liabilities string           `json:"liabilities"`
+	Accounts    []AccountSummary `json:"accounts"`
+	Combined    *CombinedSummary `json:"combined,omitempty"`
+	Groups      []GroupSummary   `json:"groups,omitempty"`
+	Provenance  Provenance       `json:"provenance"`
+}
+
+func NewSummary(evaluations []*Evaluation, provenance Provenance) *Summary {
+	summary := &Summary{TaxYear: TaxYear, Rounding: Rounding, Liabilities: LiabilitiesPolicy, Provenance: provenance}
+	for _, evaluation := range evaluations {
+		summary.Accounts = append(summary.Accounts, AccountSummary{
+			AccountId:          evaluation.AccountId,
+			Account:            evaluation.Account,
+			Person:             evaluation.Person,
+			Current:            NewAmount(evaluation.Current, "usd"),
+			BestTime:           evaluation.BestTime,
+			Best:               NewAmount(evaluation.BestAggregate, "usd"),
+			BestCost:           NewAmounts(evaluation.BestCost),
+			Excluded:           NewAmount(Aggregate(evaluation.BestExcludedCost), "usd"),
+			BestSecuritiesTime: evaluation.BestSecurities.Time,
+			BestSecurities:     NewAmount(evaluation.BestSecurities.Aggregate, "usd"),
+			BestCashTime:       evaluation.BestCash.Time,
+			BestCash:           NewAmount(evaluation.BestCash.Aggregate, "usd"),
+			Liabilities:        NewAmount(Liabilities(evaluation.BestState, evaluation.Excluded), "usd"),
+			Holdings:           NewHoldingSummaries(evaluation.BestState, evaluation.BestState.Portfolio),
+			Thresholds:         evaluation.Thresholds,
+			PeakBand:           PeakBand.RatString(),
+			PeakSustained:      evaluation.Persistence.Sustained.Seconds(),
+			PeakTotal:          evaluation.Persistence.Total.Seconds(),
+			PeakOffSession:     evaluation.PeakOffSession,
+		})
+		if result := evaluation.Conservative; result != nil {
+			summary.Accounts[len(summary.Accounts)-1].Conservative = &ConservativeSummary{
+				BestTime:   result.Best.Time,
+				Best:       NewAmount(result.Best.Aggregate, "usd"),
+				CarryLimit: result.CarryLimit.String(),
+			}
+		}
+		if evaluation.Account.IsIIS() {
+			summary.Accounts[len(summary.Accounts)-1].Contributions = NewAmounts(evaluation.Contributions())
+		}
+	}
+	return summary
+}
+
+// Redacted returns a copy of the summary with the pseudonyms and the scaled amounts when redacting,
+// the summary itself stays exact for the history and the reports, which redact on their own
+func (s *Summary) Redacted() *Summary {
+	if redactor == nil {
+		return s
+	}
+	result := *s
+	result.Accounts = make([]AccountSummary, len(s.Accounts))
+	for i, account := range s.Accounts {
+		account.AccountId = Redact("account", account.AccountId)
+		account.Account.Id = Redact("account", account.Account.Id)
+		account.Account.Name = Redact("name", account.Account.Name)
+		account.Person = Redact("name", account.Person)
+		account.Current = redactAmount(account.Current)
+		account.Best = redactAmount(account.Best)
+		account.BestCost = redactAmounts(account.BestCost)
+		account.Excluded = redactAmount(account.Excluded)
+		account.BestSecurities = redactAmount(account.BestSecurities)
+		account.BestCash = redactAmount(account.BestCash)
+		account.Liabilities = redactAmount(account.Liabilities)
+		account.Contributions = redactAmounts(account.Contributions)
+		if account.Conservative != nil {
+			conservative := *account.Conservative
+			conservative.Best = redactAmount(conservative.Best)
+			account.Conservative = &conservative
+		}
+		holdings := make(map[string]HoldingSummary, len(account.Holdings))
+		for key, holding := range account.Holdings {
+			holding.Ticker = Redact("asset", holding.Ticker)
+			holding.Name, holding.Isin = "", ""
+			if quantity, ok := (&big.Rat{}).SetString(holding.Quantity); ok {
+				holding.Quantity = ScaleAmount(quantity).RatString()
+			}
+			if holding.Value != nil {
+				value := redactAmount(*holding.Value)
+				holding.Value = &value
+			}
+			holdings[redactKey(key)] = holding
+		}
+		account.Holdings = holdings
+		result.Accounts[i] = account
+	}
+	if s.Combined != nil {
+		combined := *s.Combined
+		combined.Best = redactAmount(combined.Best)
+		result.Combined = &combined
+	}
+	result.Groups = make([]GroupSummary, len(s.Groups))
+	for i, group := range s.Groups {
+		group.AccountIds = slices.Clone(group.AccountIds)
+		for j, accountId := range group.AccountIds {
+			group.AccountIds[j] = Redact("account", accountId)
+		}
+		group.Best = redactAmount(group.Best)
+		result.Groups[i] = group
+	}
+	return &result
+}
+
+func jsonIndent(value any) ([]byte, error) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func WriteJSON(filename string, value any) error {
+	data, err := jsonIndent(value)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0o644)
+}
@@ -0,0 +1,111 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"text/tabwriter"
+)
+
+// Color modes of the printed tables
+const (
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+)
+
+const (
+	ansiHeader = "\x1b[1;36m"
+	ansiTotal  = "\x1b[1m"
+	ansiReset  = "\x1b[0m"
+)
+
+var UnknownColorModeError = errors.New("unknown color mode")
+
+// colorOutput highlights headers and totals of the printed tables
+var colorOutput = false
+
+// SetColorMode enables colors always, never or when stdout is a terminal and NO_COLOR is not set
+func SetColorMode(mode string) error {
+	switch mode {
+	case ColorAlways:
+		colorOutput = true
+	case ColorNever:
+		colorOutput = false
+	case ColorAuto:
+		info, err := os.Stdout.Stat()
+		colorOutput = err == nil && info.Mode()&os.ModeCharDevice != 0 &&
+			os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+	default:
+		return UnknownColorModeError
+	}
+	return nil
+}
+
+// Table aligns the printed rows with tabwriter and highlights the header and the total rows,
+// the colors are added to whole lines after the alignment, so they do not break it
+type Table struct {
+	*tabwriter.Writer
+	out         io.Writer
+	buffer      bytes.Buffer
+	lines       int
+	highlighted map[int]string
+}
+
+func NewTable(w io.Writer, flags uint) *Table {
+	t := &Table{out: w, highlighted: map[int]string{0: ansiHeader}}
+	t.Writer = tabwriter.NewWriter(&t.buffer, 0, 0, 2, ' ', flags)
+	return t
+}
+
+func (t *Table) Write(p []byte) (int, error) {
+	t.lines += bytes.Count(p, []byte("\n"))
+	return t.Writer.Write(p)
+}
+
+// Total highlights the next row
+func (t *Table) Total() {
+	t.highlighted[t.lines] = ansiTotal
+}
+
+func (t *Table) Flush() error {
+	err := t.Writer.Flush()
+	if err != nil {
+		return err
+	}
+	if !colorOutput {
+		_, err = t.out.Write(t.buffer.Bytes())
+		return err
+	}
+	lines := bytes.SplitAfter(t.buffer.Bytes(), []byte("\n"))
+	var out bytes.Buffer
+	for i, line := range lines {
+		color, ok := t.highlighted[i]
+		if !ok || len(line) == 0 {
+			out.Write(line)
+			continue
+		}
+		out.WriteString(color)
+		out.Write(bytes.TrimSuffix(line, []byte("\n")))
+		out.WriteString(ansiReset + "\n")
+	}
+	_, err = t.out.Write(out.Bytes())
+	return err
+}
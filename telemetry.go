@@ -0,0 +1,418 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/status"
+)
+
+const defaultServiceName = "tbank-invest"
+
+// Upper bounds of the API call duration histogram buckets, in seconds
+var callDurationBounds = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Telemetry collects spans of the run phases and the API calls and metrics of the API calls,
+// and exports them to an OTLP/HTTP endpoint at the end of the run
+type Telemetry struct {
+	endpoint string
+	headers  map[string]string
+	service  string
+	client   *http.Client
+	traceId  string
+	start    time.Time
+
+	mu    sync.Mutex
+	open  []*Span
+	spans []otlpSpan
+	calls map[callKey]*callMetric
+}
+
+// telemetry exports spans and metrics of the run, nil if disabled
+var telemetry *Telemetry
+
+// NewTelemetry uses the standard OTEL_EXPORTER_OTLP_HEADERS and OTEL_SERVICE_NAME variables,
+// as the collectors and their docs expect them
+func NewTelemetry(endpoint string) *Telemetry {
+	service := os.Getenv("OTEL_SERVICE_NAME")
+	if service == "" {
+		service = defaultServiceName
+	}
+	headers := make(map[string]string)
+	for _, header := range strings.Split(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"), ",") {
+		key, value, ok := strings.Cut(header, "=")
+		if ok {
+			headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return &Telemetry{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		headers:  headers,
+		service:  service,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		traceId:  randomId(16),
+		start:    time.Now(),
+		calls:    make(map[callKey]*callMetric),
+	}
+}
+
+func randomId(size int) string {
+	id := make([]byte, size)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// Span is a phase of the run, a nil span is a no-op when telemetry is disabled
+type Span struct {
+	id         string
+	parent     string
+	name       string
+	start      time.Time
+	attributes []Attribute
+	failure    string
+}
+
+// StartSpan starts a phase inside the innermost open one
+func StartSpan(name string, attributes ...Attribute) *Span {
+	if telemetry == nil {
+		return nil
+	}
+	t := telemetry
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	span := &Span{id: randomId(8), name: name, start: time.Now(), attributes: attributes}
+	if len(t.open) > 0 {
+		span.parent = t.open[len(t.open)-1].id
+	}
+	t.open = append(t.open, span)
+	return span
+}
+
+// Fail marks the span as failed
+func (s *Span) Fail(message string) {
+	if s == nil {
+		return
+	}
+	s.failure = message
+}
+
+// SetAttributes adds attributes known after the span start
+func (s *Span) SetAttributes(attributes ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.attributes = append(s.attributes, attributes...)
+}
+
+// End finishes the span and the phases left open inside it by early returns
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	t := telemetry
+	end := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	i := slices.Index(t.open, s)
+	if i < 0 {
+		return
+	}
+	for _, span := range t.open[i:] {
+		t.spans = append(t.spans, span.export(t.traceId, end))
+	}
+	t.open = t.open[:i]
+}
+
+func (s *Span) export(traceId string, end time.Time) otlpSpan {
+	span := otlpSpan{
+		TraceId:      traceId,
+		SpanId:       s.id,
+		ParentSpanId: s.parent,
+		Name:         s.name,
+		Kind:         otlpSpanKindInternal,
+		Start:        unixNano(s.start),
+		End:          unixNano(end),
+		Attributes:   s.attributes,
+	}
+	if s.failure != "" {
+		span.Status = &otlpStatus{Code: otlpStatusError, Message: s.failure}
+	}
+	return span
+}
+
+type callKey struct {
+	method string
+	code   string
+}
+
+type callMetric struct {
+	count   int64
+	sum     float64
+	buckets []int64
+}
+
+// Call records the finished API call as a client span and in the call metrics
+func (t *Telemetry) Call(method string, start time.Time, err error) {
+	end := time.Now()
+	code := status.Code(err).String()
+	seconds := end.Sub(start).Seconds()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	span := otlpSpan{
+		TraceId:    t.traceId,
+		SpanId:     randomId(8),
+		Name:       method,
+		Kind:       otlpSpanKindClient,
+		Start:      unixNano(start),
+		End:        unixNano(end),
+		Attributes: []Attribute{StringAttribute("rpc.method", method), StringAttribute("rpc.grpc.status_code", code)},
+	}
+	if len(t.open) > 0 {
+		span.ParentSpanId = t.open[len(t.open)-1].id
+	}
+	if err != nil {
+		span.Status = &otlpStatus{Code: otlpStatusError, Message: err.Error()}
+	}
+	t.spans = append(t.spans, span)
+
+	key := callKey{method: method, code: code}
+	metric, ok := t.calls[key]
+	if !ok {
+		metric = &callMetric{buckets: make([]int64, len(callDurationBounds)+1)}
+		t.calls[key] = metric
+	}
+	metric.count++
+	metric.sum += seconds
+	bucket, _ := slices.BinarySearch(callDurationBounds, seconds)
+	metric.buckets[bucket]++
+}
+
+// Flush exports the collected spans and metrics, failures are logged only,
+// as they should not fail the run itself
+func (t *Telemetry) Flush(logger *zap.Logger) {
+	t.mu.Lock()
+	resource := otlpResource{Attributes: []Attribute{StringAttribute("service.name", t.service)}}
+	scope := otlpScope{Name: "github.com/matshch/tbank-invest"}
+	traces := otlpTraces{ResourceSpans: []otlpResourceSpans{{
+		Resource:   resource,
+		ScopeSpans: []otlpScopeSpans{{Scope: scope, Spans: t.spans}},
+	}}}
+	metrics := otlpMetrics{ResourceMetrics: []otlpResourceMetrics{{
+		Resource:     resource,
+		ScopeMetrics: []otlpScopeMetrics{{Scope: scope, Metrics: t.metrics()}},
+	}}}
+	t.spans = nil
+	t.mu.Unlock()
+
+	logger.Debug("exporting telemetry", zap.String("endpoint", t.endpoint))
+	err := t.post("/v1/traces", traces)
+	if err != nil {
+		logger.Warn("error exporting traces", zap.String("endpoint", t.endpoint), zap.Error(err))
+	}
+	err = t.post("/v1/metrics", metrics)
+	if err != nil {
+		logger.Warn("error exporting metrics", zap.String("endpoint", t.endpoint), zap.Error(err))
+	}
+}
+
+func (t *Telemetry) metrics() []otlpMetric {
+	now := unixNano(time.Now())
+	start := unixNano(t.start)
+	var counts, durations []otlpDataPoint
+	for key, metric := range t.calls {
+		attributes := []Attribute{StringAttribute("rpc.method", key.method), StringAttribute("rpc.grpc.status_code", key.code)}
+		counts = append(counts, otlpDataPoint{
+			Attributes: attributes,
+			Start:      start,
+			Time:       now,
+			AsInt:      strconv.FormatInt(metric.count, 10),
+		})
+		buckets := make([]string, len(metric.buckets))
+		for i, count := range metric.buckets {
+			buckets[i] = strconv.FormatInt(count, 10)
+		}
+		durations = append(durations, otlpDataPoint{
+			Attributes:     attributes,
+			Start:          start,
+			Time:           now,
+			Count:          strconv.FormatInt(metric.count, 10),
+			Sum:            &metric.sum,
+			BucketCounts:   buckets,
+			ExplicitBounds: callDurationBounds,
+		})
+	}
+	return []otlpMetric{
+		{Name: "rpc.client.calls", Unit: "{call}", Sum: &otlpSum{
+			AggregationTemporality: otlpTemporalityCumulative, IsMonotonic: true, DataPoints: counts}},
+		{Name: "rpc.client.duration", Unit: "s", Histogram: &otlpHistogram{
+			AggregationTemporality: otlpTemporalityCumulative, DataPoints: durations}},
+	}
+}
+
+func (t *Telemetry) post(path string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, t.endpoint+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// OTLP JSON encoding, see https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+
+const (
+	otlpSpanKindInternal      = 1
+	otlpSpanKindClient        = 3
+	otlpStatusError           = 2
+	otlpTemporalityCumulative = 2
+)
+
+// Attribute is a key-value pair of a span or a data point
+type Attribute struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
+
+func StringAttribute(key, value string) Attribute {
+	return Attribute{Key: key, Value: otlpAnyValue{StringValue: &value}}
+}
+
+func IntAttribute(key string, value int) Attribute {
+	s := strconv.Itoa(value)
+	return Attribute{Key: key, Value: otlpAnyValue{IntValue: &s}}
+}
+
+type otlpResource struct {
+	Attributes []Attribute `json:"attributes"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceId      string      `json:"traceId"`
+	SpanId       string      `json:"spanId"`
+	ParentSpanId string      `json:"parentSpanId,omitempty"`
+	Name         string      `json:"name"`
+	Kind         int         `json:"kind"`
+	Start        string      `json:"startTimeUnixNano"`
+	End          string      `json:"endTimeUnixNano"`
+	Attributes   []Attribute `json:"attributes,omitempty"`
+	Status       *otlpStatus `json:"status,omitempty"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpDataPoint struct {
+	Attributes     []Attribute `json:"attributes"`
+	Start          string      `json:"startTimeUnixNano"`
+	Time           string      `json:"timeUnixNano"`
+	AsInt          string      `json:"asInt,omitempty"`
+	Count          string      `json:"count,omitempty"`
+	Sum            *float64    `json:"sum,omitempty"`
+	BucketCounts   []string    `json:"bucketCounts,omitempty"`
+	ExplicitBounds []float64   `json:"explicitBounds,omitempty"`
+}
+
+type otlpSum struct {
+	AggregationTemporality int             `json:"aggregationTemporality"`
+	IsMonotonic            bool            `json:"isMonotonic"`
+	DataPoints             []otlpDataPoint `json:"dataPoints"`
+}
+
+type otlpHistogram struct {
+	AggregationTemporality int             `json:"aggregationTemporality"`
+	DataPoints             []otlpDataPoint `json:"dataPoints"`
+}
+
+type otlpMetric struct {
+	Name      string         `json:"name"`
+	Unit      string         `json:"unit"`
+	Sum       *otlpSum       `json:"sum,omitempty"`
+	Histogram *otlpHistogram `json:"histogram,omitempty"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpMetrics struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
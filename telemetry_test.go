@@ -0,0 +1,92 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestTelemetryFlush(t *testing.T) {
+	bodies := make(map[string][]byte)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body json.RawMessage
+		err := json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			t.Errorf("%s body: %v", r.URL.Path, err)
+		}
+		bodies[r.URL.Path] = body
+	}))
+	defer server.Close()
+	telemetry = NewTelemetry(server.URL + "/")
+	defer func() { telemetry = nil }()
+
+	run := StartSpan("run")
+	evaluate := StartSpan("evaluate")
+	TraceCall("GetPortfolio", nil, time.Now(), nil, nil)
+	StartSpan("replay")
+	// the replay is left open by an early return
+	evaluate.End()
+	TraceCall("GetAccounts", nil, time.Now(), nil, errors.New("unavailable"))
+	TraceCall("GetAccounts", nil, time.Now(), nil, nil)
+	run.End()
+	telemetry.Flush(zap.NewNop())
+
+	var traces otlpTraces
+	err := json.Unmarshal(bodies["/v1/traces"], &traces)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parents := make(map[string]string)
+	ids := make(map[string]string)
+	for _, span := range traces.ResourceSpans[0].ScopeSpans[0].Spans {
+		ids[span.Name] = span.SpanId
+		parents[span.Name] = span.ParentSpanId
+	}
+	for name, parent := range map[string]string{
+		"run":          "",
+		"evaluate":     "run",
+		"GetPortfolio": "evaluate",
+		"replay":       "evaluate",
+		"GetAccounts":  "run",
+	} {
+		if parents[name] != ids[parent] {
+			t.Errorf("parent of %s = %q, want %s %q", name, parents[name], parent, ids[parent])
+		}
+	}
+
+	var metrics otlpMetrics
+	err = json.Unmarshal(bodies["/v1/metrics"], &metrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := make(map[string]string)
+	for _, point := range metrics.ResourceMetrics[0].ScopeMetrics[0].Metrics[0].Sum.DataPoints {
+		calls[*point.Attributes[0].Value.StringValue+" "+*point.Attributes[1].Value.StringValue] = point.AsInt
+	}
+	for key, want := range map[string]string{"GetPortfolio OK": "1", "GetAccounts OK": "1", "GetAccounts Unknown": "1"} {
+		if calls[key] != want {
+			t.Errorf("calls of %s = %q, want %s", key, calls[key], want)
+		}
+	}
+}
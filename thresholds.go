@@ -0,0 +1,138 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Threshold is a reporting threshold for the aggregate value, in USD unless the currency is set
+type Threshold struct {
+	Name     string `yaml:"Name"`
+	Value    string `yaml:"Value"`
+	Currency string `yaml:"Currency"`
+}
+
+// FBAR is required when the aggregate value exceeds $10,000 at any time during the year
+var DefaultThresholds = []Threshold{{Name: "FBAR", Value: "10000"}}
+
+type trackedThreshold struct {
+	Threshold
+	value *big.Rat
+	// the value in USD by the exchange rate of the currency
+	usd   *big.Rat
+	first time.Time
+}
+
+// ThresholdCrossing is the first time the aggregate value exceeded a threshold, zero if it did not
+type ThresholdCrossing struct {
+	Name      string     `json:"name"`
+	Threshold Amount     `json:"threshold"`
+	FirstTime *time.Time `json:"first_time,omitempty"`
+}
+
+// ThresholdTracker finds the first time each threshold was exceeded
+type ThresholdTracker []*trackedThreshold
+
+func NewThresholdTracker(logger *zap.Logger, thresholds []Threshold) ThresholdTracker {
+	if len(thresholds) == 0 {
+		thresholds = DefaultThresholds
+	}
+	tracker := make(ThresholdTracker, 0, len(thresholds))
+	for _, threshold := range thresholds {
+		value, ok := (&big.Rat{}).SetString(threshold.Value)
+		if !ok {
+			logger.Warn("invalid threshold value", zap.String("name", threshold.Name), zap.String("value", threshold.Value))
+			continue
+		}
+		threshold.Currency = NormalizeCurrency(threshold.Currency)
+		if threshold.Currency == "" {
+			threshold.Currency = "usd"
+		}
+		rate, ok := ExchangeRates[threshold.Currency]
+		if !ok {
+			logger.Warn("unknown threshold currency",
+				zap.String("name", threshold.Name),
+				zap.String("currency", threshold.Currency))
+			continue
+		}
+		usd := (&big.Rat{}).Quo(value, rate)
+		tracker = append(tracker, &trackedThreshold{Threshold: threshold, value: value, usd: usd})
+	}
+	return tracker
+}
+
+// Observe is called in reverse order, so the last exceeding time is the first one
+func (t ThresholdTracker) Observe(date time.Time, aggregate *big.Rat) {
+	for _, threshold := range t {
+		if aggregate.Cmp(threshold.usd) > 0 {
+			threshold.first = date
+		}
+	}
+}
+
+func (t ThresholdTracker) Report(logger *zap.Logger) {
+	for _, threshold := range t {
+		if threshold.first.IsZero() {
+			logger.Info("threshold was not exceeded",
+				zap.String("name", threshold.Name),
+				zap.String("value", FormatUSD(threshold.usd)),
+				zap.String("currency_value", FormatMoney(threshold.value, threshold.Currency)))
+			continue
+		}
+		logger.Info("threshold was exceeded",
+			zap.String("name", threshold.Name),
+			zap.String("value", FormatUSD(threshold.usd)),
+			zap.String("currency_value", FormatMoney(threshold.value, threshold.Currency)),
+			zap.Time("first_time", threshold.first))
+	}
+}
+
+// Crossings returns the first crossing time of each threshold
+func (t ThresholdTracker) Crossings() []ThresholdCrossing {
+	crossings := make([]ThresholdCrossing, 0, len(t))
+	for _, threshold := range t {
+		crossing := ThresholdCrossing{Name: threshold.Name, Threshold: NewAmount(threshold.value, threshold.Currency)}
+		if !threshold.first.IsZero() {
+			crossing.FirstTime = &threshold.first
+		}
+		crossings = append(crossings, crossing)
+	}
+	return crossings
+}
+
+// PrintThresholds prints the first crossing time of each threshold, both in its currency and in USD
+func PrintThresholds(w io.Writer, crossings []ThresholdCrossing) error {
+	tw := NewTable(w, tabwriter.AlignRight)
+	fmt.Fprintf(tw, "%s\t%s\tUSD\t%s\t\n", T("NAME"), T("THRESHOLD"), T("FIRST CROSSED"))
+	for _, crossing := range crossings {
+		value, _ := (&big.Rat{}).SetString(crossing.Threshold.Exact)
+		first := T("never")
+		if crossing.FirstTime != nil {
+			first = crossing.FirstTime.Format(time.DateTime)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\n", crossing.Name, FormatMoney(value, crossing.Threshold.Currency),
+			FormatUSD((&big.Rat{}).Quo(value, ExchangeRates[crossing.Threshold.Currency])), first)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,53 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestThresholdCrossings(t *testing.T) {
+	tracker := NewThresholdTracker(zap.NewNop(), []Threshold{
+		{Name: "usd", Value: "100"},
+		{Name: "eur", Value: "851", Currency: "EUR"},
+		{Name: "high", Value: "10000"},
+		{Name: "unknown", Value: "1", Currency: "xyz"},
+	})
+	if len(tracker) != 3 {
+		t.Fatalf("NewThresholdTracker() tracks %d thresholds, want 3", len(tracker))
+	}
+	start := time.Date(TaxYear, 1, 1, 0, 0, 0, 0, time.UTC)
+	// going back in time
+	for days, value := range []int64{2000, 500, 50, 150, 10} {
+		tracker.Observe(start.AddDate(0, 0, 4-days), big.NewRat(value, 1))
+	}
+	crossings := tracker.Crossings()
+	day1, day4 := start.AddDate(0, 0, 1), start.AddDate(0, 0, 4)
+	for i, want := range []*time.Time{&day1, &day4, nil} {
+		got := crossings[i].FirstTime
+		if (got == nil) != (want == nil) || got != nil && !got.Equal(*want) {
+			t.Errorf("%s first crossing = %v, want %v", crossings[i].Name, got, want)
+		}
+	}
+	if crossings[1].Threshold.Currency != "eur" {
+		t.Errorf("threshold currency = %s, want eur", crossings[1].Threshold.Currency)
+	}
+}
@@ -0,0 +1,81 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"maps"
+	"math/big"
+	"slices"
+	"sort"
+	"time"
+)
+
+// Point is the aggregate value of the state evaluated at some moment,
+// it is the value just before the events happened at that moment
+type Point struct {
+	Time      time.Time
+	Aggregate *big.Rat
+}
+
+// Timeline is sorted by time
+type Timeline []Point
+
+// Best returns the point with the maximum value, the earliest one if there are several
+func (t Timeline) Best() Point {
+	best := Point{Aggregate: &big.Rat{}}
+	for _, point := range t {
+		if best.Aggregate.Cmp(point.Aggregate) < 0 && countsForMaximum(point.Time) {
+			best = point
+		}
+	}
+	return best
+}
+
+// ValueAt returns the account value at the given time: the value of the first point not before it,
+// or the value of the last point if the time is after all the points. There was no account before
+// its opening and after its closure, it has no value then.
+func (e *Evaluation) ValueAt(date time.Time) *big.Rat {
+	if len(e.Timeline) == 0 || date.Before(e.Account.Start()) || date.After(e.Account.End()) {
+		return &big.Rat{}
+	}
+	i := sort.Search(len(e.Timeline), func(i int) bool {
+		return !e.Timeline[i].Time.Before(date)
+	})
+	if i == len(e.Timeline) {
+		return e.Timeline[i-1].Aggregate
+	}
+	return e.Timeline[i].Aggregate
+}
+
+// Combine merges timelines of several accounts into the timeline of their sum
+func Combine(evaluations []*Evaluation) Timeline {
+	times := make(map[time.Time]bool)
+	for _, evaluation := range evaluations {
+		for _, point := range evaluation.Timeline {
+			times[point.Time] = true
+		}
+	}
+	combined := make(Timeline, 0, len(times))
+	for _, date := range slices.SortedFunc(maps.Keys(times), time.Time.Compare) {
+		sum := &big.Rat{}
+		for _, evaluation := range evaluations {
+			sum = AddRat(sum, evaluation.ValueAt(date))
+		}
+		combined = append(combined, Point{Time: date, Aggregate: sum})
+	}
+	return combined
+}
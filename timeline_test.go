@@ -0,0 +1,72 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestCombineNonOverlapping(t *testing.T) {
+	at := func(month time.Month) time.Time {
+		return time.Date(TaxYear, month, 1, 0, 0, 0, 0, Location)
+	}
+	closed, opened := at(5), at(6)
+	point := func(month time.Month, value int64) Point {
+		return Point{Time: at(month), Aggregate: big.NewRat(value, 1)}
+	}
+	evaluations := []*Evaluation{
+		{
+			Account:  AccountInfo{Id: "closed", ClosedDate: &closed},
+			Timeline: Timeline{point(2, 100), point(4, 300)},
+			Current:  &big.Rat{},
+		},
+		{
+			Account:  AccountInfo{Id: "opened", OpenedDate: &opened},
+			Timeline: Timeline{point(7, 200), point(9, 250)},
+			Current:  big.NewRat(1000, 1),
+		},
+	}
+	combined := Combine(evaluations)
+	want := []int64{100, 300, 200, 250}
+	if len(combined) != len(want) {
+		t.Fatalf("Combine() = %v, want %d points", combined, len(want))
+	}
+	for i, point := range combined {
+		if point.Aggregate.Cmp(big.NewRat(want[i], 1)) != 0 {
+			t.Errorf("Combine()[%d] at %s = %s, want %d", i, point.Time, point.Aggregate.FloatString(2), want[i])
+		}
+	}
+	if best := combined.Best(); best.Aggregate.Cmp(big.NewRat(300, 1)) != 0 {
+		t.Errorf("Combine().Best() = %s, want 300", best.Aggregate.FloatString(2))
+	}
+	for _, test := range []struct {
+		date time.Time
+		want int64
+	}{
+		{at(1), 100},
+		{at(4).Add(time.Hour), 300},
+		{at(5).Add(time.Hour), 0},
+		{at(10), 250},
+	} {
+		got := AddRat(evaluations[0].ValueAt(test.date), evaluations[1].ValueAt(test.date))
+		if got.Cmp(big.NewRat(test.want, 1)) != 0 {
+			t.Errorf("value at %s = %s, want %d", test.date, got.FloatString(2), test.want)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"time"
+	// the timezone database for systems without one
+	_ "time/tzdata"
+)
+
+// Location is the reporting timezone, the tax year and its months start at midnight there
+// and report timestamps are shown in it
+var Location = time.UTC
+
+// InTaxYear tells whether the time belongs to the tax year in the reporting timezone
+func InTaxYear(date time.Time) bool {
+	return date.In(Location).Year() == TaxYear
+}
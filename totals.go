@@ -0,0 +1,118 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"math/big"
+	"slices"
+	"text/tabwriter"
+
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// Money deposited to the account
+var DepositTypes = []pb.OperationType{
+	pb.OperationType_OPERATION_TYPE_INPUT,
+	pb.OperationType_OPERATION_TYPE_INPUT_SWIFT,
+	pb.OperationType_OPERATION_TYPE_INPUT_ACQUIRING,
+}
+
+// Money withdrawn from the account
+var WithdrawalTypes = []pb.OperationType{
+	pb.OperationType_OPERATION_TYPE_OUTPUT,
+	pb.OperationType_OPERATION_TYPE_OUTPUT_SWIFT,
+	pb.OperationType_OPERATION_TYPE_OUTPUT_ACQUIRING,
+}
+
+// Fees charged by the broker
+var FeeTypes = []pb.OperationType{
+	pb.OperationType_OPERATION_TYPE_BROKER_FEE,
+	pb.OperationType_OPERATION_TYPE_SERVICE_FEE,
+	pb.OperationType_OPERATION_TYPE_MARGIN_FEE,
+	pb.OperationType_OPERATION_TYPE_SUCCESS_FEE,
+	pb.OperationType_OPERATION_TYPE_TRACK_MFEE,
+	pb.OperationType_OPERATION_TYPE_TRACK_PFEE,
+	pb.OperationType_OPERATION_TYPE_CASH_FEE,
+	pb.OperationType_OPERATION_TYPE_OUT_FEE,
+	pb.OperationType_OPERATION_TYPE_OUT_STAMP_DUTY,
+	pb.OperationType_OPERATION_TYPE_ADVICE_FEE,
+	pb.OperationType_OPERATION_TYPE_OVER_COM,
+}
+
+// Taxes withheld by the broker and their corrections
+var TaxTypes = []pb.OperationType{
+	pb.OperationType_OPERATION_TYPE_TAX,
+	pb.OperationType_OPERATION_TYPE_BOND_TAX,
+	pb.OperationType_OPERATION_TYPE_DIVIDEND_TAX,
+	pb.OperationType_OPERATION_TYPE_BENEFIT_TAX,
+	pb.OperationType_OPERATION_TYPE_TAX_CORRECTION,
+	pb.OperationType_OPERATION_TYPE_TAX_CORRECTION_COUPON,
+	pb.OperationType_OPERATION_TYPE_TAX_PROGRESSIVE,
+	pb.OperationType_OPERATION_TYPE_BOND_TAX_PROGRESSIVE,
+	pb.OperationType_OPERATION_TYPE_DIVIDEND_TAX_PROGRESSIVE,
+	pb.OperationType_OPERATION_TYPE_BENEFIT_TAX_PROGRESSIVE,
+	pb.OperationType_OPERATION_TYPE_TAX_CORRECTION_PROGRESSIVE,
+	pb.OperationType_OPERATION_TYPE_TAX_REPO,
+	pb.OperationType_OPERATION_TYPE_TAX_REPO_HOLD,
+	pb.OperationType_OPERATION_TYPE_TAX_REPO_REFUND,
+	pb.OperationType_OPERATION_TYPE_TAX_REPO_PROGRESSIVE,
+	pb.OperationType_OPERATION_TYPE_TAX_REPO_HOLD_PROGRESSIVE,
+	pb.OperationType_OPERATION_TYPE_TAX_REPO_REFUND_PROGRESSIVE,
+}
+
+// YearTotals sums payments of the operations of the given types during the tax year by currency
+func YearTotals(operations []*pb.OperationItem, types []pb.OperationType) map[string]*big.Rat {
+	totals := make(map[string]*big.Rat)
+	for _, operation := range operations {
+		if operation.Payment == nil || !InTaxYear(operation.Date.AsTime()) || !slices.Contains(types, operation.Type) {
+			continue
+		}
+		currency := operation.Payment.GetCurrency()
+		totals[currency] = AddRat(totals[currency], ToRat(operation.Payment))
+	}
+	return totals
+}
+
+// Contributions returns the deposits of the tax year, they are limited and matter for IIS accounts
+func (e *Evaluation) Contributions() map[string]*big.Rat {
+	return YearTotals(e.Operations, DepositTypes)
+}
+
+// Total is a named set of yearly totals
+type Total struct {
+	Name   string
+	Totals map[string]*big.Rat
+}
+
+// PrintTotals prints the totals by currency with their values in USD
+func PrintTotals(w io.Writer, totals []Total) error {
+	tw := NewTable(w, tabwriter.AlignRight)
+	fmt.Fprintf(tw, "%s\t%s\t%s\tUSD\t\n", T("TOTAL"), T("CURRENCY"), T("AMOUNT"))
+	for _, total := range totals {
+		for _, currency := range slices.Sorted(maps.Keys(total.Totals)) {
+			amount := total.Totals[currency]
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\n", total.Name, currency, FormatMoney(amount, currency),
+				FormatUSD(Aggregate(map[string]*big.Rat{currency: amount})))
+		}
+		tw.Total()
+		fmt.Fprintf(tw, "%s\t%s\t\t%s\t\n", total.Name, T("total"), FormatUSD(Aggregate(total.Totals)))
+	}
+	return tw.Flush()
+}
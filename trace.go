@@ -0,0 +1,118 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Tracer logs every API call with its latency and the response size, and saves the requests
+// and the responses to files if the directory is set
+type Tracer struct {
+	logger *zap.Logger
+	dir    string
+	mu     sync.Mutex
+	calls  int
+}
+
+// tracer traces the API calls of the run, nil if disabled
+var tracer *Tracer
+
+func NewTracer(logger *zap.Logger, dir string) (*Tracer, error) {
+	if dir != "" {
+		err := os.MkdirAll(dir, 0o755)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &Tracer{logger: logger, dir: dir}, nil
+}
+
+// TraceRecord is a saved API call
+type TraceRecord struct {
+	Method   string        `json:"method"`
+	Start    time.Time     `json:"start"`
+	Latency  time.Duration `json:"latency"`
+	Request  any           `json:"request"`
+	Response any           `json:"response,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// TraceCall traces the finished API call when tracing or telemetry is enabled, the call is made
+// by the caller, so the SDK response types stay out of the way
+func TraceCall(method string, request any, start time.Time, response any, err error) {
+	if telemetry != nil {
+		telemetry.Call(method, start, err)
+	}
+	if tracer == nil {
+		return
+	}
+	tracer.record(TraceRecord{Method: method, Start: start, Latency: time.Since(start), Request: request}, response, err)
+}
+
+func (t *Tracer) record(record TraceRecord, response any, err error) {
+	t.mu.Lock()
+	t.calls++
+	call := t.calls
+	t.mu.Unlock()
+	data, marshalErr := json.Marshal(response)
+	fields := []zap.Field{
+		zap.Int("call", call),
+		zap.String("method", record.Method),
+		zap.Duration("latency", record.Latency),
+		zap.Any("request", record.Request),
+		zap.Int("response_bytes", len(data)),
+	}
+	if err != nil {
+		record.Error = err.Error()
+		fields = append(fields, zap.Error(err))
+	}
+	t.logger.Info("api call", fields...)
+	if t.dir == "" {
+		return
+	}
+	if marshalErr == nil && err == nil {
+		record.Response = json.RawMessage(data)
+	}
+	data, marshalErr = json.MarshalIndent(record, "", "  ")
+	if marshalErr == nil {
+		marshalErr = os.WriteFile(filepath.Join(t.dir, fmt.Sprintf("%05d-%s.json", call, record.Method)), data, 0o644)
+	}
+	if marshalErr != nil {
+		t.logger.Warn("error saving api call trace", zap.Int("call", call), zap.Error(marshalErr))
+	}
+}
+
+// reportRequest is the traced request of a report generation
+type reportRequest struct {
+	AccountId string
+	From      time.Time
+	To        time.Time
+}
+
+// reportPageRequest is the traced request of a generated report page
+type reportPageRequest struct {
+	TaskId string
+	Page   int32
+}
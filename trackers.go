@@ -0,0 +1,200 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"math/big"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// trackerOperations returns the operations of the evaluated accounts, oldest first, as trackers import them
+func trackerOperations(evaluations []*Evaluation) []*pb.OperationItem {
+	var operations []*pb.OperationItem
+	for _, evaluation := range evaluations {
+		operations = append(operations, evaluation.Operations...)
+	}
+	slices.SortStableFunc(operations, func(a, b *pb.OperationItem) int {
+		return cmp.Or(a.Date.AsTime().Compare(b.Date.AsTime()), strings.Compare(a.Id, b.Id))
+	})
+	return operations
+}
+
+// Transaction types of the Portfolio Performance CSV import, coupons are dividends of the bond there
+var portfolioPerformanceTypes = map[pb.OperationType]string{
+	pb.OperationType_OPERATION_TYPE_BUY:          "Buy",
+	pb.OperationType_OPERATION_TYPE_SELL:         "Sell",
+	pb.OperationType_OPERATION_TYPE_DIVIDEND:     "Dividend",
+	pb.OperationType_OPERATION_TYPE_COUPON:       "Dividend",
+	pb.OperationType_OPERATION_TYPE_DIVIDEND_TAX: "Taxes",
+	pb.OperationType_OPERATION_TYPE_BOND_TAX:     "Taxes",
+	pb.OperationType_OPERATION_TYPE_INPUT:        "Deposit",
+	pb.OperationType_OPERATION_TYPE_OUTPUT:       "Removal",
+}
+
+// WritePortfolioPerformance writes the operations as the Portfolio Performance CSV import of account transactions,
+// values are positive and the trades include their fees
+func WritePortfolioPerformance(filename string, evaluations []*Evaluation) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := csv.NewWriter(file)
+	err = w.Write([]string{"Date", "Type", "Value", "Transaction Currency", "Fees", "Shares", "ISIN",
+		"Ticker Symbol", "Security Name", "Note"})
+	if err != nil {
+		return err
+	}
+	for _, operation := range trackerOperations(evaluations) {
+		kind, ok := portfolioPerformanceTypes[operation.Type]
+		if slices.Contains(FeeTypes, operation.Type) {
+			kind, ok = "Fees", true
+		}
+		if !ok || operation.Payment == nil {
+			continue
+		}
+		value := (&big.Rat{}).Abs(ToRat(operation.Payment))
+		fees := (&big.Rat{}).Abs(ToRat(operation.Commission))
+		switch operation.Type {
+		case pb.OperationType_OPERATION_TYPE_BUY:
+			value = AddRat(value, fees)
+		case pb.OperationType_OPERATION_TYPE_SELL:
+			value = SubRat(value, fees)
+		default:
+			fees = &big.Rat{}
+		}
+		shares := ""
+		if operation.Quantity != 0 {
+			shares = strconv.FormatInt(operation.Quantity, 10)
+		}
+		err = w.Write([]string{
+			operation.Date.AsTime().In(Location).Format("2006-01-02T15:04"),
+			kind,
+			value.FloatString(2),
+			strings.ToUpper(operation.Payment.Currency),
+			fees.FloatString(2),
+			shares,
+			isins[operation.AssetUid],
+			tickers[operation.AssetUid],
+			cmp.Or(names[operation.AssetUid], operation.Name),
+			operation.Id,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	err = w.Error()
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// GhostfolioActivity is an activity of the Ghostfolio import
+type GhostfolioActivity struct {
+	AccountId  string  `json:"accountId,omitempty"`
+	Comment    string  `json:"comment"`
+	Currency   string  `json:"currency"`
+	DataSource string  `json:"dataSource"`
+	Date       string  `json:"date"`
+	Fee        float64 `json:"fee"`
+	Quantity   float64 `json:"quantity"`
+	Symbol     string  `json:"symbol"`
+	Type       string  `json:"type"`
+	UnitPrice  float64 `json:"unitPrice"`
+}
+
+// Activity types of the Ghostfolio import, Ghostfolio has no deposits and no taxes, so the taxes are fees
+var ghostfolioTypes = map[pb.OperationType]string{
+	pb.OperationType_OPERATION_TYPE_BUY:          "BUY",
+	pb.OperationType_OPERATION_TYPE_SELL:         "SELL",
+	pb.OperationType_OPERATION_TYPE_DIVIDEND:     "DIVIDEND",
+	pb.OperationType_OPERATION_TYPE_COUPON:       "INTEREST",
+	pb.OperationType_OPERATION_TYPE_DIVIDEND_TAX: "FEE",
+	pb.OperationType_OPERATION_TYPE_BOND_TAX:     "FEE",
+}
+
+// NewGhostfolioActivity maps the operation to a Ghostfolio activity of a manually priced asset,
+// it returns false for operations without a counterpart
+func NewGhostfolioActivity(operation *pb.OperationItem) (GhostfolioActivity, bool) {
+	kind, ok := ghostfolioTypes[operation.Type]
+	if slices.Contains(FeeTypes, operation.Type) {
+		kind, ok = "FEE", true
+	}
+	if !ok || operation.Payment == nil {
+		return GhostfolioActivity{}, false
+	}
+	amount, _ := (&big.Rat{}).Abs(ToRat(operation.Payment)).Float64()
+	activity := GhostfolioActivity{
+		Comment:    cmp.Or(names[operation.AssetUid], operation.Name),
+		Currency:   strings.ToUpper(operation.Payment.Currency),
+		DataSource: "MANUAL",
+		Date:       operation.Date.AsTime().Format(time.RFC3339),
+		Symbol:     cmp.Or(isins[operation.AssetUid], tickers[operation.AssetUid], operation.AssetUid),
+		Type:       kind,
+	}
+	switch kind {
+	case "BUY", "SELL":
+		activity.Quantity = float64(operation.Quantity)
+		if operation.Price != nil {
+			activity.UnitPrice, _ = ToRat(operation.Price).Float64()
+		}
+		if operation.Commission != nil {
+			activity.Fee, _ = (&big.Rat{}).Abs(ToRat(operation.Commission)).Float64()
+		}
+	case "FEE":
+		activity.Fee = amount
+	default:
+		activity.Quantity = 1
+		activity.UnitPrice = amount
+	}
+	return activity, true
+}
+
+// WriteGhostfolio writes the operations as the Ghostfolio JSON import
+func WriteGhostfolio(filename string, evaluations []*Evaluation) error {
+	activities := []GhostfolioActivity{}
+	for _, operation := range trackerOperations(evaluations) {
+		if activity, ok := NewGhostfolioActivity(operation); ok {
+			activities = append(activities, activity)
+		}
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(struct {
+		Activities []GhostfolioActivity `json:"activities"`
+	}{activities})
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
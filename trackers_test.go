@@ -0,0 +1,73 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+func TestNewGhostfolioActivity(t *testing.T) {
+	date := timestamppb.New(time.Date(TaxYear, 3, 1, 12, 0, 0, 0, time.UTC))
+	for _, test := range []struct {
+		name      string
+		operation *pb.OperationItem
+		want      GhostfolioActivity
+		ok        bool
+	}{
+		{"buy", &pb.OperationItem{
+			Type:       pb.OperationType_OPERATION_TYPE_BUY,
+			AssetUid:   "a",
+			Date:       date,
+			Quantity:   2,
+			Price:      &pb.MoneyValue{Currency: "usd", Units: 10},
+			Payment:    &pb.MoneyValue{Currency: "usd", Units: -20},
+			Commission: &pb.MoneyValue{Currency: "usd", Units: -1},
+		}, GhostfolioActivity{Type: "BUY", Quantity: 2, UnitPrice: 10, Fee: 1}, true},
+		{"dividend", &pb.OperationItem{
+			Type:     pb.OperationType_OPERATION_TYPE_DIVIDEND,
+			AssetUid: "a",
+			Date:     date,
+			Payment:  &pb.MoneyValue{Currency: "usd", Units: 7},
+		}, GhostfolioActivity{Type: "DIVIDEND", Quantity: 1, UnitPrice: 7}, true},
+		{"tax", &pb.OperationItem{
+			Type:     pb.OperationType_OPERATION_TYPE_DIVIDEND_TAX,
+			AssetUid: "a",
+			Date:     date,
+			Payment:  &pb.MoneyValue{Currency: "usd", Units: -1},
+		}, GhostfolioActivity{Type: "FEE", Fee: 1}, true},
+		{"deposit", &pb.OperationItem{
+			Type:    pb.OperationType_OPERATION_TYPE_INPUT,
+			Date:    date,
+			Payment: &pb.MoneyValue{Currency: "rub", Units: 1000},
+		}, GhostfolioActivity{}, false},
+	} {
+		got, ok := NewGhostfolioActivity(test.operation)
+		if ok != test.ok {
+			t.Errorf("%s: NewGhostfolioActivity() ok = %v, want %v", test.name, ok, test.ok)
+			continue
+		}
+		if ok && (got.Type != test.want.Type || got.Quantity != test.want.Quantity ||
+			got.UnitPrice != test.want.UnitPrice || got.Fee != test.want.Fee ||
+			got.Currency != "USD" || got.Symbol != "a") {
+			t.Errorf("%s: NewGhostfolioActivity() = %+v, want %+v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"time"
+)
+
+// CashValue returns the value of the currency balances in USD,
+// negative balances are not counted under the gross liabilities policy
+func CashValue(state *State) *big.Rat {
+	sum := &big.Rat{}
+	for currency, rate := range ExchangeRates {
+		quantity, ok := state.Portfolio[currency]
+		if !ok || (quantity.Sign() < 0 && LiabilitiesPolicy == LiabilitiesGross) {
+			continue
+		}
+		sum = AddRat(sum, (&big.Rat{}).Quo(quantity, rate))
+	}
+	return sum
+}
+
+// SplitValue splits the aggregate value of the state into the securities and the cash parts,
+// some forms ask about securities and financial accounts separately
+func SplitValue(state *State, aggregate *big.Rat) (securities, cash *big.Rat) {
+	cash = CashValue(state)
+	return SubRat(aggregate, cash), cash
+}
+
+// observeMaximum replaces the point if the value is larger, the zero point is the initial one
+func observeMaximum(point *Point, date time.Time, value *big.Rat) {
+	if point.Aggregate == nil || point.Aggregate.Cmp(value) < 0 {
+		*point = Point{Time: date, Aggregate: value}
+	}
+}
@@ -0,0 +1,57 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestSplitValue(t *testing.T) {
+	defer func() { LiabilitiesPolicy = LiabilitiesNet }()
+	state := &State{
+		Portfolio:  map[string]*big.Rat{"usd": rat("-300"), "asset": rat("10")},
+		Prices:     map[string]*big.Rat{"asset": rat("50")},
+		Currencies: map[string]string{"asset": "usd"},
+	}
+	for _, test := range []struct {
+		policy           string
+		securities, cash *big.Rat
+	}{
+		{LiabilitiesNet, rat("500"), rat("-300")},
+		{LiabilitiesGross, rat("500"), rat("0")},
+	} {
+		LiabilitiesPolicy = test.policy
+		_, _, aggregate := Cost(state, nil)
+		securities, cash := SplitValue(state, aggregate)
+		if securities.Cmp(test.securities) != 0 || cash.Cmp(test.cash) != 0 {
+			t.Errorf("%s SplitValue() = %v, %v, want %v, %v", test.policy, securities, cash, test.securities, test.cash)
+		}
+	}
+}
+
+func TestObserveMaximum(t *testing.T) {
+	var best Point
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	observeMaximum(&best, start, rat("0"))
+	observeMaximum(&best, start.Add(time.Hour), rat("5"))
+	observeMaximum(&best, start.Add(2*time.Hour), rat("5"))
+	if !best.Time.Equal(start.Add(time.Hour)) || best.Aggregate.Cmp(rat("5")) != 0 {
+		t.Errorf("observeMaximum() = %v, want 5 at the first time", best)
+	}
+}
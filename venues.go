@@ -0,0 +1,99 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"maps"
+	"slices"
+
+	"go.uber.org/zap"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// TradedCurrencies returns the payment currency of the last trade of each asset in the account,
+// it tells which listing of a dual-listed asset the account actually uses
+func TradedCurrencies(operations []*pb.OperationItem) map[string]string {
+	currencies := make(map[string]string)
+	last := make(map[string]*pb.OperationItem)
+	for _, operation := range operations {
+		switch operation.Type {
+		case pb.OperationType_OPERATION_TYPE_BUY, pb.OperationType_OPERATION_TYPE_SELL:
+		default:
+			continue
+		}
+		if operation.AssetUid == "" || operation.Payment == nil || operation.Payment.Currency == "" {
+			continue
+		}
+		if previous, ok := last[operation.AssetUid]; !ok || operation.Date.AsTime().After(previous.Date.AsTime()) {
+			last[operation.AssetUid] = operation
+			currencies[operation.AssetUid] = operation.Payment.Currency
+		}
+	}
+	return currencies
+}
+
+// Venues picks the instrument whose prices value each asset, as an asset may be listed on several
+// exchanges in different currencies: the held instrument first, then the last traded one,
+// then the one in the currency of the trades, and then the instrument the asset was resolved from
+func Venues(logger *zap.Logger, held map[string]string, operations []*pb.OperationItem,
+	traded map[string]string) map[string]string {
+	venues := make(map[string]string, len(instrumentUids))
+	for assetUid, instrumentUid := range instrumentUids {
+		venues[assetUid] = instrumentUid
+	}
+	for assetUid, currency := range traded {
+		if instrumentCurrencies[venues[assetUid]] == currency {
+			continue
+		}
+		for _, instrumentUid := range slices.Sorted(maps.Keys(assets)) {
+			if assets[instrumentUid] == assetUid && instrumentCurrencies[instrumentUid] == currency {
+				venues[assetUid] = instrumentUid
+				break
+			}
+		}
+	}
+	last := make(map[string]*pb.OperationItem)
+	for _, operation := range operations {
+		switch operation.Type {
+		case pb.OperationType_OPERATION_TYPE_BUY, pb.OperationType_OPERATION_TYPE_SELL:
+		default:
+			continue
+		}
+		if operation.InstrumentUid == "" || assets[operation.InstrumentUid] != operation.AssetUid {
+			continue
+		}
+		if previous, ok := last[operation.AssetUid]; !ok || operation.Date.AsTime().After(previous.Date.AsTime()) {
+			last[operation.AssetUid] = operation
+		}
+	}
+	for assetUid, operation := range last {
+		venues[assetUid] = operation.InstrumentUid
+	}
+	maps.Copy(venues, held)
+	for _, assetUid := range slices.SortedFunc(maps.Keys(traded), ByTicker) {
+		venue, ok := venues[assetUid]
+		if ok && instrumentCurrencies[venue] != traded[assetUid] {
+			logger.Warn("valuation currency differs from the traded currency",
+				zap.String("asset", assetUid),
+				zap.String("ticker", tickers[assetUid]),
+				zap.String("instrument", venue),
+				zap.String("currency", instrumentCurrencies[venue]),
+				zap.String("traded_currency", traded[assetUid]))
+		}
+	}
+	return venues
+}
@@ -0,0 +1,161 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Webhook events
+const (
+	WebhookCompleted = "completed"
+	WebhookMaximum   = "maximum"
+)
+
+// WebhookOptions is a URL notified of the events of the runs
+type WebhookOptions struct {
+	URL string `yaml:"URL"`
+	// completed when a run completes, maximum when a scheduled run finds a new yearly maximum, both by default
+	Events []string `yaml:"Events"`
+	// text/template of the request body with the WebhookEvent, its JSON by default,
+	// the json function quotes a value, e.g. {"text": {{json .Message}}}
+	Body string `yaml:"Body"`
+	// application/json by default
+	ContentType string            `yaml:"ContentType"`
+	Headers     map[string]string `yaml:"Headers"`
+}
+
+// WebhookAccount is the yearly maximum of an account in USD
+type WebhookAccount struct {
+	AccountId string    `json:"account_id"`
+	BestTime  time.Time `json:"best_time"`
+	Best      string    `json:"best"`
+}
+
+// WebhookEvent is the data of a webhook request
+type WebhookEvent struct {
+	Event    string           `json:"event"`
+	Time     time.Time        `json:"time"`
+	TaxYear  int              `json:"tax_year"`
+	ExitCode int              `json:"exit_code"`
+	Accounts []WebhookAccount `json:"accounts"`
+	// one line for chats and push notifications
+	Message string `json:"message"`
+}
+
+// NewWebhookEvent describes the run by its record in the run history
+func NewWebhookEvent(event string, record RunRecord, code int) WebhookEvent {
+	result := WebhookEvent{Event: event, Time: record.Time, TaxYear: TaxYear, ExitCode: code}
+	var parts []string
+	for _, account := range record.Accounts {
+		best, ok := (&big.Rat{}).SetString(account.Best)
+		if !ok {
+			best = &big.Rat{}
+		}
+		value := Round(best, MoneyDecimals, Rounding).FloatString(MoneyDecimals)
+		result.Accounts = append(result.Accounts, WebhookAccount{
+			AccountId: account.AccountId,
+			BestTime:  account.BestTime,
+			Best:      value,
+		})
+		parts = append(parts, fmt.Sprintf("%s $%s at %s", Redact("account", account.AccountId), value,
+			account.BestTime.In(Location).Format(time.DateTime)))
+	}
+	switch event {
+	case WebhookMaximum:
+		result.Message = fmt.Sprintf("New %d maximum: %s", TaxYear, strings.Join(parts, ", "))
+	default:
+		result.Message = fmt.Sprintf("Run completed with exit code %d, %d maximum: %s", code, TaxYear,
+			strings.Join(parts, ", "))
+	}
+	return result
+}
+
+var webhookFuncs = template.FuncMap{
+	"json": func(value any) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+}
+
+// WebhookBody renders the body of the request for the event
+func WebhookBody(webhook WebhookOptions, event WebhookEvent) ([]byte, error) {
+	if webhook.Body == "" {
+		return json.Marshal(event)
+	}
+	tmpl, err := template.New("webhook").Funcs(webhookFuncs).Parse(webhook.Body)
+	if err != nil {
+		return nil, err
+	}
+	var body bytes.Buffer
+	err = tmpl.Execute(&body, event)
+	if err != nil {
+		return nil, err
+	}
+	return body.Bytes(), nil
+}
+
+func sendWebhook(client *http.Client, webhook WebhookOptions, event WebhookEvent) error {
+	body, err := WebhookBody(webhook, event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", cmp.Or(webhook.ContentType, "application/json"))
+	for key, value := range webhook.Headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// FireWebhooks sends the event to the webhooks subscribed to it, failures are only logged
+func FireWebhooks(logger *zap.Logger, webhooks []WebhookOptions, event WebhookEvent) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	for _, webhook := range webhooks {
+		if len(webhook.Events) > 0 && !slices.Contains(webhook.Events, event.Event) {
+			continue
+		}
+		err := sendWebhook(client, webhook, event)
+		if err != nil {
+			logger.Warn("error sending webhook", zap.String("event", event.Event), zap.Error(err))
+		}
+	}
+}
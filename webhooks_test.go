@@ -0,0 +1,50 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestFireWebhooks(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, r.Header.Get("Content-Type")+" "+string(body))
+	}))
+	defer server.Close()
+	record := RunRecord{
+		Time:     time.Date(TaxYear, 6, 1, 0, 0, 0, 0, time.UTC),
+		Accounts: []AccountRecord{{AccountId: "1", BestTime: time.Date(TaxYear, 3, 1, 9, 0, 0, 0, Location), Best: "25001/2"}},
+	}
+	event := NewWebhookEvent(WebhookMaximum, record, ExitSuccess)
+	webhooks := []WebhookOptions{
+		{URL: server.URL, Events: []string{WebhookCompleted}},
+		{URL: server.URL, Body: `{"best": {{json (index .Accounts 0).Best}}}`},
+	}
+	FireWebhooks(zap.NewNop(), webhooks, event)
+	want := `application/json {"best": "12500.50"}`
+	if len(bodies) != 1 || bodies[0] != want {
+		t.Errorf("FireWebhooks() sent %q, want only %q", bodies, want)
+	}
+}
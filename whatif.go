@@ -0,0 +1,123 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"math/big"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Hypothetical operation types
+const (
+	WhatIfDeposit    = "deposit"
+	WhatIfWithdrawal = "withdrawal"
+	WhatIfBuy        = "buy"
+	WhatIfSell       = "sell"
+)
+
+var UnknownWhatIfTypeError = errors.New("unknown hypothetical operation type")
+var InvalidWhatIfAmountError = errors.New("invalid hypothetical operation amount")
+var UnpricedWhatIfAssetError = errors.New("hypothetical operation asset has no price")
+
+// WhatIf is a hypothetical operation
+type WhatIf struct {
+	Date time.Time `yaml:"Date"`
+	// deposit, withdrawal, buy or sell
+	Type string `yaml:"Type"`
+	// ticker or asset UID for buy and sell
+	Asset    string `yaml:"Asset"`
+	Quantity string `yaml:"Quantity"`
+	// cash paid or received
+	Amount   string `yaml:"Amount"`
+	Currency string `yaml:"Currency"`
+}
+
+func LoadWhatIfs(filename string) ([]WhatIf, error) {
+	var whatIfs []WhatIf
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	err = yaml.Unmarshal(data, &whatIfs)
+	return whatIfs, err
+}
+
+func parseRat(value string) (*big.Rat, bool) {
+	if value == "" {
+		return &big.Rat{}, true
+	}
+	return (&big.Rat{}).SetString(value)
+}
+
+// Delta returns the changes of the portfolio made by the hypothetical operation
+func (w WhatIf) Delta() (map[string]*big.Rat, error) {
+	amount, ok := parseRat(w.Amount)
+	if !ok {
+		return nil, InvalidWhatIfAmountError
+	}
+	quantity, ok := parseRat(w.Quantity)
+	if !ok {
+		return nil, InvalidWhatIfAmountError
+	}
+	currency := NormalizeCurrency(w.Currency)
+	switch w.Type {
+	case WhatIfDeposit:
+		return map[string]*big.Rat{currency: amount}, nil
+	case WhatIfWithdrawal:
+		return map[string]*big.Rat{currency: (&big.Rat{}).Neg(amount)}, nil
+	case WhatIfBuy, WhatIfSell:
+		assetUid, ok := FindAsset(w.Asset)
+		if !ok {
+			return nil, UnknownAssetError
+		}
+		if w.Type == WhatIfBuy {
+			return map[string]*big.Rat{assetUid: quantity, currency: (&big.Rat{}).Neg(amount)}, nil
+		}
+		return map[string]*big.Rat{assetUid: (&big.Rat{}).Neg(quantity), currency: amount}, nil
+	default:
+		return nil, UnknownWhatIfTypeError
+	}
+}
+
+// ApplyWhatIf adds the hypothetical operation to the current state
+// and returns the update removing it at the operation date
+func ApplyWhatIf(whatIf WhatIf, state *State) (Update, error) {
+	delta, err := whatIf.Delta()
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range delta {
+		if _, ok := ExchangeRates[key]; !ok {
+			if _, ok := state.Prices[key]; !ok && !IsFutures(key) {
+				return nil, UnpricedWhatIfAssetError
+			}
+		}
+		state.Portfolio[key] = AddRat(state.Portfolio[key], value)
+	}
+	return func(state *State) {
+		for key, value := range delta {
+			state.Portfolio[key] = SubRat(state.Portfolio[key], value)
+			if state.Portfolio[key].Sign() == 0 {
+				delete(state.Portfolio, key)
+			}
+		}
+	}, nil
+}
@@ -0,0 +1,226 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"math/big"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/zap"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+// DividendTaxOptions are the rates to check the foreign dividend withholding against, in percent
+type DividendTaxOptions struct {
+	// tax rate on dividends in the country of residence, 13 by default
+	LocalRate string `yaml:"LocalRate"`
+	// country of risk -> treaty withholding rate with a W-8BEN or a similar form, US 10 by default
+	TreatyRates map[string]string `yaml:"TreatyRates"`
+}
+
+// Dividends of issuers of the local country are taxed by the broker and not summarized
+const localCountry = "RU"
+
+var defaultLocalDividendRate = big.NewRat(13, 100)
+
+var defaultTreatyRates = map[string]*big.Rat{"US": big.NewRat(10, 100)}
+
+// DividendTaxRates are the parsed rates of DividendTaxOptions as fractions
+type DividendTaxRates struct {
+	Local  *big.Rat
+	Treaty map[string]*big.Rat
+}
+
+func parsePercent(value string) (*big.Rat, error) {
+	rate, ok := (&big.Rat{}).SetString(value)
+	if !ok || rate.Sign() < 0 || rate.Cmp(big.NewRat(100, 1)) > 0 {
+		return nil, fmt.Errorf("invalid percent %q", value)
+	}
+	return rate.Quo(rate, big.NewRat(100, 1)), nil
+}
+
+// ParseDividendTaxRates parses the options over the defaults
+func ParseDividendTaxRates(options DividendTaxOptions) (DividendTaxRates, error) {
+	rates := DividendTaxRates{Local: defaultLocalDividendRate, Treaty: maps.Clone(defaultTreatyRates)}
+	if options.LocalRate != "" {
+		rate, err := parsePercent(options.LocalRate)
+		if err != nil {
+			return rates, err
+		}
+		rates.Local = rate
+	}
+	for country, value := range options.TreatyRates {
+		rate, err := parsePercent(value)
+		if err != nil {
+			return rates, fmt.Errorf("treaty rate of %s: %w", country, err)
+		}
+		rates.Treaty[country] = rate
+	}
+	return rates, nil
+}
+
+// DividendPayment is a foreign dividend with the tax withheld at the source
+type DividendPayment struct {
+	Asset    string
+	Country  string
+	Date     time.Time
+	Currency string
+	Gross    *big.Rat
+	Withheld *big.Rat
+}
+
+// Rate returns the share of the gross dividend withheld
+func (p DividendPayment) Rate() *big.Rat {
+	if p.Gross.Sign() == 0 {
+		return &big.Rat{}
+	}
+	return (&big.Rat{}).Quo(p.Withheld, p.Gross)
+}
+
+// ForeignDividends matches the dividends of the tax year with the taxes withheld from them on the same day,
+// the dividend payments are gross and the taxes are separate operations
+func ForeignDividends(operations []*pb.OperationItem) []DividendPayment {
+	type key struct {
+		asset, date, currency string
+	}
+	payments := make(map[key]*DividendPayment)
+	for _, operation := range operations {
+		switch operation.Type {
+		case pb.OperationType_OPERATION_TYPE_DIVIDEND, pb.OperationType_OPERATION_TYPE_DIVIDEND_TAX:
+		default:
+			continue
+		}
+		date := operation.Date.AsTime().In(Location)
+		if operation.Payment == nil || operation.AssetUid == "" || !InTaxYear(date) {
+			continue
+		}
+		country := countries[operation.AssetUid]
+		if country == localCountry {
+			continue
+		}
+		date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, Location)
+		k := key{operation.AssetUid, date.Format(time.DateOnly), operation.Payment.GetCurrency()}
+		payment, ok := payments[k]
+		if !ok {
+			payment = &DividendPayment{Asset: k.asset, Country: country, Date: date, Currency: k.currency,
+				Gross: &big.Rat{}, Withheld: &big.Rat{}}
+			payments[k] = payment
+		}
+		amount := ToRat(operation.Payment)
+		if operation.Type == pb.OperationType_OPERATION_TYPE_DIVIDEND {
+			payment.Gross = AddRat(payment.Gross, amount)
+		} else {
+			payment.Withheld = SubRat(payment.Withheld, amount)
+		}
+	}
+	result := make([]DividendPayment, 0, len(payments))
+	for _, payment := range payments {
+		result = append(result, *payment)
+	}
+	slices.SortFunc(result, func(a, b DividendPayment) int {
+		return cmp.Or(a.Date.Compare(b.Date), ByTicker(a.Asset, b.Asset), cmp.Compare(a.Currency, b.Currency))
+	})
+	return result
+}
+
+// WithholdingRow sums the payments of a country withheld at the same rate
+type WithholdingRow struct {
+	Country  string
+	Rate     string
+	Currency string
+	Payments int
+	Gross    *big.Rat
+	Withheld *big.Rat
+	// withheld above the treaty rate, it may be reclaimed from the source country
+	Excess *big.Rat
+	// the local tax less the credit for the foreign tax up to the treaty rate
+	LocalTax *big.Rat
+}
+
+// SummarizeWithholding groups the payments by country, withholding rate and currency, and flags payments
+// withheld above the treaty rates. The foreign tax is credited up to the treaty rate, or in full without
+// a treaty rate, and the rest of the local tax is due.
+func SummarizeWithholding(logger *zap.Logger, payments []DividendPayment, rates DividendTaxRates) []WithholdingRow {
+	type key struct {
+		country, rate, currency string
+	}
+	rows := make(map[key]*WithholdingRow)
+	for _, payment := range payments {
+		credit := payment.Withheld
+		excess := &big.Rat{}
+		if treaty, ok := rates.Treaty[payment.Country]; ok {
+			allowed := (&big.Rat{}).Mul(payment.Gross, treaty)
+			if payment.Withheld.Cmp(allowed) > 0 {
+				credit, excess = allowed, SubRat(payment.Withheld, allowed)
+				logger.Warn("dividend withheld above the treaty rate",
+					zap.String("ticker", tickers[payment.Asset]),
+					zap.String("country", payment.Country),
+					zap.Time("date", payment.Date),
+					zap.String("rate", percent(payment.Withheld, payment.Gross)),
+					zap.String("treaty_rate", percent(treaty, big.NewRat(1, 1))),
+					zap.String("excess", FormatMoney(excess, payment.Currency)))
+			}
+		}
+		localTax := SubRat((&big.Rat{}).Mul(payment.Gross, rates.Local), credit)
+		if localTax.Sign() < 0 {
+			localTax = &big.Rat{}
+		}
+		k := key{payment.Country, percent(payment.Withheld, payment.Gross), payment.Currency}
+		row, ok := rows[k]
+		if !ok {
+			row = &WithholdingRow{Country: k.country, Rate: k.rate, Currency: k.currency,
+				Gross: &big.Rat{}, Withheld: &big.Rat{}, Excess: &big.Rat{}, LocalTax: &big.Rat{}}
+			rows[k] = row
+		}
+		row.Payments++
+		row.Gross = AddRat(row.Gross, payment.Gross)
+		row.Withheld = AddRat(row.Withheld, payment.Withheld)
+		row.Excess = AddRat(row.Excess, excess)
+		row.LocalTax = AddRat(row.LocalTax, localTax)
+	}
+	result := make([]WithholdingRow, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, *row)
+	}
+	slices.SortFunc(result, func(a, b WithholdingRow) int {
+		return cmp.Or(cmp.Compare(a.Country, b.Country), cmp.Compare(a.Rate, b.Rate), cmp.Compare(a.Currency, b.Currency))
+	})
+	return result
+}
+
+// PrintWithholding prints the withholding summary
+func PrintWithholding(w io.Writer, rows []WithholdingRow) error {
+	tw := NewTable(w, tabwriter.AlignRight)
+	fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n", T("COUNTRY"), T("RATE"), T("PAYMENTS"), T("GROSS"),
+		T("WITHHELD"), T("ABOVE TREATY"), T("LOCAL TAX"))
+	for _, row := range rows {
+		country := row.Country
+		if country == "" {
+			country = "unknown"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%s\t%s\t\n", country, row.Rate, row.Payments,
+			FormatMoney(row.Gross, row.Currency), FormatMoney(row.Withheld, row.Currency),
+			FormatMoney(row.Excess, row.Currency), FormatMoney(row.LocalTax, row.Currency))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,85 @@
+// Maximum T-Bank Invest Account Value Evaluator
+// Copyright (C) 2025  Artem Leshchev
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	pb "opensource.tbank.ru/invest/invest-go/proto"
+)
+
+func TestSummarizeWithholding(t *testing.T) {
+	defer func() { delete(countries, "us"); delete(countries, "ru") }()
+	countries["us"], countries["ru"] = "US", "RU"
+	paid := time.Date(TaxYear, 6, 10, 12, 0, 0, 0, time.UTC)
+	operation := func(kind pb.OperationType, asset string, date time.Time, units int64) *pb.OperationItem {
+		return &pb.OperationItem{
+			Type:     kind,
+			AssetUid: asset,
+			Date:     timestamppb.New(date),
+			Payment:  &pb.MoneyValue{Currency: "usd", Units: units},
+		}
+	}
+	payments := ForeignDividends([]*pb.OperationItem{
+		operation(pb.OperationType_OPERATION_TYPE_DIVIDEND, "us", paid, 100),
+		operation(pb.OperationType_OPERATION_TYPE_DIVIDEND_TAX, "us", paid.Add(time.Minute), -30),
+		operation(pb.OperationType_OPERATION_TYPE_DIVIDEND, "us", paid.AddDate(0, 3, 0), 100),
+		operation(pb.OperationType_OPERATION_TYPE_DIVIDEND_TAX, "us", paid.AddDate(0, 3, 0), -10),
+		operation(pb.OperationType_OPERATION_TYPE_DIVIDEND, "ru", paid, 100),
+		operation(pb.OperationType_OPERATION_TYPE_DIVIDEND, "us", paid.AddDate(-1, 0, 0), 100),
+	})
+	if len(payments) != 2 || payments[0].Withheld.Cmp(big.NewRat(30, 1)) != 0 {
+		t.Fatalf("ForeignDividends() = %v, want 2 payments of the tax year, the first one withheld 30", payments)
+	}
+	rates, err := ParseDividendTaxRates(DividendTaxOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := SummarizeWithholding(zap.NewNop(), payments, rates)
+	if len(rows) != 2 {
+		t.Fatalf("SummarizeWithholding() = %v, want rows at 10%% and 30%%", rows)
+	}
+	for _, test := range []struct {
+		rate             string
+		excess, localTax *big.Rat
+	}{
+		{"10.0%", big.NewRat(0, 1), big.NewRat(3, 1)},
+		{"30.0%", big.NewRat(20, 1), big.NewRat(3, 1)},
+	} {
+		found := false
+		for _, row := range rows {
+			if row.Rate != test.rate {
+				continue
+			}
+			found = true
+			if row.Excess.Cmp(test.excess) != 0 || row.LocalTax.Cmp(test.localTax) != 0 {
+				t.Errorf("%s row excess %v, local tax %v, want %v and %v", test.rate, row.Excess, row.LocalTax,
+					test.excess, test.localTax)
+			}
+		}
+		if !found {
+			t.Errorf("no row at %s", test.rate)
+		}
+	}
+	if _, err := ParseDividendTaxRates(DividendTaxOptions{TreatyRates: map[string]string{"US": "150"}}); err == nil {
+		t.Error("ParseDividendTaxRates() accepted a rate above 100%")
+	}
+}